@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/boz/kcache/types/unstructured"
+
+	lr "github.com/boz/go-logutil/logrus"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	group := flag.String("group", "example.com", "API group of the resource")
+	version := flag.String("version", "v1", "API version of the resource")
+	resource := flag.String("resource", "widgets", "plural name of the resource")
+	ns := flag.String("namespace", "", "namespace (default: all)")
+	flag.Parse()
+
+	log := lr.New(logrus.New())
+	ctx := context.Background()
+
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(),
+		&clientcmd.ConfigOverrides{},
+	).ClientConfig()
+	if err != nil {
+		log.ErrFatal(err, "can't get kube config")
+	}
+
+	dc, err := dynamic.NewForConfig(config)
+	if err != nil {
+		log.ErrFatal(err, "can't get dynamic client")
+	}
+
+	gvr := schema.GroupVersionResource{Group: *group, Version: *version, Resource: *resource}
+
+	controller, err := unstructured.NewController(ctx, log, dc, gvr, *ns)
+	if err != nil {
+		log.ErrFatal(err, "unstructured.NewController()")
+	}
+	defer controller.Close()
+
+	sub, err := controller.Subscribe()
+	if err != nil {
+		log.ErrFatal(err, "subscribe")
+	}
+
+	select {
+	case <-sub.Ready():
+	case <-sub.Done():
+		return
+	}
+
+	list, err := sub.Cache().List()
+	if err != nil {
+		log.ErrFatal(err, "Cache().List()")
+	}
+	for _, obj := range list {
+		fmt.Printf("%v/%v: %v\n", obj.GetNamespace(), obj.GetName(), obj.GetResourceVersion())
+	}
+
+	for ev := range sub.Events() {
+		obj := ev.Resource()
+		fmt.Printf("event: %v: %v/%v[%v]\n", ev.Type(), obj.GetNamespace(), obj.GetName(), obj.GetResourceVersion())
+	}
+}
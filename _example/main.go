@@ -70,20 +70,16 @@ func main() {
 			fmt.Printf("event: %v: %v/%v[%v]\n", ev.Type(), obj.GetNamespace(), obj.GetName(), obj.GetResourceVersion())
 
 			cnobj, err := subscription.Cache().Get(obj.GetNamespace(), obj.GetName())
-			if err != nil {
-				log.ErrWarn(err, "Get()")
-				continue
-			}
 
 			if ev.Type() == kcache.EventTypeDelete {
-				if cnobj != nil {
-					log.Warnf("Get(deleted) != nil")
+				if !kcache.IsNotFound(err) {
+					log.Warnf("Get(deleted): %v", err)
 				}
 				continue
 			}
 
-			if cnobj == nil {
-				log.Warnf("Get() -> nil")
+			if err != nil {
+				log.ErrWarn(err, "Get()")
 				continue
 			}
 
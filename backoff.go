@@ -0,0 +1,65 @@
+package kcache
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	defaultBackoffInitial = time.Second
+	defaultBackoffMax     = time.Minute
+	defaultBackoffFactor  = 2.0
+)
+
+// backoff computes capped exponential delays with full jitter.
+type backoff struct {
+	initial time.Duration
+	max     time.Duration
+	factor  float64
+
+	attempts int
+
+	rand func(int64) int64
+}
+
+func newBackoff(initial, max time.Duration, factor float64) *backoff {
+	if initial <= 0 {
+		initial = defaultBackoffInitial
+	}
+	if max < initial {
+		max = initial
+	}
+	if factor < 1 {
+		factor = 1
+	}
+	return &backoff{
+		initial: initial,
+		max:     max,
+		factor:  factor,
+		rand:    rand.Int63n,
+	}
+}
+
+// ceiling() returns the upper bound of the next delay.
+func (b *backoff) ceiling() time.Duration {
+	d := float64(b.initial) * math.Pow(b.factor, float64(b.attempts))
+	if d > float64(b.max) || math.IsInf(d, 0) {
+		return b.max
+	}
+	return time.Duration(d)
+}
+
+// next() returns a random delay in [0, ceiling()) and advances the backoff.
+func (b *backoff) next() time.Duration {
+	ceil := b.ceiling()
+	b.attempts++
+	if ceil <= 0 {
+		return 0
+	}
+	return time.Duration(b.rand(int64(ceil)))
+}
+
+func (b *backoff) reset() {
+	b.attempts = 0
+}
@@ -0,0 +1,48 @@
+package kcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	b := newBackoff(time.Second, 10*time.Second, 2)
+
+	var bounds []int64
+	b.rand = func(n int64) int64 {
+		bounds = append(bounds, n)
+		return n - 1
+	}
+
+	for i := 0; i < 6; i++ {
+		assert.True(t, b.next() < 10*time.Second)
+	}
+
+	assert.Equal(t, []int64{
+		int64(time.Second),
+		int64(2 * time.Second),
+		int64(4 * time.Second),
+		int64(8 * time.Second),
+		int64(10 * time.Second),
+		int64(10 * time.Second),
+	}, bounds)
+
+	b.reset()
+	assert.Equal(t, time.Second, b.ceiling())
+}
+
+func TestBackoff_defaults(t *testing.T) {
+	b := newBackoff(0, 0, 0)
+	assert.Equal(t, defaultBackoffInitial, b.ceiling())
+	b.next()
+	assert.Equal(t, defaultBackoffInitial, b.ceiling())
+
+	b = newBackoff(time.Second, time.Minute, 2)
+	for i := 0; i < 100; i++ {
+		d := b.next()
+		assert.True(t, d >= 0 && d < time.Minute)
+	}
+	assert.Equal(t, time.Minute, b.ceiling())
+}
@@ -3,21 +3,136 @@ package kcache
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	lifecycle "github.com/boz/go-lifecycle"
 	logutil "github.com/boz/go-logutil"
 	"github.com/boz/kcache/client"
+	"github.com/boz/kcache/clock"
 	"github.com/boz/kcache/filter"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	toolscache "k8s.io/client-go/tools/cache"
 )
 
 type Builder interface {
 	Context(context.Context) Builder
+
+	// Log() sets the logger used by the controller and everything it
+	// creates.  Any logutil.Log implementation may be used to route
+	// output into another logging system.
 	Log(logutil.Log) Builder
 
 	Filter(filter.Filter) Builder
 
+	// FieldSelector() sets a field selector that is sent with every list
+	// and watch request, so that the API server omits objects which the
+	// controller would otherwise receive only to discard.  Objects that
+	// stop matching are delivered as delete events.  Filter() may be
+	// used in addition to it.
+	//
+	// Every resource supports metadata.name and metadata.namespace; the
+	// other supported fields depend on the resource and the version of
+	// the API server.  For example, pods support spec.nodeName,
+	// spec.restartPolicy, spec.schedulerName, spec.serviceAccountName,
+	// status.phase, status.podIP and status.nominatedNodeName; nodes
+	// support spec.unschedulable; secrets support type; and events
+	// support involvedObject.*, reason, source and type.  The API server
+	// rejects requests with any other field as a bad request, which is
+	// fatal: the controller shuts down without becoming ready, Error()
+	// reports the rejection, and WaitForSync() returns false.
+	FieldSelector(fields.Selector) Builder
+
+	// LabelSelector() sets a label selector that is sent with every list
+	// and watch request, so that the cache holds only matching objects.
+	// Objects that stop matching are delivered as delete events.
+	// Subscriptions may be narrowed further by Labels() and other
+	// filters.
+	LabelSelector(labels.Selector) Builder
+
+	// ResyncPeriod() sets the interval at which the full contents of
+	// the cache are re-delivered to subscribers as update events.
+	// Zero (the default) disables resync.
+	ResyncPeriod(time.Duration) Builder
+
+	// Index() adds an index to the controller's cache, available through
+	// CacheReader.ByIndex().  Filtered subscriptions inherit the index.
+	// NamespaceIndex is always present and cannot be replaced.
+	Index(name string, fn IndexFunc) Builder
+
+	// Transform() sets a function that is applied once to each object
+	// received from the API server.  The cache, subscriptions, and
+	// events hold the transformed objects.  See TransformFunc.
+	Transform(TransformFunc) Builder
+
+	// Metrics() installs instrumentation callbacks for the controller
+	// and its subscriptions.
+	Metrics(Metrics) Builder
+
+	// PanicHandler() sets a function that is called with an error
+	// describing each panic recovered from a user-supplied function run by
+	// the controller or its clones:
+	//
+	//	- a Transform() function: the object is used untransformed.
+	//	- a Publisher.OnEvent() callback.
+	//	- the ObjectLimit() callback.
+	//	- the Accept() method of a Filter() or of a subscription's or
+	//	  clone's filter, when evaluated by its cache: the object is
+	//	  rejected.
+	//	- a SubscribeChanged() change function: the update is delivered.
+	//	- the methods of a Handler passed to NewMonitor().
+	//
+	// Panics are logged whether or not a handler is set, and the
+	// controller continues.  Filters evaluated by a FilterCacheReader run
+	// on the caller's goroutine and are not recovered.  The handler is
+	// called on the goroutine that recovered the panic: it must not block.
+	PanicHandler(func(error)) Builder
+
+	// Clock() sets the clock that times resync, list refresh, watch
+	// reconnection backoff, Shutdown(), and the timers of subscriptions
+	// and Settler, so that tests can drive them with a clock.Fake.  The
+	// default is clock.Real().  See also filter.CreatedWithinClock().
+	Clock(clock.Clock) Builder
+
+	// ReplayBuffer() sets the number of recently published events that
+	// the controller retains for Publisher.SubscribeWithReplay().  Resync
+	// events are not retained.  Zero (the default) retains none.
+	ReplayBuffer(size int) Builder
+
+	// FilteredCache() restricts the controller's cache to objects
+	// accepted by the filter of at least one of its subscribers, in
+	// addition to Filter().  Subscriptions without a filter, and clones,
+	// are interested in every object.  Until the first subscription is
+	// created the cache is empty.
+	//
+	// Objects that no subscriber accepts are evicted when subscribers
+	// are closed or refiltered.  Creating a filtered subscription or
+	// refiltering one may broaden the cache, so either relists from the
+	// API server; a new subscription may become ready before the relist
+	// completes and receives create events for the objects it adds.
+	FilteredCache() Builder
+
+	// ObjectLimit() sets a limit on the number of objects in the cache,
+	// to catch runaway caches such as an unintended cluster-wide watch.
+	// Each time the cache grows beyond max objects a warning is logged
+	// and onExceed, if not nil, is called with the number of objects.
+	// onExceed is called on the controller's goroutine: it must not
+	// block.  The cache is not limited.
+	ObjectLimit(max int, onExceed func(int)) Builder
+
 	Client(client.Client) Builder
+
+	// Informer() sets the client to client.ForInformer(informer), so
+	// that the controller shares the informer's watch, and adds an index
+	// for each of the informer's indexers.  The informer must be run by
+	// the caller.  FieldSelector() and LabelSelector() can't be used
+	// with an informer.
+	Informer(toolscache.SharedIndexInformer) Builder
+
 	Lister() ListerBuilder
 	Watcher() WatcherBuilder
 
@@ -26,20 +141,36 @@ type Builder interface {
 
 type ListerBuilder interface {
 	RefreshPeriod(time.Duration) ListerBuilder
+
+	// PageSize() sets the maximum number of objects requested in each
+	// list call.  Lists are assembled from as many calls as necessary
+	// before they are used.  Zero (the default) lists everything in a
+	// single call.
+	PageSize(int) ListerBuilder
+
 	Client(client.ListClient) ListerBuilder
 }
 
 type WatcherBuilder interface {
 	Client(client.WatchClient) WatcherBuilder
+
+	// Backoff() sets the delay between watch reconnection attempts.
+	// The delay for attempt n is chosen at random from
+	// [0, min(max, initial * factor^n)).  The default is
+	// Backoff(time.Second, time.Minute, 2).
+	Backoff(initial, max time.Duration, factor float64) WatcherBuilder
 }
 
 func NewBuilder() Builder {
 	return &builder{
-		filter: filter.Null(),
-		log:    logutil.Default(),
-		ctx:    context.Background(),
-		lb:     newListerBuilder(),
-		wb:     newWatcherBuilder(),
+		filter:  filter.Null(),
+		log:     logutil.Default(),
+		ctx:     context.Background(),
+		metrics: nullMetrics{},
+		clock:   clock.Real(),
+		indexes: make(map[string]IndexFunc),
+		lb:      newListerBuilder(),
+		wb:      newWatcherBuilder(),
 	}
 }
 
@@ -49,6 +180,20 @@ type builder struct {
 	ctx    context.Context
 	filter filter.Filter
 
+	selectors listSelectors
+	informer  bool
+
+	resyncPeriod  time.Duration
+	replaySize    int
+	filteredCache bool
+	limit         *objectLimit
+
+	metrics   Metrics
+	onPanic   func(error)
+	clock     clock.Clock
+	indexes   map[string]IndexFunc
+	transform TransformFunc
+
 	lb *listerBuilder
 	wb *watcherBuilder
 }
@@ -68,9 +213,74 @@ func (b *builder) Filter(filter filter.Filter) Builder {
 	return b
 }
 
+func (b *builder) FieldSelector(selector fields.Selector) Builder {
+	b.selectors.fields = selector
+	return b
+}
+
+func (b *builder) LabelSelector(selector labels.Selector) Builder {
+	b.selectors.labels = selector
+	return b
+}
+
+func (b *builder) ResyncPeriod(period time.Duration) Builder {
+	b.resyncPeriod = period
+	return b
+}
+
+func (b *builder) Index(name string, fn IndexFunc) Builder {
+	b.indexes[name] = fn
+	return b
+}
+
+func (b *builder) Transform(fn TransformFunc) Builder {
+	b.transform = fn
+	return b
+}
+
+func (b *builder) Metrics(metrics Metrics) Builder {
+	b.metrics = metrics
+	return b
+}
+
+func (b *builder) PanicHandler(fn func(error)) Builder {
+	b.onPanic = fn
+	return b
+}
+
+func (b *builder) Clock(clock clock.Clock) Builder {
+	b.clock = clock
+	return b
+}
+
+func (b *builder) ReplayBuffer(size int) Builder {
+	b.replaySize = size
+	return b
+}
+
+func (b *builder) FilteredCache() Builder {
+	b.filteredCache = true
+	return b
+}
+
+func (b *builder) ObjectLimit(max int, onExceed func(int)) Builder {
+	b.limit = &objectLimit{max: max, onExceed: onExceed}
+	return b
+}
+
 func (b *builder) Client(client client.Client) Builder {
 	b.lb.Client(client)
 	b.wb.Client(client)
+	b.informer = false
+	return b
+}
+
+func (b *builder) Informer(informer toolscache.SharedIndexInformer) Builder {
+	b.Client(client.ForInformer(informer))
+	for name, fn := range informer.GetIndexer().GetIndexers() {
+		b.Index(name, informerIndexFunc(fn))
+	}
+	b.informer = true
 	return b
 }
 
@@ -87,25 +297,86 @@ func (b *builder) Create() (Controller, error) {
 		return nil, fmt.Errorf("kcache builder: log required")
 	}
 
+	if b.metrics == nil {
+		return nil, fmt.Errorf("kcache builder: metrics required")
+	}
+
+	if b.clock == nil {
+		return nil, fmt.Errorf("kcache builder: clock required")
+	}
+
+	if b.replaySize < 0 {
+		return nil, fmt.Errorf("kcache builder: invalid replay buffer size: %v", b.replaySize)
+	}
+
+	if b.limit != nil && b.limit.max < 0 {
+		return nil, fmt.Errorf("kcache builder: invalid object limit: %v", b.limit.max)
+	}
+
+	if b.informer && !b.selectors.empty() {
+		return nil, fmt.Errorf("kcache builder: selectors can't be applied to an informer")
+	}
+
+	// labels.Nothing() has no string form.
+	if ls := b.selectors.labels; ls != nil && !ls.Empty() && ls.String() == "" {
+		return nil, fmt.Errorf("kcache builder: label selector cannot be sent: %#v", ls)
+	}
+
 	log := b.log.WithComponent("controller")
 	ctx := b.ctx
 
+	lclient := b.selectors.listClient(b.lb.client)
+	wclient := b.selectors.watchClient(b.wb.client)
+
 	lc := lifecycle.New()
 
-	cache := newCache(ctx, log, lc.ShuttingDown(), b.filter)
+	var interest *interestTracker
+	cacheFilter := b.filter
+	if b.filteredCache {
+		interest = newInterestTracker()
+		cacheFilter = filter.And(b.filter, filter.Or())
+	}
+
+	panics := panicGuard{log, b.onPanic}
+	cache := newIndexedCache(ctx, log, lc.ShuttingDown(), cacheFilter, b.indexes, panics)
+	stats := newStatsRecorder(b.metrics, cache)
 	readych := make(chan struct{})
 
-	subscription := newSubscription(log, lc.ShuttingDown(), readych, cache)
-	publisher := newPublisher(log, subscription)
+	snapshotch := make(chan *snapshotMarker)
+	snapshotfn := sendSnapshotFn(snapshotch, lc.ShuttingDown())
+
+	version := &atomic.Value{}
+	version.Store("")
+	versionfn := func() string { return version.Load().(string) }
+
+	userFilter := b.filter
+	filterfn := func() filter.Filter { return userFilter }
+
+	subscription := newBufferedSubscription(log, lc.ShuttingDown(), lc.Error, snapshotfn, versionfn, filterfn, readych, cache, EventBufsiz, OverflowDropNewest, true, b.clock, stats)
+	publisher := newRootPublisher(log, subscription, b.replaySize, interest, b.clock, panics, stats)
 
 	c := &controller{
 		readych: readych,
 
 		subscription: subscription,
 		publisher:    publisher,
+		snapshotch:   snapshotch,
+		drainch:      make(chan *flushMarker),
+		version:      version,
+
+		filter:      b.filter,
+		cacheFilter: cacheFilter,
+		interest:    interest,
+
+		resyncPeriod: b.resyncPeriod,
+		clock:        b.clock,
+		transform:    panics.transform(b.transform),
+		limit:        b.limit.copy(panics),
+		metrics:      stats,
+		stats:        stats,
 
-		lister:  newLister(ctx, log, lc.ShuttingDown(), b.lb.period, b.lb.client),
-		watcher: newWatcher(ctx, log, lc.ShuttingDown(), b.wb.client),
+		lister:  newLister(ctx, log, lc.ShuttingDown(), b.lb.period, b.lb.pageSize, b.clock, lclient),
+		watcher: newWatcher(ctx, log, lc.ShuttingDown(), wclient, b.wb.newBackoff(), b.clock, stats),
 
 		cache: cache,
 
@@ -121,9 +392,53 @@ func (b *builder) Create() (Controller, error) {
 	return c, nil
 }
 
+// listSelectors are the selectors sent with every list and watch
+// request.
+type listSelectors struct {
+	fields fields.Selector
+	labels labels.Selector
+}
+
+func (s listSelectors) empty() bool {
+	return (s.fields == nil || s.fields.Empty()) &&
+		(s.labels == nil || s.labels.Empty())
+}
+
+func (s listSelectors) apply(opts *metav1.ListOptions) {
+	if s.fields != nil && !s.fields.Empty() {
+		opts.FieldSelector = s.fields.String()
+	}
+	if s.labels != nil && !s.labels.Empty() {
+		opts.LabelSelector = s.labels.String()
+	}
+}
+
+// listClient() returns a client whose requests to c carry the selectors.
+func (s listSelectors) listClient(c client.ListClient) client.ListClient {
+	if c == nil || s.empty() {
+		return c
+	}
+	return client.NewListClient(func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		s.apply(&opts)
+		return c.List(ctx, opts)
+	})
+}
+
+// watchClient() returns a client whose requests to c carry the selectors.
+func (s listSelectors) watchClient(c client.WatchClient) client.WatchClient {
+	if c == nil || s.empty() {
+		return c
+	}
+	return client.NewWatchClient(func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+		s.apply(&opts)
+		return c.Watch(ctx, opts)
+	})
+}
+
 type listerBuilder struct {
-	client client.ListClient
-	period time.Duration
+	client   client.ListClient
+	period   time.Duration
+	pageSize int
 }
 
 func newListerBuilder() *listerBuilder {
@@ -135,6 +450,11 @@ func (b *listerBuilder) RefreshPeriod(period time.Duration) ListerBuilder {
 	return b
 }
 
+func (b *listerBuilder) PageSize(size int) ListerBuilder {
+	b.pageSize = size
+	return b
+}
+
 func (b *listerBuilder) Client(client client.ListClient) ListerBuilder {
 	b.client = client
 	return b
@@ -142,10 +462,29 @@ func (b *listerBuilder) Client(client client.ListClient) ListerBuilder {
 
 type watcherBuilder struct {
 	client client.WatchClient
+
+	backoffInitial time.Duration
+	backoffMax     time.Duration
+	backoffFactor  float64
 }
 
 func newWatcherBuilder() *watcherBuilder {
-	return &watcherBuilder{}
+	return &watcherBuilder{
+		backoffInitial: defaultBackoffInitial,
+		backoffMax:     defaultBackoffMax,
+		backoffFactor:  defaultBackoffFactor,
+	}
+}
+
+func (b *watcherBuilder) Backoff(initial, max time.Duration, factor float64) WatcherBuilder {
+	b.backoffInitial = initial
+	b.backoffMax = max
+	b.backoffFactor = factor
+	return b
+}
+
+func (b *watcherBuilder) newBackoff() *backoff {
+	return newBackoff(b.backoffInitial, b.backoffMax, b.backoffFactor)
 }
 
 func (b *watcherBuilder) Client(client client.WatchClient) WatcherBuilder {
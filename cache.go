@@ -3,18 +3,34 @@ package kcache
 import (
 	"context"
 	"strconv"
+	"sync/atomic"
 
 	lifecycle "github.com/boz/go-lifecycle"
 	logutil "github.com/boz/go-logutil"
 	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/nsname"
 	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// CacheReader provides access to cached objects.  Returned objects are
+// shared with subscribers and must not be modified.
+//
+// Get(), GetObject(), and GetByKey() return ErrNotFound, never a nil
+// object and nil error, if the object is not in the cache.
 type CacheReader interface {
 	GetObject(obj metav1.Object) (metav1.Object, error)
 	Get(ns string, name string) (metav1.Object, error)
+
+	// GetByKey() returns the object with the given "namespace/name" key.
+	// The key of a cluster-scoped object is its name.
+	GetByKey(key string) (metav1.Object, error)
+
 	List() ([]metav1.Object, error)
+
+	// ByIndex() returns the objects whose index values for the named
+	// index include value.
+	ByIndex(name string, value string) ([]metav1.Object, error)
 }
 
 type cache interface {
@@ -22,6 +38,12 @@ type cache interface {
 	sync([]metav1.Object) ([]Event, error)
 	update(Event) ([]Event, error)
 	refilter([]metav1.Object, filter.Filter) ([]Event, error)
+	addIndex(string, IndexFunc) error
+
+	// count() returns the number of objects in the cache without
+	// waiting for the cache's loop.
+	count() int
+
 	Done() <-chan struct{}
 	Error() error
 }
@@ -51,6 +73,23 @@ type updateRequest struct {
 	resultch chan<- []Event
 }
 
+type indexRequest struct {
+	name     string
+	value    string
+	resultch chan<- indexResult
+}
+
+type addIndexRequest struct {
+	name     string
+	fn       IndexFunc
+	resultch chan<- error
+}
+
+type indexResult struct {
+	list []metav1.Object
+	err  error
+}
+
 type refilterRequest struct {
 	list     []metav1.Object
 	filter   filter.Filter
@@ -58,15 +97,25 @@ type refilterRequest struct {
 }
 
 type _cache struct {
+	// number of items; accessed atomically.  first for 64-bit alignment.
+	size int64
+
 	filter     filter.Filter
 	syncch     chan syncRequest
 	updatech   chan updateRequest
 	refilterch chan refilterRequest
 
-	getch  chan getRequest
-	listch chan chan []metav1.Object
+	getch   chan getRequest
+	listch  chan chan []metav1.Object
+	indexch chan indexRequest
 
-	items map[cacheKey]cacheEntry
+	addindexch   chan addIndexRequest
+	indexfuncsch chan chan map[string]IndexFunc
+
+	items   map[cacheKey]cacheEntry
+	indexes map[string]*cacheIndex
+
+	panics panicGuard
 
 	log logutil.Log
 	lc  lifecycle.Lifecycle
@@ -74,21 +123,38 @@ type _cache struct {
 }
 
 func newCache(ctx context.Context, log logutil.Log, stopch <-chan struct{}, filter filter.Filter) cache {
+	return newIndexedCache(ctx, log, stopch, filter, nil, panicGuard{log: log})
+}
+
+// newIndexedCache() returns a cache that maintains an index for
+// each of the given index functions.  Panics in the filter are recovered
+// by panics.
+func newIndexedCache(ctx context.Context, log logutil.Log, stopch <-chan struct{}, filter filter.Filter, indexes map[string]IndexFunc, panics panicGuard) cache {
 	log = log.WithComponent("cache")
 
 	c := &_cache{
-		filter:     filter,
-		syncch:     make(chan syncRequest),
-		updatech:   make(chan updateRequest),
-		getch:      make(chan getRequest),
-		refilterch: make(chan refilterRequest),
-		listch:     make(chan chan []metav1.Object),
-		items:      make(map[cacheKey]cacheEntry),
-		log:        log,
-		lc:         lifecycle.New(),
-		ctx:        ctx,
+		filter:       filter,
+		syncch:       make(chan syncRequest),
+		updatech:     make(chan updateRequest),
+		getch:        make(chan getRequest),
+		refilterch:   make(chan refilterRequest),
+		listch:       make(chan chan []metav1.Object),
+		indexch:      make(chan indexRequest),
+		addindexch:   make(chan addIndexRequest),
+		indexfuncsch: make(chan chan map[string]IndexFunc),
+		items:        make(map[cacheKey]cacheEntry),
+		indexes:      make(map[string]*cacheIndex),
+		panics:       panics,
+		log:          log,
+		lc:           lifecycle.New(),
+		ctx:          ctx,
 	}
 
+	for name, fn := range indexes {
+		c.indexes[name] = newCacheIndex(fn)
+	}
+	c.indexes[NamespaceIndex] = newCacheIndex(namespaceIndexFunc)
+
 	go c.lc.WatchContext(ctx)
 	go c.lc.WatchChannel(stopch)
 	go c.run()
@@ -170,7 +236,52 @@ func (c *_cache) Get(ns, name string) (metav1.Object, error) {
 		return nil, errors.WithStack(ErrNotRunning)
 	case c.getch <- request:
 	}
-	return <-resultch, nil
+	if obj := <-resultch; obj != nil {
+		return obj, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (c *_cache) ByIndex(name, value string) ([]metav1.Object, error) {
+	resultch := make(chan indexResult, 1)
+	request := indexRequest{name, value, resultch}
+	select {
+	case <-c.lc.ShuttingDown():
+		return nil, errors.WithStack(ErrNotRunning)
+	case c.indexch <- request:
+	}
+	result := <-resultch
+	return result.list, result.err
+}
+
+func (c *_cache) addIndex(name string, fn IndexFunc) error {
+	resultch := make(chan error, 1)
+	request := addIndexRequest{name, fn, resultch}
+	select {
+	case <-c.lc.ShuttingDown():
+		return errors.WithStack(ErrNotRunning)
+	case c.addindexch <- request:
+	}
+	return <-resultch
+}
+
+// indexFuncs() returns the index functions used by the cache.
+func (c *_cache) indexFuncs() map[string]IndexFunc {
+	resultch := make(chan map[string]IndexFunc, 1)
+	select {
+	case <-c.lc.ShuttingDown():
+		return nil
+	case c.indexfuncsch <- resultch:
+	}
+	return <-resultch
+}
+
+func (c *_cache) GetByKey(key string) (metav1.Object, error) {
+	id, err := nsname.Parse(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid key")
+	}
+	return c.Get(id.Namespace, id.Name)
 }
 
 func (c *_cache) run() {
@@ -185,6 +296,12 @@ func (c *_cache) run() {
 			request.resultch <- c.doRefilter(request.list, request.filter)
 		case request := <-c.listch:
 			request <- c.doList()
+		case request := <-c.indexch:
+			request.resultch <- c.doByIndex(request.name, request.value)
+		case request := <-c.addindexch:
+			request.resultch <- c.doAddIndex(request.name, request.fn)
+		case request := <-c.indexfuncsch:
+			request <- c.doIndexFuncs()
 		case request := <-c.getch:
 			if entry, ok := c.items[request.key]; ok {
 				request.resultch <- entry.object
@@ -206,6 +323,59 @@ func (c *_cache) doList() []metav1.Object {
 	return result
 }
 
+func (c *_cache) doByIndex(name, value string) indexResult {
+	idx, ok := c.indexes[name]
+	if !ok {
+		return indexResult{err: errors.Errorf("unknown index: %v", name)}
+	}
+	keys := idx.values[value]
+	result := make([]metav1.Object, 0, len(keys))
+	for key := range keys {
+		result = append(result, c.items[key].object)
+	}
+	return indexResult{list: result}
+}
+
+func (c *_cache) doAddIndex(name string, fn IndexFunc) error {
+	if _, ok := c.indexes[name]; ok {
+		return errors.Errorf("index exists: %v", name)
+	}
+	idx := newCacheIndex(fn)
+	for key, entry := range c.items {
+		idx.update(key, entry.object)
+	}
+	c.indexes[name] = idx
+	return nil
+}
+
+func (c *_cache) doIndexFuncs() map[string]IndexFunc {
+	fns := make(map[string]IndexFunc, len(c.indexes))
+	for name, idx := range c.indexes {
+		fns[name] = idx.fn
+	}
+	return fns
+}
+
+func (c *_cache) count() int {
+	return int(atomic.LoadInt64(&c.size))
+}
+
+func (c *_cache) setItem(key cacheKey, entry cacheEntry) {
+	c.items[key] = entry
+	atomic.StoreInt64(&c.size, int64(len(c.items)))
+	for _, idx := range c.indexes {
+		idx.update(key, entry.object)
+	}
+}
+
+func (c *_cache) deleteItem(key cacheKey) {
+	delete(c.items, key)
+	atomic.StoreInt64(&c.size, int64(len(c.items)))
+	for _, idx := range c.indexes {
+		idx.remove(key)
+	}
+}
+
 func (c *_cache) doSync(list []metav1.Object) []Event {
 
 	var events []Event
@@ -227,17 +397,17 @@ func (c *_cache) doSync(list []metav1.Object) []Event {
 
 		current, found := c.items[key]
 
-		accept := c.filter.Accept(entry.object)
+		accept := c.accept(entry.object)
 
 		switch {
 		case accept && !found:
 			events = append(events, NewEvent(EventTypeCreate, entry.object))
-			c.items[key] = entry
+			c.setItem(key, entry)
 		case accept && current.version < entry.version:
 			events = append(events, NewEvent(EventTypeUpdate, entry.object))
-			c.items[key] = entry
+			c.setItem(key, entry)
 		case current.version >= entry.version:
-			if !c.filter.Accept(current.object) {
+			if !c.accept(current.object) {
 				continue
 			}
 		default:
@@ -251,13 +421,20 @@ func (c *_cache) doSync(list []metav1.Object) []Event {
 	for k, current := range c.items {
 		if _, ok := set[k]; !ok {
 			events = append(events, NewEvent(EventTypeDelete, current.object))
-			delete(c.items, k)
+			c.deleteItem(k)
 		}
 	}
 
 	return events
 }
 
+// accept() returns true if the cache's filter accepts obj.  An object
+// for which the filter panics is rejected.
+func (c *_cache) accept(obj metav1.Object) (accepted bool) {
+	defer c.panics.recover("filter")
+	return c.filter.Accept(obj)
+}
+
 func (c *_cache) doRefilter(list []metav1.Object, filter filter.Filter) []Event {
 	c.filter = filter
 	return c.doSync(list)
@@ -279,13 +456,13 @@ func (c *_cache) doUpdate(evt Event) []Event {
 
 	current, found := c.items[key]
 
-	accept := c.filter.Accept(entry.object)
+	accept := c.accept(entry.object)
 
 	switch evt.Type() {
 	case EventTypeDelete:
 		if found {
 			events = append(events, evt)
-			delete(c.items, key)
+			c.deleteItem(key)
 		}
 	default:
 		switch {
@@ -293,16 +470,16 @@ func (c *_cache) doUpdate(evt Event) []Event {
 			// do nothing
 		case accept && !found:
 			// create
-			events = append(events, NewEvent(EventTypeCreate, obj))
-			c.items[key] = entry
+			events = append(events, deriveEvent(EventTypeCreate, obj, evt))
+			c.setItem(key, entry)
 		case accept && current.version < entry.version:
 			// update
-			events = append(events, NewEvent(EventTypeUpdate, obj))
-			c.items[key] = entry
+			events = append(events, deriveEvent(EventTypeUpdate, obj, evt))
+			c.setItem(key, entry)
 		case !accept && current.version < entry.version:
 			// filter-delete
-			events = append(events, NewEvent(EventTypeDelete, obj))
-			delete(c.items, key)
+			events = append(events, deriveEvent(EventTypeDelete, obj, evt))
+			c.deleteItem(key)
 		}
 	}
 
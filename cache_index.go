@@ -0,0 +1,106 @@
+package kcache
+
+import (
+	"github.com/boz/kcache/filter"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NamespaceIndex is the name of an index of objects by namespace that
+// every cache maintains.
+const NamespaceIndex = "metadata.namespace"
+
+func namespaceIndexFunc(obj metav1.Object) []string {
+	return []string{obj.GetNamespace()}
+}
+
+// IndexFunc returns the index values for obj.  An object may have
+// any number of index values.
+type IndexFunc func(obj metav1.Object) []string
+
+// cacheIndex maps index values to the keys of the objects that have them.
+// cacheIndex is not safe for concurrent use; it is owned by the cache loop.
+type cacheIndex struct {
+	fn     IndexFunc
+	values map[string]map[cacheKey]struct{}
+	keys   map[cacheKey][]string
+}
+
+func newCacheIndex(fn IndexFunc) *cacheIndex {
+	return &cacheIndex{
+		fn:     fn,
+		values: make(map[string]map[cacheKey]struct{}),
+		keys:   make(map[cacheKey][]string),
+	}
+}
+
+func (idx *cacheIndex) update(key cacheKey, obj metav1.Object) {
+	idx.remove(key)
+
+	values := idx.fn(obj)
+	if len(values) == 0 {
+		return
+	}
+
+	idx.keys[key] = values
+	for _, value := range values {
+		set, ok := idx.values[value]
+		if !ok {
+			set = make(map[cacheKey]struct{})
+			idx.values[value] = set
+		}
+		set[key] = struct{}{}
+	}
+}
+
+func (idx *cacheIndex) remove(key cacheKey) {
+	for _, value := range idx.keys[key] {
+		set := idx.values[value]
+		delete(set, key)
+		if len(set) == 0 {
+			delete(idx.values, value)
+		}
+	}
+	delete(idx.keys, key)
+}
+
+// addCacheIndex() adds an index to cache.
+func addCacheIndex(cache CacheReader, name string, fn IndexFunc) error {
+	if cache, ok := cache.(interface {
+		addIndex(string, IndexFunc) error
+	}); ok {
+		return cache.addIndex(name, fn)
+	}
+	return errors.Errorf("indexes not supported by %T", cache)
+}
+
+// cacheIndexFuncs() returns the index functions of cache, if it has any.
+func cacheIndexFuncs(cache CacheReader) map[string]IndexFunc {
+	if cache, ok := cache.(interface {
+		indexFuncs() map[string]IndexFunc
+	}); ok {
+		return cache.indexFuncs()
+	}
+	return nil
+}
+
+// listForFilter() returns the objects of cache that may be accepted by f.
+// If f only accepts objects in particular namespaces, the objects are
+// read from the namespace index rather than listing the entire cache.
+func listForFilter(cache CacheReader, f filter.Filter) ([]metav1.Object, error) {
+	namespaces, ok := filter.NamespacesOf(f)
+	if !ok {
+		return cache.List()
+	}
+
+	var list []metav1.Object
+	for _, ns := range namespaces {
+		objs, err := cache.ByIndex(NamespaceIndex, ns)
+		if err != nil {
+			// index not supported by cache.
+			return cache.List()
+		}
+		list = append(list, objs...)
+	}
+	return list, nil
+}
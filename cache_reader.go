@@ -0,0 +1,62 @@
+package kcache
+
+import (
+	"github.com/boz/kcache/filter"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FilterCacheReader() returns a view of base that contains only the
+// objects accepted by f.  Objects are not copied; f is applied to the
+// contents of base on each call.  Objects that f rejects are reported as
+// ErrNotFound.
+func FilterCacheReader(base CacheReader, f filter.Filter) CacheReader {
+	return &filterCacheReader{base, f}
+}
+
+type filterCacheReader struct {
+	base   CacheReader
+	filter filter.Filter
+}
+
+func (c *filterCacheReader) GetObject(obj metav1.Object) (metav1.Object, error) {
+	return c.accept(c.base.GetObject(obj))
+}
+
+func (c *filterCacheReader) Get(ns string, name string) (metav1.Object, error) {
+	return c.accept(c.base.Get(ns, name))
+}
+
+func (c *filterCacheReader) GetByKey(key string) (metav1.Object, error) {
+	return c.accept(c.base.GetByKey(key))
+}
+
+func (c *filterCacheReader) List() ([]metav1.Object, error) {
+	return c.acceptList(c.base.List())
+}
+
+func (c *filterCacheReader) ByIndex(name string, value string) ([]metav1.Object, error) {
+	return c.acceptList(c.base.ByIndex(name, value))
+}
+
+func (c *filterCacheReader) accept(obj metav1.Object, err error) (metav1.Object, error) {
+	if err != nil {
+		return nil, err
+	}
+	if !c.filter.Accept(obj) {
+		return nil, ErrNotFound
+	}
+	return obj, nil
+}
+
+func (c *filterCacheReader) acceptList(objs []metav1.Object, err error) ([]metav1.Object, error) {
+	if err != nil {
+		return nil, err
+	}
+	result := make([]metav1.Object, 0, len(objs))
+	for _, obj := range objs {
+		if c.filter.Accept(obj) {
+			result = append(result, obj)
+		}
+	}
+	return result, nil
+}
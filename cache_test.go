@@ -2,10 +2,13 @@ package kcache
 
 import (
 	"context"
+	"sort"
+	"strconv"
 	"testing"
 
 	logutil "github.com/boz/go-logutil"
 	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/nsname"
 	"github.com/boz/kcache/testutil"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -126,11 +129,13 @@ func TestCache_update(t *testing.T) {
 	}
 
 	{
-		events, err := cache.update(testGenEvent(EventTypeCreate, "default", "pod-3", "5"))
+		evt := testGenEvent(EventTypeCreate, "default", "pod-3", "5")
+		events, err := cache.update(evt)
 		assert.NoError(t, err)
 		require.Len(t, events, 1)
 		assert.Equal(t, EventTypeCreate, events[0].Type())
 		assert.Equal(t, "pod-3", events[0].Resource().GetName())
+		assert.Equal(t, evt.ObservedAt(), events[0].ObservedAt())
 	}
 
 	list, err := cache.List()
@@ -301,3 +306,262 @@ func TestCache_lifecycle_stopch(t *testing.T) {
 	assert.Equal(t, ErrNotRunning, errors.Cause(err))
 	assert.Nil(t, obj)
 }
+
+func TestCache_index(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	byNS := func(obj metav1.Object) []string {
+		return []string{obj.GetNamespace()}
+	}
+
+	cache := newIndexedCache(ctx, logutil.Default(), nil, filter.Null(), map[string]IndexFunc{"ns": byNS}, panicGuard{log: logutil.Default()})
+
+	names := func(value string) []string {
+		objs, err := cache.ByIndex("ns", value)
+		require.NoError(t, err)
+		var names []string
+		for _, obj := range objs {
+			names = append(names, obj.GetName())
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	_, err := cache.sync([]metav1.Object{
+		testGenPod("a", "pod-1", "1"),
+		testGenPod("a", "pod-2", "2"),
+		testGenPod("b", "pod-1", "3"),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"pod-1", "pod-2"}, names("a"))
+	assert.Equal(t, []string{"pod-1"}, names("b"))
+	assert.Empty(t, names("c"))
+
+	_, err = cache.update(testGenEvent(EventTypeDelete, "a", "pod-2", "4"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pod-1"}, names("a"))
+
+	_, err = cache.sync([]metav1.Object{testGenPod("b", "pod-1", "3")})
+	require.NoError(t, err)
+	assert.Empty(t, names("a"))
+	assert.Equal(t, []string{"pod-1"}, names("b"))
+
+	_, err = cache.ByIndex("unknown", "a")
+	assert.Error(t, err)
+
+	// added indexes are built from the current contents.
+	require.NoError(t, cache.addIndex("name", func(obj metav1.Object) []string {
+		return []string{obj.GetName()}
+	}))
+	objs, err := cache.ByIndex("name", "pod-1")
+	require.NoError(t, err)
+	assert.Len(t, objs, 1)
+
+	assert.Error(t, cache.addIndex("ns", byNS))
+}
+
+func benchmarkCache(b *testing.B, size int) (cache, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	byNS := func(obj metav1.Object) []string {
+		return []string{obj.GetNamespace()}
+	}
+
+	cache := newIndexedCache(ctx, logutil.Default(), nil, filter.Null(), map[string]IndexFunc{"ns": byNS}, panicGuard{log: logutil.Default()})
+
+	list := make([]metav1.Object, 0, size)
+	for i := 0; i < size; i++ {
+		list = append(list, testGenPod(strconv.Itoa(i%100), strconv.Itoa(i), "1"))
+	}
+	if _, err := cache.sync(list); err != nil {
+		b.Fatal(err)
+	}
+	return cache, cancel
+}
+
+func BenchmarkCache_ByIndex(b *testing.B) {
+	cache, cancel := benchmarkCache(b, 10000)
+	defer cancel()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := cache.ByIndex("ns", "42"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCache_ListScan(b *testing.B) {
+	cache, cancel := benchmarkCache(b, 10000)
+	defer cancel()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		objs, err := cache.List()
+		if err != nil {
+			b.Fatal(err)
+		}
+		var result []metav1.Object
+		for _, obj := range objs {
+			if obj.GetNamespace() == "42" {
+				result = append(result, obj)
+			}
+		}
+	}
+}
+
+type listCountingReader struct {
+	CacheReader
+	lists int
+}
+
+func (r *listCountingReader) List() ([]metav1.Object, error) {
+	r.lists++
+	return r.CacheReader.List()
+}
+
+func TestListForFilter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := newCache(ctx, logutil.Default(), nil, filter.Null())
+
+	_, err := cache.sync([]metav1.Object{
+		testGenPod("a", "pod-1", "1"),
+		testGenPod("a", "pod-2", "2"),
+		testGenPod("b", "pod-1", "3"),
+		testGenPod("c", "pod-1", "4"),
+	})
+	require.NoError(t, err)
+
+	names := func(objs []metav1.Object) []string {
+		var names []string
+		for _, obj := range objs {
+			names = append(names, nsname.ForObject(obj).String())
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	reader := &listCountingReader{CacheReader: cache}
+
+	objs, err := listForFilter(reader, filter.NSName(nsname.New("a", "pod-1"), nsname.New("b", "")))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/pod-1", "a/pod-2", "b/pod-1"}, names(objs))
+
+	objs, err = listForFilter(reader, filter.Namespace("c", "d"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c/pod-1"}, names(objs))
+
+	assert.Equal(t, 0, reader.lists)
+
+	objs, err = listForFilter(reader, filter.NSName(nsname.New("", "pod-2")))
+	require.NoError(t, err)
+	assert.Len(t, objs, 4)
+	assert.Equal(t, 1, reader.lists)
+
+	// the namespace index is reserved.
+	assert.Error(t, cache.addIndex(NamespaceIndex, namespaceIndexFunc))
+}
+
+func TestCache_GetByKey(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := newCache(ctx, logutil.Default(), nil, filter.Null())
+
+	_, err := cache.sync([]metav1.Object{
+		testGenPod("a", "pod-1", "1"),
+		testGenPod("", "node-1", "2"),
+	})
+	require.NoError(t, err)
+
+	obj, err := cache.GetByKey("a/pod-1")
+	require.NoError(t, err)
+	if assert.NotNil(t, obj) {
+		assert.Equal(t, "pod-1", obj.GetName())
+	}
+
+	obj, err = cache.GetByKey("node-1")
+	require.NoError(t, err)
+	if assert.NotNil(t, obj) {
+		assert.Equal(t, "node-1", obj.GetName())
+	}
+
+	obj, err = cache.GetByKey("b/pod-1")
+	assert.Equal(t, ErrNotFound, err)
+	assert.True(t, IsNotFound(err))
+	assert.Nil(t, obj)
+
+	obj, err = cache.Get("b", "pod-1")
+	assert.Equal(t, ErrNotFound, err)
+	assert.Nil(t, obj)
+
+	obj, err = cache.GetObject(testGenPod("b", "pod-1", ""))
+	assert.Equal(t, ErrNotFound, err)
+	assert.Nil(t, obj)
+
+	for _, key := range []string{"", "a/", "a/b/c"} {
+		_, err := cache.GetByKey(key)
+		assert.Error(t, err, key)
+	}
+}
+
+func TestFilterCacheReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := newIndexedCache(ctx, logutil.Default(), nil, filter.Null(), map[string]IndexFunc{
+		"name": func(obj metav1.Object) []string { return []string{obj.GetName()} },
+	}, panicGuard{log: logutil.Default()})
+
+	_, err := cache.sync([]metav1.Object{
+		testGenPod("a", "pod-1", "1"),
+		testGenPod("b", "pod-1", "2"),
+	})
+	require.NoError(t, err)
+
+	reader := FilterCacheReader(cache, filter.Namespace("a"))
+
+	objs, err := reader.List()
+	require.NoError(t, err)
+	require.Len(t, objs, 1)
+	assert.Equal(t, "a", objs[0].GetNamespace())
+
+	objs, err = reader.ByIndex("name", "pod-1")
+	require.NoError(t, err)
+	assert.Len(t, objs, 1)
+
+	obj, err := reader.Get("a", "pod-1")
+	require.NoError(t, err)
+	assert.NotNil(t, obj)
+
+	obj, err = reader.Get("b", "pod-1")
+	assert.Equal(t, ErrNotFound, err)
+	assert.Nil(t, obj)
+
+	obj, err = reader.GetByKey("b/pod-1")
+	assert.Equal(t, ErrNotFound, err)
+	assert.Nil(t, obj)
+
+	obj, err = reader.GetObject(testGenPod("b", "pod-1", ""))
+	assert.Equal(t, ErrNotFound, err)
+	assert.Nil(t, obj)
+
+	obj, err = reader.Get("a", "missing")
+	assert.Equal(t, ErrNotFound, err)
+	assert.Nil(t, obj)
+
+	// changes to the underlying cache are visible.
+	_, err = cache.update(testGenEvent(EventTypeCreate, "a", "pod-2", "3"))
+	require.NoError(t, err)
+
+	objs, err = reader.List()
+	require.NoError(t, err)
+	assert.Len(t, objs, 2)
+
+	_, err = reader.GetByKey("a/")
+	assert.Error(t, err)
+}
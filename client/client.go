@@ -2,10 +2,16 @@ package client
 
 import (
 	"context"
+	"io"
 
+	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/apimachinery/pkg/runtime/serializer/streaming"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 )
@@ -55,6 +61,9 @@ type restRequester interface {
 	Get() *rest.Request
 }
 
+// ForResource() returns a client for the given resource in namespace ns.
+// Every request is scoped to ns, so only namespace-scoped permissions are
+// required; an empty ns lists and watches every namespace.
 func ForResource(
 	c restRequester, res string, ns string) Client {
 	return NewClient(
@@ -89,3 +98,78 @@ func makeResourceWatchFn(
 			Watch()
 	}
 }
+
+// ForDynamicResource() returns a client for the given resource that
+// produces *unstructured.Unstructured objects.
+//
+// The dynamic client does not accept a context; requests are not
+// cancelled when the context passed to List() or Watch() is done.
+func ForDynamicResource(
+	c dynamic.Interface, gvr schema.GroupVersionResource, ns string) Client {
+	rc := c.Resource(gvr).Namespace(ns)
+	return NewClient(
+		func(_ context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return rc.List(opts)
+		},
+		func(_ context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+			return rc.Watch(opts)
+		},
+	)
+}
+
+// ForSchemeResource() returns a client for the given resource that
+// decodes responses with s rather than with the codecs of c.  Objects
+// are of the concrete type registered in s for their kind, and lists are
+// of the type registered for the kind of exemplar with a "List" suffix.
+//
+// ForSchemeResource() returns an error if s does not recognize exemplar
+// or its list kind.
+func ForSchemeResource(
+	c restRequester, s *runtime.Scheme, exemplar runtime.Object, res string, ns string) (Client, error) {
+
+	kinds, _, err := s.ObjectKinds(exemplar)
+	if err != nil {
+		return nil, errors.Wrapf(err, "kind of %T", exemplar)
+	}
+	listKind := kinds[0].GroupVersion().WithKind(kinds[0].Kind + "List")
+	if !s.Recognizes(listKind) {
+		return nil, errors.Errorf("list kind %v not registered", listKind)
+	}
+
+	codec := json.NewSerializer(json.DefaultMetaFactory, s, s, false)
+
+	listFn := func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		body, err := c.Get().
+			Context(ctx).
+			Namespace(ns).
+			Resource(res).
+			VersionedParams(&opts, scheme.ParameterCodec).
+			SetHeader("Accept", runtime.ContentTypeJSON).
+			Do().
+			Raw()
+		if err != nil {
+			return nil, err
+		}
+		into, err := s.New(listKind)
+		if err != nil {
+			return nil, err
+		}
+		obj, _, err := codec.Decode(body, &listKind, into)
+		return obj, err
+	}
+
+	watchFn := func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+		return c.Get().
+			Context(ctx).
+			Prefix("watch").
+			Namespace(ns).
+			Resource(res).
+			VersionedParams(&opts, scheme.ParameterCodec).
+			SetHeader("Accept", runtime.ContentTypeJSON).
+			WatchWithSpecificDecoders(func(body io.ReadCloser) streaming.Decoder {
+				return streaming.NewDecoder(json.Framer.NewFrameReader(body), codec)
+			}, codec)
+	}
+
+	return NewClient(listFn, watchFn), nil
+}
@@ -0,0 +1,72 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/boz/kcache/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+func TestForResource_namespace(t *testing.T) {
+	var mtx sync.Mutex
+	var paths []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		paths = append(paths, r.URL.Path)
+		mtx.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&v1.PodList{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "PodList"}})
+	}))
+	defer srv.Close()
+
+	rc, err := rest.RESTClientFor(&rest.Config{
+		Host:    srv.URL,
+		APIPath: "/api",
+		ContentConfig: rest.ContentConfig{
+			GroupVersion:         &v1.SchemeGroupVersion,
+			NegotiatedSerializer: serializer.DirectCodecFactory{CodecFactory: scheme.Codecs},
+		},
+	})
+	require.NoError(t, err)
+
+	request := func(ns string) []string {
+		mtx.Lock()
+		paths = nil
+		mtx.Unlock()
+
+		c := client.ForResource(rc, "pods", ns)
+
+		_, err := c.List(context.Background(), metav1.ListOptions{})
+		require.NoError(t, err)
+
+		w, err := c.Watch(context.Background(), metav1.ListOptions{})
+		require.NoError(t, err)
+		w.Stop()
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		return append([]string(nil), paths...)
+	}
+
+	assert.Equal(t, []string{
+		"/api/v1/namespaces/tenant-a/pods",
+		"/api/v1/watch/namespaces/tenant-a/pods",
+	}, request("tenant-a"))
+
+	assert.Equal(t, []string{
+		"/api/v1/pods",
+		"/api/v1/watch/pods",
+	}, request(""))
+}
@@ -0,0 +1,208 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ForInformer() returns a client that lists the contents of informer's
+// store and watches the events delivered to its event handlers, so that
+// a controller can share the informer's watch rather than open its own.
+// The informer must be run by the caller; lists block until it has
+// synced.
+//
+// Each watch delivers the events that follow the most recent list, so
+// the client should be used by a single controller.  Selectors can't be
+// applied to the informer's objects: lists and watches with a label or
+// field selector fail.
+//
+// The client's event handler remains registered with informer for the
+// life of the informer.
+func ForInformer(informer cache.SharedInformer) Client {
+	c := &informerClient{
+		informer: informer,
+		watches:  make(map[*informerWatch]struct{}),
+	}
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.dispatch(watch.Added, obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			c.dispatch(watch.Modified, obj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			c.dispatch(watch.Deleted, obj)
+		},
+	})
+	return c
+}
+
+type informerClient struct {
+	informer cache.SharedInformer
+
+	watches map[*informerWatch]struct{}
+
+	// events since the last list, replayed to the next watch.
+	backlog   []watch.Event
+	recording bool
+
+	mtx sync.Mutex
+}
+
+func (c *informerClient) List(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+	if err := checkInformerOptions(opts); err != nil {
+		return nil, err
+	}
+
+	if !cache.WaitForCacheSync(ctx.Done(), c.informer.HasSynced) {
+		return nil, ctx.Err()
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	// the store is updated before handlers are notified: events recorded
+	// from here on are either newer than the list or repeat its objects.
+	c.backlog = nil
+	c.recording = true
+
+	list := &metav1.List{
+		ListMeta: metav1.ListMeta{ResourceVersion: c.informer.LastSyncResourceVersion()},
+	}
+	for _, obj := range c.informer.GetStore().List() {
+		if obj, ok := obj.(runtime.Object); ok {
+			list.Items = append(list.Items, runtime.RawExtension{Object: obj})
+		}
+	}
+	return list, nil
+}
+
+func (c *informerClient) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	if err := checkInformerOptions(opts); err != nil {
+		return nil, err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	w := newInformerWatch(ctx, c, c.backlog)
+	c.backlog = nil
+	c.recording = false
+	c.watches[w] = struct{}{}
+	return w, nil
+}
+
+func (c *informerClient) dispatch(etype watch.EventType, obj interface{}) {
+	robj, ok := obj.(runtime.Object)
+	if !ok {
+		return
+	}
+	evt := watch.Event{Type: etype, Object: robj}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.recording {
+		c.backlog = append(c.backlog, evt)
+	}
+	for w := range c.watches {
+		w.push(evt)
+	}
+}
+
+func (c *informerClient) remove(w *informerWatch) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	delete(c.watches, w)
+}
+
+func checkInformerOptions(opts metav1.ListOptions) error {
+	if opts.LabelSelector != "" || opts.FieldSelector != "" {
+		return errors.Errorf("informer client: selectors not supported: labels %q fields %q",
+			opts.LabelSelector, opts.FieldSelector)
+	}
+	return nil
+}
+
+// informerWatch queues events from the informer's handler, which must not
+// block, and delivers them in order.
+type informerWatch struct {
+	parent   *informerClient
+	resultch chan watch.Event
+
+	pending []watch.Event
+	notifch chan struct{}
+	mtx     sync.Mutex
+
+	stopch   chan struct{}
+	stopOnce sync.Once
+}
+
+func newInformerWatch(ctx context.Context, parent *informerClient, backlog []watch.Event) *informerWatch {
+	w := &informerWatch{
+		parent:   parent,
+		resultch: make(chan watch.Event),
+		pending:  backlog,
+		notifch:  make(chan struct{}, 1),
+		stopch:   make(chan struct{}),
+	}
+	go w.run(ctx)
+	return w
+}
+
+func (w *informerWatch) ResultChan() <-chan watch.Event {
+	return w.resultch
+}
+
+func (w *informerWatch) Stop() {
+	w.stopOnce.Do(func() { close(w.stopch) })
+}
+
+func (w *informerWatch) push(evt watch.Event) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	w.pending = append(w.pending, evt)
+	select {
+	case w.notifch <- struct{}{}:
+	default:
+	}
+}
+
+func (w *informerWatch) run(ctx context.Context) {
+	defer close(w.resultch)
+	defer w.parent.remove(w)
+
+	for {
+		w.mtx.Lock()
+		events := w.pending
+		w.pending = nil
+		w.mtx.Unlock()
+
+		for _, evt := range events {
+			select {
+			case w.resultch <- evt:
+			case <-w.stopch:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-w.notifch:
+		case <-w.stopch:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
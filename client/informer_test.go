@@ -0,0 +1,88 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/boz/kcache/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestForInformer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	genpod := func(name, vsn string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, ResourceVersion: vsn}}
+	}
+
+	fw := watch.NewFake()
+	lw := &cache.ListWatch{
+		ListFunc: func(metav1.ListOptions) (runtime.Object, error) {
+			return &v1.PodList{
+				ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+				Items:    []v1.Pod{*genpod("a", "1")},
+			}, nil
+		},
+		WatchFunc: func(metav1.ListOptions) (watch.Interface, error) {
+			return fw, nil
+		},
+	}
+	informer := cache.NewSharedIndexInformer(lw, &v1.Pod{}, 0, cache.Indexers{})
+	c := client.ForInformer(informer)
+
+	_, err := c.List(ctx, metav1.ListOptions{LabelSelector: "a=b"})
+	assert.Error(t, err)
+	_, err = c.Watch(ctx, metav1.ListOptions{FieldSelector: "metadata.name=a"})
+	assert.Error(t, err)
+
+	go informer.Run(ctx.Done())
+
+	lctx, lcancel := context.WithTimeout(ctx, 5*time.Second)
+	defer lcancel()
+	list, err := c.List(lctx, metav1.ListOptions{})
+	require.NoError(t, err)
+
+	items, err := meta.ExtractList(list)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "a", items[0].(*v1.Pod).Name)
+
+	// events between the list and the watch are delivered by the watch.
+	fw.Add(genpod("b", "2"))
+
+	w, err := c.Watch(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	defer w.Stop()
+
+	fw.Delete(genpod("a", "3"))
+
+	for _, expected := range []struct {
+		etype watch.EventType
+		name  string
+	}{{watch.Added, "b"}, {watch.Deleted, "a"}} {
+		select {
+		case evt := <-w.ResultChan():
+			assert.Equal(t, expected.etype, evt.Type)
+			assert.Equal(t, expected.name, evt.Object.(*v1.Pod).Name)
+		case <-time.After(time.Second):
+			require.Fail(t, "no event", "%v %v", expected.etype, expected.name)
+		}
+	}
+
+	w.Stop()
+	select {
+	case _, ok := <-w.ResultChan():
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		require.Fail(t, "watch not stopped")
+	}
+}
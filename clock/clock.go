@@ -0,0 +1,90 @@
+package clock
+
+import "time"
+
+// Clock is a source of time.  Real() returns a Clock backed by the time
+// package; NewFake() returns one that is advanced by hand for tests.
+type Clock interface {
+	Now() time.Time
+
+	// After() returns a channel that receives the current time once
+	// d has elapsed.  Use NewTimer() if the wait may be abandoned.
+	After(d time.Duration) <-chan time.Time
+
+	// NewTimer() returns a timer that delivers the current time once d
+	// has elapsed.
+	NewTimer(d time.Duration) Timer
+
+	// NewTicker() returns a ticker that delivers the current time every
+	// d.  Like a time.Ticker, it drops ticks for slow receivers.
+	NewTicker(d time.Duration) Ticker
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Timer is like a time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+
+	// Stop() prevents the timer from firing.  It returns false if the
+	// timer had already fired or been stopped.
+	Stop() bool
+
+	// Reset() changes the timer to fire once d has elapsed.  It must be
+	// called only on stopped or fired timers whose channel is drained.
+	Reset(d time.Duration)
+}
+
+// Real() returns a Clock backed by the time package.
+func Real() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t realTicker) Stop() {
+	t.ticker.Stop()
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+func (t realTimer) Reset(d time.Duration) {
+	t.timer.Reset(d)
+}
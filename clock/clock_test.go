@@ -0,0 +1,130 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/boz/kcache/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := clock.NewFake(start)
+
+	assert.Equal(t, start, c.Now())
+
+	fired := func(ch <-chan time.Time) (time.Time, bool) {
+		select {
+		case t := <-ch:
+			return t, true
+		default:
+			return time.Time{}, false
+		}
+	}
+
+	immediate := c.After(0)
+	now, ok := fired(immediate)
+	assert.True(t, ok)
+	assert.Equal(t, start, now)
+
+	after := c.After(2 * time.Second)
+	ticker := c.NewTicker(time.Second)
+	assert.Equal(t, 2, c.Waiters())
+
+	c.Advance(999 * time.Millisecond)
+	_, ok = fired(after)
+	assert.False(t, ok)
+	_, ok = fired(ticker.C())
+	assert.False(t, ok)
+
+	c.Advance(time.Millisecond)
+	now, ok = fired(ticker.C())
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(time.Second), now)
+	_, ok = fired(after)
+	assert.False(t, ok)
+
+	// slow receivers drop ticks.
+	c.Advance(2 * time.Second)
+	now, ok = fired(after)
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(2*time.Second), now)
+	now, ok = fired(ticker.C())
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(2*time.Second), now)
+	_, ok = fired(ticker.C())
+	assert.False(t, ok)
+
+	assert.Equal(t, start.Add(3*time.Second), c.Now())
+	assert.Equal(t, 1, c.Waiters())
+
+	ticker.Stop()
+	assert.Equal(t, 0, c.Waiters())
+	c.Advance(time.Minute)
+	_, ok = fired(ticker.C())
+	assert.False(t, ok)
+}
+
+func TestFakeTimer(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := clock.NewFake(start)
+
+	fired := func(timer clock.Timer) bool {
+		select {
+		case <-timer.C():
+			return true
+		default:
+			return false
+		}
+	}
+
+	timer := c.NewTimer(time.Second)
+	assert.Equal(t, 1, c.Waiters())
+
+	c.Advance(time.Second)
+	assert.True(t, fired(timer))
+	assert.Equal(t, 0, c.Waiters())
+	assert.False(t, timer.Stop())
+
+	timer.Reset(time.Second)
+	assert.Equal(t, 1, c.Waiters())
+	assert.True(t, timer.Stop())
+	assert.Equal(t, 0, c.Waiters())
+
+	c.Advance(time.Minute)
+	assert.False(t, fired(timer))
+
+	timer.Reset(0)
+	assert.True(t, fired(timer))
+	assert.Equal(t, 0, c.Waiters())
+}
+
+func TestReal(t *testing.T) {
+	c := clock.Real()
+
+	before := time.Now()
+	assert.False(t, c.Now().Before(before))
+
+	select {
+	case <-c.After(time.Millisecond):
+	case <-time.After(time.Second):
+		assert.Fail(t, "After() did not fire")
+	}
+
+	timer := c.NewTimer(time.Millisecond)
+	select {
+	case <-timer.C():
+	case <-time.After(time.Second):
+		assert.Fail(t, "timer did not fire")
+	}
+	assert.False(t, timer.Stop())
+
+	ticker := c.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	select {
+	case <-ticker.C():
+	case <-time.After(time.Second):
+		assert.Fail(t, "ticker did not fire")
+	}
+}
@@ -0,0 +1,167 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a Clock whose time only changes when Advance() is called.
+type Fake struct {
+	now     time.Time
+	waiters []*fakeWaiter
+	mtx     sync.Mutex
+}
+
+type fakeWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+
+	// non-zero for tickers.
+	period time.Duration
+}
+
+// NewFake() returns a Fake whose current time is now.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (c *Fake) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.now
+}
+
+func (c *Fake) After(d time.Duration) <-chan time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	w := &fakeWaiter{deadline: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.ch <- c.now
+		return w.ch
+	}
+	c.waiters = append(c.waiters, w)
+	return w.ch
+}
+
+func (c *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: c, waiter: &fakeWaiter{ch: make(chan time.Time, 1)}}
+	t.Reset(d)
+	return t
+}
+
+func (c *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	w := &fakeWaiter{deadline: c.now.Add(d), ch: make(chan time.Time, 1), period: d}
+	c.waiters = append(c.waiters, w)
+	return &fakeTicker{c, w}
+}
+
+// Advance() moves the current time forward by d, delivering the time to
+// every After() channel and ticker whose deadline has been reached, in
+// deadline order.  A ticker whose receiver is slow drops ticks.
+func (c *Fake) Advance(d time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	end := c.now.Add(d)
+
+	for {
+		sort.SliceStable(c.waiters, func(i, j int) bool {
+			return c.waiters[i].deadline.Before(c.waiters[j].deadline)
+		})
+		if len(c.waiters) == 0 || c.waiters[0].deadline.After(end) {
+			break
+		}
+
+		w := c.waiters[0]
+		c.now = w.deadline
+
+		select {
+		case w.ch <- c.now:
+		default:
+		}
+
+		if w.period > 0 {
+			w.deadline = w.deadline.Add(w.period)
+		} else {
+			c.waiters = c.waiters[1:]
+		}
+	}
+
+	c.now = end
+}
+
+// Waiters() returns the number of pending After() channels and timers,
+// and running tickers.  Tests may wait for it to change to learn that the code under
+// test is waiting on the clock.
+func (c *Fake) Waiters() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return len(c.waiters)
+}
+
+// remove() returns false if w was not waiting.
+func (c *Fake) remove(w *fakeWaiter) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for idx, other := range c.waiters {
+		if other == w {
+			c.waiters = append(c.waiters[:idx], c.waiters[idx+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTicker struct {
+	clock  *Fake
+	waiter *fakeWaiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.waiter.ch
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.remove(t.waiter)
+}
+
+type fakeTimer struct {
+	clock  *Fake
+	waiter *fakeWaiter
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.waiter.ch
+}
+
+func (t *fakeTimer) Stop() bool {
+	return t.clock.remove(t.waiter)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) {
+	c := t.clock
+	c.remove(t.waiter)
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	t.waiter.deadline = c.now.Add(d)
+	if d <= 0 {
+		select {
+		case t.waiter.ch <- c.now:
+		default:
+		}
+		return
+	}
+	c.waiters = append(c.waiters, t.waiter)
+}
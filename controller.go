@@ -3,38 +3,181 @@ package kcache
 import (
 	"context"
 	builtin_errors "errors"
+	"io"
+	"sync/atomic"
+	"time"
 
 	lifecycle "github.com/boz/go-lifecycle"
 	logutil "github.com/boz/go-logutil"
 	"github.com/boz/kcache/client"
+	"github.com/boz/kcache/clock"
 	"github.com/boz/kcache/filter"
 	"github.com/pkg/errors"
 )
 
 var (
 	ErrNotRunning = builtin_errors.New("Not running")
+
+	// ErrNotFound is returned by CacheReader when the requested object
+	// is not in the cache.  It is returned unwrapped; see IsNotFound().
+	ErrNotFound = builtin_errors.New("Not found")
 )
 
+// IsNotFound() returns true if err, or the cause of err, is ErrNotFound.
+func IsNotFound(err error) bool {
+	return errors.Cause(err) == ErrNotFound
+}
+
 type Publisher interface {
 	Subscribe() (Subscription, error)
+
+	// SubscribeWithBuffer() returns a subscription that buffers up to
+	// size events and handles overflow according to policy.  Subscribe()
+	// is equivalent to SubscribeWithBuffer(EventBufsiz, OverflowDropNewest).
+	SubscribeWithBuffer(size int, policy OverflowPolicy) (Subscription, error)
+
+	// SubscribeWithReplay() returns a subscription whose events begin
+	// with the events most recently published by the controller, followed
+	// by live events.  Replayed events are identified by Replayed().
+	//
+	// Events are retained only by controllers created with
+	// Builder.ReplayBuffer(); elsewhere SubscribeWithReplay() is
+	// equivalent to Subscribe().  Every retained event is buffered by the
+	// new subscription in addition to the usual EventBufsiz.
+	SubscribeWithReplay() (Subscription, error)
+
+	// SubscribeWithFilter() returns a subscription whose cache and events
+	// are restricted to objects accepted by the given filter.
+	//
+	// The filter is installed before the subscription becomes ready, so
+	// subscribers never observe objects that the filter rejects.
 	SubscribeWithFilter(filter.Filter) (FilterSubscription, error)
 	SubscribeForFilter() (FilterSubscription, error)
+
+	// SubscribeCoalesced() returns a subscription that collapses events
+	// for the same object that occur within window of each other.
+	//
+	// Events are buffered for window after the first buffered event and
+	// then the latest event for each object is delivered.  An object that
+	// is created and deleted within the window produces no events.
+	SubscribeCoalesced(window time.Duration) (Subscription, error)
+
+	// SubscribeChanged() returns a subscription that delivers an update
+	// event only if changed() reports a difference between the updated
+	// object and the last version of it delivered to the subscription.
+	// Create and delete events are always delivered.
+	//
+	// The first update to an object that the subscription has not
+	// delivered, such as one present when it became ready, is delivered;
+	// Snapshot() records its result as delivered.  Resync events repeat
+	// the delivered object and are suppressed unless changed() reports a
+	// difference between identical objects.  Updates are compared one at
+	// a time rather than coalesced: suppressed updates do not become the
+	// basis for later comparisons.
+	SubscribeChanged(changed filter.ChangeFunc) (Subscription, error)
+
+	// OnEvent() registers fn to be called with every event published
+	// after it returns, without the buffering of a subscription.  The
+	// returned function deregisters fn; fn is not called once it returns.
+	// It may be called more than once, and from within fn.
+	//
+	// fn is called synchronously on the publisher's goroutine, before the
+	// event is sent to subscriptions: it must not block, and must not
+	// call other methods of the controller.  A panic in fn is recovered
+	// and logged; see Builder.PanicHandler().
+	OnEvent(fn func(Event)) (func(), error)
+
+	// Clone() returns a controller that shares this publisher's cache
+	// and watch but has its own subscribers and lifecycle.  Closing a
+	// clone shuts down only the clone and its subscriptions; closing
+	// the original controller shuts down every clone.
 	Clone() (Controller, error)
+
+	// CloneWithFilter() returns a clone whose cache and events are
+	// restricted to objects accepted by the given filter.  The filter is
+	// evaluated once per event on behalf of all of the clone's
+	// subscribers; subscribers that share a filter should subscribe to a
+	// shared clone rather than each call SubscribeWithFilter().
 	CloneWithFilter(filter.Filter) (FilterController, error)
 	CloneForFilter() (FilterController, error)
 }
 
 type CacheController interface {
 	Cache() CacheReader
+
+	// Ready() is closed once the initial list has been loaded into
+	// Cache().  The initial list is not delivered as events; every event
+	// received from a subscription describes a change made after its
+	// Ready() channel was closed.
 	Ready() <-chan struct{}
+
+	// AddIndex() adds an index to Cache(), available through
+	// CacheReader.ByIndex().  The index is built from the current
+	// contents of the cache and maintained as it changes.
+	// Filtered subscriptions created afterwards inherit the index.
+	AddIndex(name string, fn IndexFunc) error
 }
 
 type Controller interface {
 	CacheController
 	Publisher
 	Done() <-chan struct{}
+
+	// Close() shuts down the controller and blocks until it is done.
+	// Close() may be called any number of times, from any goroutine.
 	Close()
 	Error() error
+
+	// Shutdown() stops the controller from accepting new events, waits
+	// until its subscribers have read every event that it accepted
+	// before Shutdown() was called, and then closes the controller.
+	// Events held back by coalesced subscriptions and RefilterWithRate()
+	// are delivered without further delay.  If ctx is done first, the
+	// controller is closed without waiting further and ctx.Err() is
+	// returned.
+	//
+	// A clone shares the watch of its parent: Shutdown() of a clone
+	// stops it from accepting events from its parent, waits for the
+	// clone's own subscribers, and leaves its parent running.
+	Shutdown(ctx context.Context) error
+
+	// WaitForSync() blocks until Ready() is closed, the controller is
+	// done, or ctx is done and returns true if the controller is ready.
+	// It may be called from any goroutine, and returns true immediately
+	// once the controller is ready.  A controller that shuts down before
+	// its initial list is loaded, for example because the list failed,
+	// is never ready; Error() describes why.
+	WaitForSync(ctx context.Context) bool
+
+	// Stats() returns a summary of the controller's state.  It does not
+	// block.  The stats of a clone are those of the controller that it
+	// was cloned from.
+	Stats() Stats
+
+	// Export() writes the contents of Cache() to w as described by the
+	// Export() function.
+	Export(w io.Writer) error
+
+	// ObjectCount() returns the number of objects in Cache().  It does
+	// not block; the count is updated by the cache as it changes.
+	ObjectCount() int
+
+	// SubscriberCount() returns the number of active subscriptions whose
+	// own filter is equal to f according to filter.FiltersEqual() once
+	// both are normalized by filter.Normalize().  A subscription's own
+	// filter is the one given to SubscribeWithFilter(), CloneWithFilter(),
+	// or the latest Refilter(), without the controller's Builder.Filter()
+	// that Subscription.Filter() includes; other subscriptions have
+	// filter.Null().  If f is nil or filter.Null(), every active
+	// subscription is counted, whatever its filter; otherwise
+	// subscriptions whose filter is not comparable are never counted.
+	//
+	// Subscriptions are counted as the controller sees them: a clone is
+	// a single subscription, whose filter is that of CloneWithFilter(),
+	// and the subscriptions of clones are not counted.  Once the
+	// subscriptions have shut down with the controller, SubscriberCount()
+	// returns zero.
+	SubscriberCount(f filter.Filter) int
 }
 
 func NewController(ctx context.Context, log logutil.Log, client client.Client) (Controller, error) {
@@ -57,6 +200,33 @@ type controller struct {
 	subscription subscription
 	publisher    Publisher
 
+	// snapshot markers from subscription
+	snapshotch chan *snapshotMarker
+
+	// stops the intake of new events; the marker follows the last event.
+	drainch chan *flushMarker
+
+	// resource version processed; stored before events are distributed.
+	version *atomic.Value
+
+	// the filter from Builder.Filter() and the filter of the cache, which
+	// also reflects the interest of subscribers with Builder.FilteredCache().
+	filter      filter.Filter
+	cacheFilter filter.Filter
+	interest    *interestTracker
+
+	// applied to the next list; set when the interest of subscribers broadens.
+	pendingFilter filter.Filter
+
+	resyncPeriod time.Duration
+	clock        clock.Clock
+
+	transform TransformFunc
+	limit     *objectLimit
+
+	metrics Metrics
+	stats   *statsRecorder
+
 	log logutil.Log
 	lc  lifecycle.Lifecycle
 	ctx context.Context
@@ -78,14 +248,54 @@ func (c *controller) Error() error {
 	return c.lc.Error()
 }
 
+func (c *controller) WaitForSync(ctx context.Context) bool {
+	return waitForSync(ctx, c.readych, c.Done())
+}
+
+func (c *controller) Stats() Stats {
+	return c.stats.stats()
+}
+
+func (c *controller) Export(w io.Writer) error {
+	return Export(c, w)
+}
+
+func (c *controller) ObjectCount() int {
+	return c.cache.count()
+}
+
+func (c *controller) SubscriberCount(f filter.Filter) int {
+	infos, err := c.subscribers()
+	if err != nil {
+		return 0
+	}
+	return countSubscribers(infos, f)
+}
+
+func (c *controller) subscribers() ([]subscriberInfo, error) {
+	return listSubscribers(c.publisher)
+}
+
 func (c *controller) Cache() CacheReader {
 	return c.cache
 }
 
+func (c *controller) AddIndex(name string, fn IndexFunc) error {
+	return c.cache.addIndex(name, fn)
+}
+
 func (c *controller) Subscribe() (Subscription, error) {
 	return c.publisher.Subscribe()
 }
 
+func (c *controller) SubscribeWithBuffer(size int, policy OverflowPolicy) (Subscription, error) {
+	return c.publisher.SubscribeWithBuffer(size, policy)
+}
+
+func (c *controller) SubscribeWithReplay() (Subscription, error) {
+	return c.publisher.SubscribeWithReplay()
+}
+
 func (c *controller) SubscribeWithFilter(f filter.Filter) (FilterSubscription, error) {
 	return c.publisher.SubscribeWithFilter(f)
 }
@@ -94,6 +304,14 @@ func (c *controller) SubscribeForFilter() (FilterSubscription, error) {
 	return c.publisher.SubscribeForFilter()
 }
 
+func (c *controller) SubscribeCoalesced(window time.Duration) (Subscription, error) {
+	return c.publisher.SubscribeCoalesced(window)
+}
+
+func (c *controller) SubscribeChanged(changed filter.ChangeFunc) (Subscription, error) {
+	return c.publisher.SubscribeChanged(changed)
+}
+
 func (c *controller) Clone() (Controller, error) {
 	return c.publisher.Clone()
 }
@@ -106,12 +324,75 @@ func (c *controller) CloneForFilter() (FilterController, error) {
 	return c.publisher.CloneForFilter()
 }
 
+// waitForSync() returns true once readych is closed, or false if donech
+// is closed or ctx is done first.
+func waitForSync(ctx context.Context, readych <-chan struct{}, donech <-chan struct{}) bool {
+	select {
+	case <-readych:
+		return true
+	default:
+	}
+	select {
+	case <-readych:
+		return true
+	case <-donech:
+		// ready may have been closed just before shutdown.
+		select {
+		case <-readych:
+			return true
+		default:
+			return false
+		}
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (c *controller) timeSource() clock.Clock {
+	return c.clock
+}
+
+func (c *controller) guard() panicGuard {
+	return guardOf(c.publisher)
+}
+
+// clocked is implemented by controllers to provide the clock set by
+// Builder.Clock().
+type clocked interface {
+	timeSource() clock.Clock
+}
+
+// clockOf() returns the clock of the controller that p belongs to, or
+// clock.Real() if it has none.
+func clockOf(p Publisher) clock.Clock {
+	if c, ok := p.(clocked); ok {
+		return c.timeSource()
+	}
+	return clock.Real()
+}
+
 func (c *controller) run() {
 	defer c.lc.ShutdownCompleted()
 	initialized := false
 
+	var resynch <-chan time.Time
+	if c.resyncPeriod > 0 {
+		ticker := c.clock.NewTicker(c.resyncPeriod)
+		defer ticker.Stop()
+		resynch = ticker.C()
+	}
+
+	draining := false
+
 mainloop:
 	for {
+		var listch <-chan listResult
+		var watchch <-chan Event
+		if !draining {
+			listch = c.lister.Result()
+			watchch = c.watcher.events()
+		}
+
 		select {
 
 		case err := <-c.lc.ShutdownRequest():
@@ -141,7 +422,15 @@ mainloop:
 			c.lc.ShutdownInitiated(errors.Wrap(err, "cache complete"))
 			break mainloop
 
-		case result := <-c.lister.Result():
+		case m := <-c.drainch:
+			c.log.Debugf("draining: no longer accepting events")
+			draining = true
+			resynch = nil
+			if err := c.subscription.send(m); err != nil {
+				m.done()
+			}
+
+		case result := <-listch:
 
 			if result.err != nil {
 				c.log.Errorf("lister error: %v", result.err)
@@ -164,8 +453,16 @@ mainloop:
 				c.lc.ShutdownInitiated(errors.Wrap(err, "extracting list"))
 				break mainloop
 			}
+			list = transformList(c.transform, list)
 
-			events, err := c.cache.sync(list)
+			var events []Event
+			if c.pendingFilter != nil {
+				c.log.Debugf("list: applying subscriber filters")
+				c.cacheFilter, c.pendingFilter = c.pendingFilter, nil
+				events, err = c.cache.refilter(list, c.cacheFilter)
+			} else {
+				events, err = c.cache.sync(list)
+			}
 			if err != nil {
 				c.log.Errorf("cache sync error: %v", err)
 				c.lc.ShutdownInitiated(err)
@@ -175,6 +472,10 @@ mainloop:
 			c.log.Debugf("list complete: version: %v, items: %v, events: %v",
 				version, len(list), len(events))
 
+			c.version.Store(version)
+			c.stats.synced(c.clock.Now())
+			c.limit.check(c.log, c.cache.count())
+
 			if !initialized {
 				c.log.Debugf("ready")
 				initialized = true
@@ -189,15 +490,56 @@ mainloop:
 				break mainloop
 			}
 
-		case evt := <-c.watcher.events():
+		case <-c.watcher.expired():
+			c.log.Debugf("watch expired: relisting")
+			c.lister.relist()
+
+		case <-c.interest.changed():
+			if err := c.updateInterest(); err != nil {
+				c.log.Errorf("interest: %v", err)
+				c.lc.ShutdownInitiated(errors.Wrap(err, "interest"))
+				break mainloop
+			}
+
+		case <-resynch:
+			if !initialized {
+				continue
+			}
+
+			list, err := c.cache.List()
+			if err != nil {
+				c.log.Errorf("resync: cache list error: %v", err)
+				c.lc.ShutdownInitiated(errors.Wrap(err, "resync"))
+				break mainloop
+			}
+
+			c.log.Debugf("resync: %v objects", len(list))
+
+			events := make([]Event, 0, len(list))
+			for _, obj := range list {
+				events = append(events, resyncEvent{NewEvent(EventTypeUpdate, obj)})
+			}
+			c.distributeEvents(events)
+
+		case m := <-c.snapshotch:
+			// listed in the same loop that updates the cache and
+			// distributes events, so that m marks the list's position.
+			m.list, m.err = c.cache.List()
+			if err := m.pop().send(m); err != nil {
+				m.fail(err)
+			}
+
+		case evt := <-watchch:
 			c.log.Debugf("update event: %v", evt)
 
-			events, err := c.cache.update(evt)
+			events, err := c.cache.update(transformEvent(c.transform, evt))
 			if err != nil {
 				c.log.Errorf("update event: cache update error %v", err)
 				c.lc.ShutdownInitiated(errors.Wrap(err, "updating cache"))
 				break mainloop
 			}
+			c.version.Store(evt.Resource().GetResourceVersion())
+			c.limit.check(c.log, c.cache.count())
 			c.distributeEvents(events)
 		}
 	}
@@ -209,7 +551,51 @@ mainloop:
 
 func (c *controller) distributeEvents(events []Event) {
 	for _, evt := range events {
+		c.metrics.EventPublished(evt.Type())
 		c.subscription.send(evt)
 	}
 	c.log.Debugf("distribute events: %v events", len(events))
 }
+
+// updateInterest() applies the union of the subscribers' filters to the
+// cache.  Objects that no subscriber accepts are evicted immediately;
+// a filter that may accept more objects is applied to the next list.
+func (c *controller) updateInterest() error {
+	union, broadened := c.interest.union()
+	f := filter.And(c.filter, union)
+
+	switch {
+	case broadened:
+		if c.pendingFilter == nil && filter.FiltersEqual(f, c.cacheFilter) {
+			return nil
+		}
+		c.log.Debugf("interest: relisting for %v", f)
+		c.pendingFilter = f
+		c.lister.relist()
+		return nil
+
+	case c.pendingFilter != nil:
+		c.pendingFilter = f
+		return nil
+
+	case filter.FiltersEqual(f, c.cacheFilter):
+		return nil
+	}
+
+	list, err := c.cache.List()
+	if err != nil {
+		return errors.Wrap(err, "cache list")
+	}
+
+	// evicted objects are not accepted by any subscriber; their
+	// deletions are not distributed.
+	evicted, err := c.cache.refilter(list, f)
+	if err != nil {
+		return errors.Wrap(err, "cache refilter")
+	}
+	c.cacheFilter = f
+	c.limit.check(c.log, c.cache.count())
+
+	c.log.Debugf("interest: evicted %v objects", len(evicted))
+	return nil
+}
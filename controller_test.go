@@ -2,19 +2,31 @@ package kcache
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/client"
 	"github.com/boz/kcache/client/mocks"
+	"github.com/boz/kcache/clock"
 	"github.com/boz/kcache/filter"
 	"github.com/boz/kcache/nsname"
 	"github.com/boz/kcache/testutil"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/watch"
 )
 
@@ -152,7 +164,7 @@ func TestController(t *testing.T) {
 		}
 
 		obj, err = c.Cache().Get(obj_b.GetNamespace(), obj_b.GetName())
-		assert.NoError(t, err, name)
+		assert.Equal(t, ErrNotFound, err, name)
 		assert.Nil(t, obj, name)
 
 	}
@@ -288,3 +300,1079 @@ func TestController(t *testing.T) {
 	testutil.AssertDone(t, "csub_ff", csub_ff)
 
 }
+
+func TestController_subscriptionError(t *testing.T) {
+	gr := schema.GroupResource{Resource: "pods"}
+
+	doTestControllerSubscriptionError(t, "forbidden",
+		apierrors.NewForbidden(gr, "", fmt.Errorf("denied")), apierrors.IsForbidden)
+
+	doTestControllerSubscriptionError(t, "gone",
+		apierrors.NewGone("expired"), apierrors.IsGone)
+}
+
+func doTestControllerSubscriptionError(t *testing.T, name string, listErr error, check func(error) bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	listch := make(chan time.Time)
+
+	client := &mocks.Client{}
+	client.On("List", mock.Anything, mock.AnythingOfType("v1.ListOptions")).
+		WaitUntil(listch).
+		Return((*v1.PodList)(nil), listErr)
+
+	controller, err := NewBuilder().
+		Context(ctx).
+		Client(client).
+		Create()
+	require.NoError(t, err, name)
+
+	sub, err := controller.Subscribe()
+	require.NoError(t, err, name)
+
+	sub_wf, err := controller.SubscribeWithFilter(filter.Null())
+	require.NoError(t, err, name)
+
+	clone, err := controller.Clone()
+	require.NoError(t, err, name)
+
+	csub, err := clone.Subscribe()
+	require.NoError(t, err, name)
+
+	close(listch)
+
+	testutil.AssertDone(t, name, controller)
+	testutil.AssertDone(t, name, sub)
+	testutil.AssertDone(t, name, sub_wf)
+	testutil.AssertDone(t, name, clone)
+	testutil.AssertDone(t, name, csub)
+
+	for _, err := range []error{controller.Error(), sub.Error(), sub_wf.Error(), clone.Error(), csub.Error()} {
+		if assert.Error(t, err, name) {
+			assert.True(t, check(errors.Cause(err)), "%v: %v", name, err)
+		}
+	}
+}
+
+func TestController_subscriptionError_close(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	listch := make(chan time.Time)
+
+	controller := testNewController(t, testContext(ctx), testList(&v1.PodList{}), testListWait(listch))
+
+	sub, err := controller.Subscribe()
+	require.NoError(t, err)
+
+	clone, err := controller.Clone()
+	require.NoError(t, err)
+
+	csub, err := clone.Subscribe()
+	require.NoError(t, err)
+
+	close(listch)
+	testutil.AssertReady(t, "controller", controller)
+
+	controller.Close()
+
+	testutil.AssertDone(t, "sub", sub)
+	testutil.AssertDone(t, "csub", csub)
+
+	assert.NoError(t, controller.Error())
+	assert.NoError(t, sub.Error())
+	assert.NoError(t, clone.Error())
+	assert.NoError(t, csub.Error())
+
+	// closing a subscription directly is also a clean close.
+	dsub := testNewController(t, testContext(ctx), testList(&v1.PodList{}), testListWait(listch))
+	s, err := dsub.Subscribe()
+	require.NoError(t, err)
+	s.Close()
+	testutil.AssertDone(t, "s", s)
+	assert.NoError(t, s.Error())
+	dsub.Close()
+}
+
+func TestController_Snapshot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventch := make(chan watch.Event, 100)
+
+	controller := testNewController(t, testContext(ctx), testEvents(eventch))
+	defer controller.Close()
+
+	sub, err := controller.Subscribe()
+	require.NoError(t, err)
+
+	clone, err := controller.Clone()
+	require.NoError(t, err)
+	csub, err := clone.Subscribe()
+	require.NoError(t, err)
+
+	fclone, err := controller.CloneWithFilter(filter.Null())
+	require.NoError(t, err)
+	fsub, err := fclone.Subscribe()
+	require.NoError(t, err)
+
+	subs := map[string]Subscription{"sub": sub, "csub": csub, "fsub": fsub}
+
+	for name, s := range subs {
+		testutil.AssertReady(t, name, s)
+	}
+
+	const count = 50
+
+	send := func(from, to int) {
+		for i := from; i < to; i++ {
+			eventch <- watch.Event{
+				Type:   watch.Added,
+				Object: testGenPod("ns", fmt.Sprintf("p%v", i), fmt.Sprintf("%v", i+2)),
+			}
+		}
+	}
+
+	send(0, count/2)
+
+	snapshots := make(map[string][]metav1.Object)
+	for name, s := range subs {
+		list, err := s.Snapshot()
+		require.NoError(t, err, name)
+		snapshots[name] = list
+	}
+
+	send(count/2, count)
+
+	// every object is either in the snapshot or delivered after it; never both.
+	for name, s := range subs {
+		seen := make(map[string]bool)
+		for _, obj := range snapshots[name] {
+			seen[obj.GetName()] = true
+		}
+		for len(seen) < count {
+			select {
+			case ev := <-s.Events():
+				require.Equal(t, EventTypeCreate, ev.Type(), name)
+				require.False(t, seen[ev.Resource().GetName()], "%v: duplicate %v", name, ev)
+				seen[ev.Resource().GetName()] = true
+			case <-testutil.Timerch(ctx, time.Second):
+				require.Fail(t, "missing events", "%v: %v/%v", name, len(seen), count)
+			}
+		}
+	}
+}
+
+func TestController_context(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	client := testutil.MockClient(&v1.PodList{}, make(chan watch.Event))
+
+	controller, err := NewController(ctx, logutil.Default(), client)
+	require.NoError(t, err)
+
+	sub, err := controller.Subscribe()
+	require.NoError(t, err)
+
+	testutil.AssertReady(t, "controller", controller)
+
+	cancel()
+
+	testutil.AssertDone(t, "controller", controller)
+	testutil.AssertDone(t, "sub", sub)
+	assert.Equal(t, context.Canceled, errors.Cause(controller.Error()))
+}
+
+func TestController_resync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	list := &v1.PodList{
+		ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+		Items:    []v1.Pod{*testGenPod("ns", "a", "1")},
+	}
+
+	controller := testNewController(t, testContext(ctx), testList(list),
+		testBuild(func(b Builder) { b.ResyncPeriod(20 * time.Millisecond) }))
+	defer controller.Close()
+
+	sub, err := controller.Subscribe()
+	require.NoError(t, err)
+
+	sub_wf, err := controller.SubscribeWithFilter(filter.Null())
+	require.NoError(t, err)
+
+	sub_nf, err := controller.SubscribeWithFilter(filter.All())
+	require.NoError(t, err)
+
+	for name, sub := range map[string]Subscription{"sub": sub, "sub_wf": sub_wf} {
+		testutil.AssertReady(t, name, sub)
+		for i := 0; i < 2; i++ {
+			select {
+			case ev := <-sub.Events():
+				assert.Equal(t, EventTypeUpdate, ev.Type(), name)
+				assert.Equal(t, "a", ev.Resource().GetName(), name)
+			case <-testutil.Timerch(ctx, time.Second):
+				require.Fail(t, "no resync event", name)
+			}
+		}
+	}
+
+	select {
+	case ev := <-sub_nf.Events():
+		assert.Fail(t, "resync event for filtered object", "%v", ev)
+	case <-testutil.Timerch(ctx, 50*time.Millisecond):
+	}
+}
+
+func TestController_clock(t *testing.T) {
+	list := &v1.PodList{
+		ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+		Items:    []v1.Pod{*testGenPod("ns", "a", "1")},
+	}
+
+	t.Run("resync", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		c := clock.NewFake(time.Now())
+
+		controller := testNewController(t, testContext(ctx), testList(list),
+			testBuild(func(b Builder) { b.Clock(c).ResyncPeriod(time.Hour) }))
+		defer controller.Close()
+
+		sub, err := controller.Subscribe()
+		require.NoError(t, err)
+		testutil.AssertReady(t, "sub", sub)
+
+		select {
+		case ev := <-sub.Events():
+			assert.Fail(t, "resync before period elapsed", "%v", ev)
+		case <-testutil.AsyncWaitch(ctx):
+		}
+
+		c.Advance(time.Hour)
+		select {
+		case ev := <-sub.Events():
+			assert.Equal(t, EventTypeUpdate, ev.Type())
+			assert.Equal(t, "a", ev.Resource().GetName())
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "no resync event")
+		}
+	})
+
+	t.Run("backoff", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		watchedch := make(chan struct{}, 2)
+
+		client := &mocks.Client{}
+		client.On("List", mock.Anything, mock.AnythingOfType("v1.ListOptions")).Return(list, nil)
+		client.On("Watch", mock.Anything, mock.AnythingOfType("v1.ListOptions")).
+			Return((*mocks.WatchInterface)(nil), apierrors.NewInternalError(fmt.Errorf("unavailable"))).
+			Run(func(mock.Arguments) { watchedch <- struct{}{} }).
+			Once()
+		client.On("Watch", mock.Anything, mock.AnythingOfType("v1.ListOptions")).
+			Return(testutil.MockWatch(make(chan watch.Event)), nil).
+			Run(func(mock.Arguments) { watchedch <- struct{}{} })
+
+		c := clock.NewFake(time.Now())
+
+		controller := testNewController(t, testContext(ctx), testClient(client), testBuild(func(b Builder) {
+			b.Clock(c).Watcher().Backoff(time.Minute, time.Minute, 1)
+		}))
+		defer controller.Close()
+
+		wait := func(t *testing.T) {
+			select {
+			case <-watchedch:
+			case <-testutil.Timerch(ctx, time.Second):
+				require.Fail(t, "no watch")
+			}
+		}
+
+		wait(t)
+		testWaitForClock(t, c, 1)
+
+		select {
+		case <-watchedch:
+			require.Fail(t, "retried before backoff elapsed")
+		case <-testutil.AsyncWaitch(ctx):
+		}
+
+		c.Advance(time.Minute)
+		wait(t)
+	})
+}
+
+func TestController_AddIndex(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	list := &v1.PodList{
+		ListMeta: metav1.ListMeta{ResourceVersion: "2"},
+		Items:    []v1.Pod{*testGenPod("a", "x", "1"), *testGenPod("b", "y", "2")},
+	}
+
+	controller := testNewController(t, testContext(ctx), testList(list))
+	defer controller.Close()
+
+	testutil.AssertReady(t, "controller", controller)
+
+	byNS := func(obj metav1.Object) []string {
+		return []string{obj.GetNamespace()}
+	}
+
+	sub, err := controller.Subscribe()
+	require.NoError(t, err)
+	require.NoError(t, sub.AddIndex("ns", byNS))
+	assert.Error(t, controller.AddIndex("ns", byNS))
+
+	objs, err := controller.Cache().ByIndex("ns", "a")
+	require.NoError(t, err)
+	assert.Len(t, objs, 1)
+
+	fsub, err := controller.SubscribeWithFilter(filter.NSName(nsname.New("a", "")))
+	require.NoError(t, err)
+	testutil.AssertReady(t, "fsub", fsub)
+
+	objs, err = fsub.Cache().ByIndex("ns", "a")
+	require.NoError(t, err)
+	assert.Len(t, objs, 1)
+
+	objs, err = fsub.Cache().ByIndex("ns", "b")
+	require.NoError(t, err)
+	assert.Len(t, objs, 0)
+}
+
+func TestController_Clone_close(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	controller := testNewController(t, testContext(ctx))
+
+	clone_a, err := controller.Clone()
+	require.NoError(t, err)
+	clone_b, err := controller.Clone()
+	require.NoError(t, err)
+
+	sub_b, err := clone_b.Subscribe()
+	require.NoError(t, err)
+
+	testutil.AssertReady(t, "sub_b", sub_b)
+
+	clone_a.Close()
+	testutil.AssertDone(t, "clone_a", clone_a)
+
+	testutil.AssertNotDone(t, "controller", controller)
+	testutil.AssertNotDone(t, "clone_b", clone_b)
+	testutil.AssertNotDone(t, "sub_b", sub_b)
+
+	controller.Close()
+	testutil.AssertDone(t, "controller", controller)
+	testutil.AssertDone(t, "clone_b", clone_b)
+	testutil.AssertDone(t, "sub_b", sub_b)
+}
+
+func TestController_transform(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	genpod := func(name, vsn string) *v1.Pod {
+		pod := testGenPod("ns", name, vsn)
+		pod.Labels = map[string]string{"a": "b"}
+		pod.Spec.NodeName = "node"
+		return pod
+	}
+
+	eventch := make(chan watch.Event, 1)
+	list := &v1.PodList{
+		ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+		Items:    []v1.Pod{*genpod("a", "1")},
+	}
+
+	calls := 0
+	controller := testNewController(t, testContext(ctx), testEvents(eventch), testList(list), testBuild(func(b Builder) {
+		b.Transform(func(obj metav1.Object) metav1.Object {
+			calls++
+			pod := obj.(*v1.Pod)
+			return &v1.Pod{ObjectMeta: *pod.ObjectMeta.DeepCopy()}
+		})
+	}))
+	defer controller.Close()
+
+	sub_a, err := controller.Subscribe()
+	require.NoError(t, err)
+	sub_b, err := controller.Subscribe()
+	require.NoError(t, err)
+
+	testutil.AssertReady(t, "controller", controller)
+
+	obj, err := controller.Cache().Get("ns", "a")
+	require.NoError(t, err)
+	if assert.NotNil(t, obj) {
+		assert.Equal(t, "b", obj.GetLabels()["a"])
+		assert.Empty(t, obj.(*v1.Pod).Spec.NodeName)
+	}
+
+	eventch <- watch.Event{Type: watch.Added, Object: genpod("b", "2")}
+
+	for name, sub := range map[string]Subscription{"sub_a": sub_a, "sub_b": sub_b} {
+		select {
+		case ev := <-sub.Events():
+			assert.Equal(t, "b", ev.Resource().GetName(), name)
+			assert.Empty(t, ev.Resource().(*v1.Pod).Spec.NodeName, name)
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "no event", name)
+		}
+	}
+
+	// once per object, regardless of the number of subscribers.
+	assert.Equal(t, 2, calls)
+}
+
+func TestController_DeepCopyResource(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventch := make(chan watch.Event, 1)
+
+	controller := testNewController(t, testContext(ctx), testEvents(eventch))
+	defer controller.Close()
+
+	sub_a, err := controller.Subscribe()
+	require.NoError(t, err)
+	sub_b, err := controller.Subscribe()
+	require.NoError(t, err)
+
+	testutil.AssertReady(t, "controller", controller)
+
+	eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", "a", "2")}
+
+	recv := func(name string, sub Subscription) Event {
+		select {
+		case ev := <-sub.Events():
+			return ev
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "no event", name)
+			return nil
+		}
+	}
+
+	ev_a := recv("sub_a", sub_a)
+	ev_b := recv("sub_b", sub_b)
+
+	copy := ev_a.DeepCopyResource()
+	copy.SetLabels(map[string]string{"mutated": "true"})
+
+	assert.Empty(t, ev_a.Resource().GetLabels())
+	assert.Empty(t, ev_b.Resource().GetLabels())
+
+	obj, err := controller.Cache().Get("ns", "a")
+	require.NoError(t, err)
+	if assert.NotNil(t, obj) {
+		assert.Empty(t, obj.GetLabels())
+	}
+}
+
+func TestController_ResourceVersion(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventch := make(chan watch.Event, 1)
+	list := &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "5"}}
+
+	controller := testNewController(t, testContext(ctx), testEvents(eventch), testList(list))
+	defer controller.Close()
+
+	sub, err := controller.Subscribe()
+	require.NoError(t, err)
+	fsub, err := controller.SubscribeWithFilter(filter.Null())
+	require.NoError(t, err)
+
+	testutil.AssertReady(t, "sub", sub)
+	testutil.AssertReady(t, "fsub", fsub)
+
+	assert.Equal(t, "5", sub.ResourceVersion())
+	assert.Equal(t, "5", fsub.ResourceVersion())
+
+	eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", "a", "7")}
+
+	for name, sub := range map[string]Subscription{"sub": sub, "fsub": fsub} {
+		select {
+		case <-sub.Events():
+			assert.Equal(t, "7", sub.ResourceVersion(), name)
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "no event", name)
+		}
+	}
+}
+
+func TestController_subscriptionFilter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	list := &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "5"}}
+
+	assertFilter := func(t *testing.T, name string, expected filter.Filter, sub Subscription) {
+		assert.True(t, filter.FiltersEqual(expected, sub.Filter()), "%v: %v != %v", name, sub.Filter(), expected)
+	}
+
+	t.Run("unfiltered", func(t *testing.T) {
+		controller := testNewController(t, testContext(ctx), testList(list))
+		defer controller.Close()
+
+		sub, err := controller.Subscribe()
+		require.NoError(t, err)
+		assertFilter(t, "sub", filter.Null(), sub)
+
+		csub, err := controller.SubscribeCoalesced(time.Millisecond)
+		require.NoError(t, err)
+		assertFilter(t, "csub", filter.Null(), csub)
+
+		fsub, err := controller.SubscribeWithFilter(filter.Namespace("a"))
+		require.NoError(t, err)
+		assertFilter(t, "fsub", filter.Namespace("a"), fsub)
+
+		require.NoError(t, fsub.Refilter(filter.Namespace("b")))
+		assertFilter(t, "fsub", filter.Namespace("b"), fsub)
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		base := filter.Labels(map[string]string{"app": "web"})
+
+		controller := testNewController(t, testContext(ctx), testList(list),
+			testBuild(func(b Builder) { b.Filter(base) }))
+		defer controller.Close()
+
+		sub, err := controller.Subscribe()
+		require.NoError(t, err)
+		assertFilter(t, "sub", base, sub)
+
+		clone, err := controller.CloneWithFilter(filter.Namespace("a"))
+		require.NoError(t, err)
+
+		csub, err := clone.Subscribe()
+		require.NoError(t, err)
+		assertFilter(t, "csub", filter.And(base, filter.Namespace("a")), csub)
+
+		fsub, err := clone.SubscribeWithFilter(filter.Name("x"))
+		require.NoError(t, err)
+		assertFilter(t, "fsub", filter.And(filter.And(base, filter.Namespace("a")), filter.Name("x")), fsub)
+
+		require.NoError(t, clone.Refilter(filter.Namespace("b")))
+		assertFilter(t, "csub", filter.And(base, filter.Namespace("b")), csub)
+	})
+}
+
+func TestController_eventOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const updates = 20
+	keys := []string{"a", "b", "c"}
+
+	eventch := make(chan watch.Event, len(keys)*(updates+2))
+
+	controller := testNewController(t, testContext(ctx), testEvents(eventch))
+	defer controller.Close()
+
+	sub, err := controller.Subscribe()
+	require.NoError(t, err)
+	fsub, err := controller.SubscribeWithFilter(filter.Null())
+	require.NoError(t, err)
+	csub, err := controller.SubscribeCoalesced(5 * time.Millisecond)
+	require.NoError(t, err)
+
+	testutil.AssertReady(t, "sub", sub)
+	testutil.AssertReady(t, "fsub", fsub)
+	testutil.AssertReady(t, "csub", csub)
+
+	// interleave events for all keys.
+	vsn := 1
+	send := func(et watch.EventType, name string) {
+		vsn++
+		eventch <- watch.Event{Type: et, Object: testGenPod("ns", name, strconv.Itoa(vsn))}
+	}
+	for _, key := range keys {
+		send(watch.Added, key)
+	}
+	for i := 0; i < updates; i++ {
+		for _, key := range keys {
+			send(watch.Modified, key)
+		}
+	}
+	for _, key := range keys {
+		send(watch.Deleted, key)
+	}
+
+	check := func(name string, sub Subscription, complete bool) {
+		last := make(map[string]int)
+		deleted := make(map[string]bool)
+
+		for len(deleted) < len(keys) {
+			select {
+			case ev := <-sub.Events():
+				key := ev.Resource().GetName()
+				vsn, err := strconv.Atoi(ev.Resource().GetResourceVersion())
+				require.NoError(t, err, name)
+
+				require.False(t, deleted[key], "%v: event for %v after delete", name, key)
+				require.True(t, vsn > last[key], "%v: %v: version %v after %v", name, key, vsn, last[key])
+
+				if complete {
+					switch {
+					case last[key] == 0:
+						assert.Equal(t, EventTypeCreate, ev.Type(), name)
+					case ev.Type() != EventTypeDelete:
+						assert.Equal(t, EventTypeUpdate, ev.Type(), name)
+						assert.Equal(t, last[key]+len(keys), vsn, "%v: %v: missing events", name, key)
+					}
+				}
+
+				last[key] = vsn
+				if ev.Type() == EventTypeDelete {
+					deleted[key] = true
+				}
+			case <-testutil.Timerch(ctx, time.Second):
+				if complete {
+					require.Fail(t, "missing events", name)
+				}
+				// coalesced subscriptions may never see some objects.
+				return
+			}
+		}
+	}
+
+	check("sub", sub, true)
+	check("fsub", fsub, true)
+	check("csub", csub, false)
+}
+
+func TestController_Ready(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventch := make(chan watch.Event, 1)
+	listch := make(chan time.Time, 1)
+
+	obj_a := testGenPod("ns", "a", "1")
+	obj_b := testGenPod("ns", "b", "2")
+	obj_c := testGenPod("ns", "c", "4")
+
+	list := &v1.PodList{
+		ListMeta: metav1.ListMeta{ResourceVersion: "3"},
+		Items:    []v1.Pod{*obj_a, *obj_b},
+	}
+
+	controller := testNewController(t, testContext(ctx), testEvents(eventch), testList(list), testListWait(listch))
+	defer controller.Close()
+
+	sub, err := controller.Subscribe()
+	require.NoError(t, err)
+	fsub, err := controller.SubscribeWithFilter(filter.Null())
+	require.NoError(t, err)
+	csub, err := controller.SubscribeCoalesced(time.Millisecond)
+	require.NoError(t, err)
+
+	// queued before the initial list completes.
+	eventch <- watch.Event{Type: watch.Added, Object: obj_c}
+
+	for name, sub := range map[string]Subscription{"sub": sub, "fsub": fsub, "csub": csub} {
+		select {
+		case ev := <-sub.Events():
+			require.Fail(t, "event before list", "%v: %v", name, ev)
+		case <-testutil.Timerch(ctx, 50*time.Millisecond):
+		}
+		testutil.AssertNotReady(t, name, sub)
+	}
+
+	listch <- time.Now()
+
+	for name, sub := range map[string]Subscription{"sub": sub, "fsub": fsub, "csub": csub} {
+		select {
+		case ev := <-sub.Events():
+			testutil.AssertReady(t, name, sub)
+			assert.Equal(t, EventTypeCreate, ev.Type(), name)
+			assert.Equal(t, obj_c.GetName(), ev.Resource().GetName(), name)
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "no live event", name)
+		}
+
+		objs, err := sub.Cache().List()
+		require.NoError(t, err)
+		assert.Len(t, objs, 3, name)
+	}
+}
+
+func TestController_Close_concurrent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	controller := testNewController(t, testContext(ctx))
+
+	clone, err := controller.Clone()
+	require.NoError(t, err)
+
+	sub, err := controller.Subscribe()
+	require.NoError(t, err)
+	fsub, err := controller.SubscribeWithFilter(filter.Null())
+	require.NoError(t, err)
+	csub, err := controller.SubscribeCoalesced(time.Millisecond)
+	require.NoError(t, err)
+
+	closeAll := func(name string, obj interface {
+		Close()
+		Done() <-chan struct{}
+	}) {
+		donech := obj.Done()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				obj.Close()
+			}()
+		}
+		wg.Wait()
+
+		testutil.AssertDone(t, name, obj)
+		obj.Close()
+
+		assert.Equal(t, donech, obj.Done(), name)
+	}
+
+	closeAll("sub", sub)
+	closeAll("fsub", fsub)
+	closeAll("csub", csub)
+	closeAll("clone", clone)
+	closeAll("controller", controller)
+}
+
+func TestController_listPages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	page := func(cont string, objs ...*v1.Pod) *v1.PodList {
+		list := &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "10", Continue: cont}}
+		for _, obj := range objs {
+			list.Items = append(list.Items, *obj)
+		}
+		return list
+	}
+
+	withContinue := func(cont string) interface{} {
+		return mock.MatchedBy(func(opts metav1.ListOptions) bool {
+			return opts.Limit == 2 && opts.Continue == cont
+		})
+	}
+
+	client := &mocks.Client{}
+	client.On("Watch", mock.Anything, mock.AnythingOfType("v1.ListOptions")).Return(testutil.MockWatch(make(chan watch.Event)), nil)
+	client.On("List", mock.Anything, withContinue("")).
+		Return(page("1", testGenPod("ns", "a", "1"), testGenPod("ns", "b", "2")), nil)
+	client.On("List", mock.Anything, withContinue("1")).
+		Return((*v1.PodList)(nil), apierrors.NewResourceExpired("expired")).Once()
+	client.On("List", mock.Anything, withContinue("1")).
+		Return(page("2", testGenPod("ns", "c", "3"), testGenPod("ns", "d", "4")), nil).Once()
+	client.On("List", mock.Anything, withContinue("2")).
+		Return(page("", testGenPod("ns", "e", "5")), nil)
+
+	controller := testNewController(t, testContext(ctx), testClient(client),
+		testBuild(func(b Builder) { b.Lister().PageSize(2) }))
+	defer controller.Close()
+
+	testutil.AssertReady(t, "controller", controller)
+
+	objs, err := controller.Cache().List()
+	require.NoError(t, err)
+
+	var names []string
+	for _, obj := range objs {
+		names = append(names, obj.GetName())
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, names)
+
+	sub, err := controller.Subscribe()
+	require.NoError(t, err)
+	assert.Equal(t, "10", sub.ResourceVersion())
+
+	client.AssertNumberOfCalls(t, "List", 5)
+}
+
+func TestController_selectors(t *testing.T) {
+
+	// asserts that list and watch requests match opts.
+	run := func(t *testing.T, configure func(Builder), matches func(metav1.ListOptions) bool) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		list := &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}}
+
+		watchedch := make(chan struct{}, 1)
+
+		client := &mocks.Client{}
+		client.On("List", mock.Anything, mock.MatchedBy(matches)).Return(list, nil)
+		client.On("Watch", mock.Anything, mock.MatchedBy(matches)).Return(testutil.MockWatch(make(chan watch.Event)), nil).Run(func(mock.Arguments) {
+			select {
+			case watchedch <- struct{}{}:
+			default:
+			}
+		})
+
+		controller := testNewController(t, testContext(ctx), testClient(client), testBuild(configure))
+		defer controller.Close()
+
+		testutil.AssertReady(t, "controller", controller)
+
+		select {
+		case <-watchedch:
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "no watch")
+		}
+	}
+
+	t.Run("fields", func(t *testing.T) {
+		configure := func(b Builder) {
+			b.FieldSelector(fields.OneTermEqualSelector("spec.nodeName", "node-a"))
+		}
+		run(t, configure, func(opts metav1.ListOptions) bool {
+			return opts.FieldSelector == "spec.nodeName=node-a" && opts.LabelSelector == ""
+		})
+	})
+
+	t.Run("labels", func(t *testing.T) {
+		configure := func(b Builder) {
+			b.LabelSelector(labels.SelectorFromSet(labels.Set{"app": "web"}))
+		}
+		run(t, configure, func(opts metav1.ListOptions) bool {
+			return opts.LabelSelector == "app=web" && opts.FieldSelector == ""
+		})
+	})
+
+	t.Run("both", func(t *testing.T) {
+		configure := func(b Builder) {
+			b.FieldSelector(fields.OneTermEqualSelector("spec.nodeName", "node-a")).
+				LabelSelector(labels.SelectorFromSet(labels.Set{"app": "web"}))
+		}
+		run(t, configure, func(opts metav1.ListOptions) bool {
+			return opts.LabelSelector == "app=web" && opts.FieldSelector == "spec.nodeName=node-a"
+		})
+	})
+
+	t.Run("everything", func(t *testing.T) {
+		configure := func(b Builder) {
+			b.FieldSelector(fields.Everything()).LabelSelector(labels.Everything())
+		}
+		run(t, configure, func(opts metav1.ListOptions) bool {
+			return opts.LabelSelector == "" && opts.FieldSelector == ""
+		})
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		listch := make(chan time.Time)
+
+		client := &mocks.Client{}
+		client.On("List", mock.Anything, mock.Anything).
+			Return((*v1.PodList)(nil), apierrors.NewBadRequest("field label not supported: spec.nodename")).
+			WaitUntil(listch)
+
+		controller := testNewController(t, testContext(ctx), testClient(client), testBuild(func(b Builder) {
+			b.FieldSelector(fields.OneTermEqualSelector("spec.nodename", "node-a"))
+		}))
+		defer controller.Close()
+
+		clone, err := controller.Clone()
+		require.NoError(t, err)
+
+		resultch := make(chan bool, 2)
+		go func() { resultch <- controller.WaitForSync(ctx) }()
+		go func() { resultch <- clone.WaitForSync(ctx) }()
+
+		close(listch)
+
+		for i := 0; i < 2; i++ {
+			select {
+			case ok := <-resultch:
+				assert.False(t, ok)
+			case <-testutil.Timerch(ctx, time.Second):
+				require.Fail(t, "WaitForSync() blocked")
+			}
+		}
+
+		testutil.AssertDone(t, "controller", controller)
+		testutil.AssertNotReady(t, "controller", controller)
+		err = controller.Error()
+		assert.True(t, apierrors.IsBadRequest(errors.Cause(err)), "%v", err)
+	})
+
+	t.Run("nothing", func(t *testing.T) {
+		_, err := NewBuilder().Client(&mocks.Client{}).LabelSelector(labels.Nothing()).Create()
+		assert.Error(t, err)
+	})
+}
+
+func TestController_watchErrors(t *testing.T) {
+	gr := schema.GroupResource{Resource: "pods"}
+
+	genList := func(vsn string, pods ...*v1.Pod) *v1.PodList {
+		list := &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: vsn}}
+		for _, pod := range pods {
+			list.Items = append(list.Items, *pod)
+		}
+		return list
+	}
+
+	withVersion := func(vsn string) interface{} {
+		return mock.MatchedBy(func(opts metav1.ListOptions) bool {
+			return opts.ResourceVersion == vsn
+		})
+	}
+
+	readEvent := func(t *testing.T, sub Subscription) Event {
+		select {
+		case evt, ok := <-sub.Events():
+			require.True(t, ok, "events closed")
+			return evt
+		case <-testutil.Timerch(context.Background(), time.Second):
+			require.Fail(t, "no event")
+			return nil
+		}
+	}
+
+	newController := func(t *testing.T, ctx context.Context, client client.Client) Controller {
+		return testNewController(t, testContext(ctx), testClient(client), testBuild(func(b Builder) {
+			b.Watcher().Backoff(time.Millisecond, time.Millisecond, 1)
+		}))
+	}
+
+	t.Run("fail", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		client := &mocks.Client{}
+		client.On("List", mock.Anything, mock.AnythingOfType("v1.ListOptions")).Return(genList("1"), nil)
+		client.On("Watch", mock.Anything, mock.AnythingOfType("v1.ListOptions")).
+			Return((*mocks.WatchInterface)(nil), apierrors.NewForbidden(gr, "", fmt.Errorf("denied")))
+
+		controller := newController(t, ctx, client)
+		defer controller.Close()
+
+		sub, err := controller.Subscribe()
+		require.NoError(t, err)
+
+		testutil.AssertDone(t, "controller", controller)
+		testutil.AssertDone(t, "sub", sub)
+
+		for _, err := range []error{controller.Error(), sub.Error()} {
+			if assert.Error(t, err) {
+				assert.True(t, apierrors.IsForbidden(errors.Cause(err)), "%v", err)
+			}
+		}
+		client.AssertNumberOfCalls(t, "Watch", 1)
+	})
+
+	t.Run("relist", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		expiredch := make(chan watch.Event, 1)
+		expiredch <- watch.Event{Type: watch.Error, Object: &apierrors.NewResourceExpired("too old").ErrStatus}
+
+		client := &mocks.Client{}
+		client.On("List", mock.Anything, mock.AnythingOfType("v1.ListOptions")).
+			Return(genList("1", testGenPod("ns", "a", "1")), nil).Once()
+		client.On("List", mock.Anything, mock.AnythingOfType("v1.ListOptions")).
+			Return(genList("5", testGenPod("ns", "a", "1"), testGenPod("ns", "b", "5")), nil)
+		client.On("Watch", mock.Anything, withVersion("1")).Return(testutil.MockWatch(expiredch), nil)
+		client.On("Watch", mock.Anything, withVersion("5")).Return(testutil.MockWatch(make(chan watch.Event)), nil)
+
+		controller := newController(t, ctx, client)
+		defer controller.Close()
+
+		sub, err := controller.Subscribe()
+		require.NoError(t, err)
+
+		evt := readEvent(t, sub)
+		assert.Equal(t, EventTypeCreate, evt.Type())
+		assert.Equal(t, "b", evt.Resource().GetName())
+
+		testutil.AssertNotDone(t, "controller", controller)
+		client.AssertNumberOfCalls(t, "List", 2)
+		client.AssertCalled(t, "Watch", mock.Anything, withVersion("5"))
+	})
+
+	t.Run("backoff", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		eventch := make(chan watch.Event, 1)
+		eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", "b", "2")}
+
+		client := &mocks.Client{}
+		client.On("List", mock.Anything, mock.AnythingOfType("v1.ListOptions")).Return(genList("1"), nil)
+		client.On("Watch", mock.Anything, withVersion("1")).
+			Return((*mocks.WatchInterface)(nil), fmt.Errorf("connection refused")).Once()
+		client.On("Watch", mock.Anything, withVersion("1")).Return(testutil.MockWatch(eventch), nil)
+
+		controller := newController(t, ctx, client)
+		defer controller.Close()
+
+		sub, err := controller.Subscribe()
+		require.NoError(t, err)
+
+		evt := readEvent(t, sub)
+		assert.Equal(t, "b", evt.Resource().GetName())
+
+		client.AssertNumberOfCalls(t, "List", 1)
+		client.AssertNumberOfCalls(t, "Watch", 2)
+	})
+}
+
+func TestController_WaitForSync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	listch := make(chan time.Time)
+
+	controller := testNewController(t, testContext(ctx), testListWait(listch))
+	defer controller.Close()
+
+	clone, err := controller.Clone()
+	require.NoError(t, err)
+
+	tctx, tcancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer tcancel()
+	assert.False(t, controller.WaitForSync(tctx))
+
+	const waiters = 5
+	resultch := make(chan bool, waiters*2)
+	for i := 0; i < waiters; i++ {
+		go func() { resultch <- controller.WaitForSync(ctx) }()
+		go func() { resultch <- clone.WaitForSync(ctx) }()
+	}
+
+	close(listch)
+
+	for i := 0; i < waiters*2; i++ {
+		select {
+		case ok := <-resultch:
+			assert.True(t, ok)
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "not synced")
+		}
+	}
+
+	// already synced.
+	cctx, ccancel := context.WithCancel(ctx)
+	ccancel()
+	assert.True(t, controller.WaitForSync(cctx))
+	assert.True(t, clone.WaitForSync(cctx))
+}
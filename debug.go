@@ -0,0 +1,214 @@
+package kcache
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/nsname"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// subscriberInfo describes one of a publisher's subscriptions.
+type subscriberInfo struct {
+	// Buffered is the number of events waiting to be read.
+	Buffered int `json:"buffered"`
+
+	// Capacity is the size of the subscription's event buffer.
+	Capacity int `json:"capacity"`
+
+	// Dropped is the number of events dropped because the buffer was full.
+	Dropped uint64 `json:"dropped"`
+
+	// Filter describes the filter of the subscription.
+	Filter string `json:"filter"`
+
+	// the filter given to the subscription that wraps the publisher's
+	// subscription, or filter.Null() if there is none.
+	filter filter.Filter
+}
+
+// filterRef reports the filter given to a subscription that wraps one of
+// a publisher's subscriptions, without that of the publisher.  It is set
+// once the wrapper is created.
+type filterRef struct {
+	fn  func() filter.Filter
+	mtx sync.Mutex
+}
+
+func (r *filterRef) set(fn func() filter.Filter) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.fn = fn
+}
+
+// get() returns the filter of the wrapper, or filter.Null() if r is nil
+// or not yet set.
+func (r *filterRef) get() filter.Filter {
+	if r == nil {
+		return filter.Null()
+	}
+	r.mtx.Lock()
+	fn := r.fn
+	r.mtx.Unlock()
+	if fn == nil {
+		return filter.Null()
+	}
+	return fn()
+}
+
+func (s *publisher) SubscriberCount(f filter.Filter) int {
+	infos, err := s.subscribers()
+	if err != nil {
+		return 0
+	}
+	return countSubscribers(infos, f)
+}
+
+// countSubscribers() returns the number of infos whose filter matches f
+// as described by Controller.SubscriberCount().
+func countSubscribers(infos []subscriberInfo, f filter.Filter) int {
+	if f == nil {
+		return len(infos)
+	}
+	f = filter.Normalize(f)
+	if filter.FiltersEqual(f, filter.Null()) {
+		return len(infos)
+	}
+
+	// hashable filters are only compared with those sharing their hash.
+	hf, hashable := f.(filter.HashableFilter)
+
+	count := 0
+	for _, info := range infos {
+		other := filter.Normalize(info.filter)
+		if ho, ok := other.(filter.HashableFilter); ok && hashable && ho.Hash() != hf.Hash() {
+			continue
+		}
+		if filter.FiltersEqual(f, other) {
+			count++
+		}
+	}
+	return count
+}
+
+type subscriberLister interface {
+	subscribers() ([]subscriberInfo, error)
+}
+
+// listSubscribers() returns the subscriptions of obj if it keeps track of
+// them.
+func listSubscribers(obj interface{}) ([]subscriberInfo, error) {
+	l, ok := obj.(subscriberLister)
+	if !ok {
+		return nil, errors.Errorf("subscribers not supported by %T", obj)
+	}
+	return l.subscribers()
+}
+
+type debugState struct {
+	Ready       bool             `json:"ready"`
+	Stats       Stats            `json:"stats"`
+	Subscribers []subscriberInfo `json:"subscribers"`
+	Objects     []metav1.Object  `json:"objects"`
+}
+
+// DebugHandler() returns an http.Handler that serves the state of c as
+// JSON: its Stats(), the filter and queue depth of each of its
+// subscriptions, and the contents of its cache.
+//
+// The objects served can be limited with a label selector given in the
+// "filter" query parameter (for example, "?filter=app=web").
+//
+// Subscriptions are listed as the controller sees them: a clone is a single
+// subscription, with the filter of CloneWithFilter().  The filter shown is
+// the one given to the subscription, as formatted by fmt.Sprint(), or
+// Null() for a subscription without one.
+func DebugHandler(c Controller) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveDebug(c, w, r)
+	})
+}
+
+func serveDebug(c Controller, w http.ResponseWriter, r *http.Request) {
+	f := filter.Null()
+	if expr := r.URL.Query().Get("filter"); expr != "" {
+		var err error
+		if f, err = filter.SelectorFromString(expr); err != nil {
+			http.Error(w, "invalid filter: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	state := debugState{
+		Stats:   c.Stats(),
+		Objects: []metav1.Object{},
+	}
+
+	select {
+	case <-c.Ready():
+		state.Ready = true
+	default:
+	}
+
+	subscribers, err := listSubscribers(c)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	state.Subscribers = subscribers
+
+	objs, err := c.Cache().List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, obj := range objs {
+		if f.Accept(obj) {
+			state.Objects = append(state.Objects, obj)
+		}
+	}
+	sortObjects(state.Objects)
+
+	buf, err := json.Marshal(state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf)
+}
+
+// Export() writes the contents of the cache of c, which may be a
+// controller or a subscription, to w as newline-delimited JSON: one
+// object per line, ordered by namespace and name.  The objects are those
+// of a single list of the cache, so the output is a point-in-time view;
+// the cache is not held while they are written.  Controller.Export()
+// is equivalent for controllers.
+func Export(c CacheController, w io.Writer) error {
+	objs, err := c.Cache().List()
+	if err != nil {
+		return errors.Wrap(err, "cache list")
+	}
+	sortObjects(objs)
+
+	enc := json.NewEncoder(w)
+	for _, obj := range objs {
+		if err := enc.Encode(obj); err != nil {
+			return errors.Wrapf(err, "encoding %v/%v", obj.GetNamespace(), obj.GetName())
+		}
+	}
+	return nil
+}
+
+// sortObjects() orders objs by namespace and name.
+func sortObjects(objs []metav1.Object) {
+	sort.Slice(objs, func(i, j int) bool {
+		return nsname.ForObject(objs[i]).Less(nsname.ForObject(objs[j]))
+	})
+}
@@ -0,0 +1,260 @@
+package kcache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDebugHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	genPod := func(ns, name, app string) v1.Pod {
+		pod := testGenPod(ns, name, "1")
+		pod.Labels = map[string]string{"app": app}
+		return *pod
+	}
+
+	list := &v1.PodList{
+		ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+		Items: []v1.Pod{
+			genPod("b", "web-1", "web"),
+			genPod("a", "db-1", "db"),
+			genPod("a", "web-2", "web"),
+		},
+	}
+
+	controller := testNewController(t, testContext(ctx), testList(list))
+	defer controller.Close()
+
+	_, err := controller.SubscribeWithBuffer(5, OverflowDropNewest)
+	require.NoError(t, err)
+	_, err = controller.SubscribeWithFilter(filter.Namespace("a"))
+	require.NoError(t, err)
+
+	testutil.AssertReady(t, "controller", controller)
+
+	server := httptest.NewServer(DebugHandler(controller))
+	defer server.Close()
+
+	type state struct {
+		Ready       bool             `json:"ready"`
+		Subscribers []subscriberInfo `json:"subscribers"`
+		Objects     []v1.Pod         `json:"objects"`
+	}
+
+	get := func(query string) (int, state) {
+		resp, err := http.Get(server.URL + query)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var st state
+		if resp.StatusCode == http.StatusOK {
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&st))
+		}
+		sort.Slice(st.Subscribers, func(i, j int) bool {
+			return st.Subscribers[i].Filter < st.Subscribers[j].Filter
+		})
+		return resp.StatusCode, st
+	}
+
+	names := func(st state) []string {
+		var names []string
+		for _, obj := range st.Objects {
+			names = append(names, obj.Namespace+"/"+obj.Name)
+		}
+		return names
+	}
+
+	status, st := get("/")
+	require.Equal(t, http.StatusOK, status)
+	assert.True(t, st.Ready)
+	assert.Equal(t, []string{"a/db-1", "a/web-2", "b/web-1"}, names(st))
+	assert.Equal(t, []subscriberInfo{
+		{Buffered: 0, Capacity: EventBufsiz, Filter: "Namespace(a)"},
+		{Buffered: 0, Capacity: 5, Filter: "Null()"},
+	}, st.Subscribers)
+
+	status, st = get("/?filter=app%3Dweb")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"a/web-2", "b/web-1"}, names(st))
+
+	status, _ = get("/?filter=app+in+(")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	controller.Close()
+	testutil.AssertDone(t, "controller", controller)
+
+	status, _ = get("/")
+	assert.Equal(t, http.StatusInternalServerError, status)
+}
+
+func TestExport(t *testing.T) {
+	sub, cache, readych := testNewSubscription(t, logutil.Default(), filter.Null())
+	defer sub.Close()
+
+	cache.update(testGenEvent(EventTypeCreate, "b", "x", "1"))
+	cache.update(testGenEvent(EventTypeCreate, "a", "y", "2"))
+	cache.update(testGenEvent(EventTypeCreate, "a", "x", "3"))
+	close(readych)
+	testutil.AssertReady(t, "sub", sub)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, Export(sub, buf))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 3)
+
+	var keys []string
+	for _, line := range lines {
+		var pod v1.Pod
+		require.NoError(t, json.Unmarshal([]byte(line), &pod))
+		keys = append(keys, pod.Namespace+"/"+pod.Name+"@"+pod.ResourceVersion)
+	}
+	assert.Equal(t, []string{"a/x@3", "a/y@2", "b/x@1"}, keys)
+
+	sub.Close()
+	testutil.AssertDone(t, "sub", sub)
+	testutil.AssertDone(t, "cache", cache)
+	assert.Error(t, Export(sub, &bytes.Buffer{}))
+}
+
+func TestController_Export(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	list := &v1.PodList{
+		ListMeta: metav1.ListMeta{ResourceVersion: "3"},
+		Items:    []v1.Pod{*testGenPod("b", "x", "1"), *testGenPod("a", "y", "2"), *testGenPod("a", "x", "3")},
+	}
+
+	controller := testNewController(t, testContext(ctx), testList(list))
+	defer controller.Close()
+	testutil.AssertReady(t, "controller", controller)
+
+	fclone, err := controller.CloneWithFilter(filter.Namespace("a"))
+	require.NoError(t, err)
+	testutil.AssertReady(t, "fclone", fclone)
+
+	export := func(name string, c Controller) []string {
+		buf := &bytes.Buffer{}
+		require.NoError(t, c.Export(buf), name)
+
+		var keys []string
+		for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			var pod v1.Pod
+			require.NoError(t, json.Unmarshal([]byte(line), &pod), name)
+			keys = append(keys, pod.Namespace+"/"+pod.Name)
+		}
+		return keys
+	}
+
+	assert.Equal(t, []string{"a/x", "a/y", "b/x"}, export("controller", controller))
+	assert.Equal(t, []string{"a/x", "a/y"}, export("fclone", fclone))
+}
+
+func TestController_SubscriberCount(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	controller := testNewController(t, testContext(ctx))
+	defer controller.Close()
+	testutil.AssertReady(t, "controller", controller)
+
+	waitCount := func(f filter.Filter, count int) {
+		timeout := testutil.Timerch(ctx, time.Second)
+		for controller.SubscriberCount(f) != count {
+			select {
+			case <-time.After(time.Millisecond):
+			case <-timeout:
+				require.Fail(t, "wrong count", "%v: %v != %v", f, controller.SubscriberCount(f), count)
+			}
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := controller.Subscribe()
+		require.NoError(t, err)
+	}
+
+	fsub1, err := controller.SubscribeWithFilter(filter.Namespace("a"))
+	require.NoError(t, err)
+	fsub2, err := controller.SubscribeWithFilter(filter.Namespace("a"))
+	require.NoError(t, err)
+
+	_, err = controller.SubscribeWithFilter(filter.FN(func(metav1.Object) bool { return true }))
+	require.NoError(t, err)
+
+	fclone, err := controller.CloneWithFilter(filter.Namespace("a"))
+	require.NoError(t, err)
+	_, err = fclone.Subscribe()
+	require.NoError(t, err)
+
+	clone, err := controller.Clone()
+	require.NoError(t, err)
+	_, err = clone.Subscribe()
+	require.NoError(t, err)
+
+	assert.Equal(t, 7, controller.SubscriberCount(filter.Null()))
+	assert.Equal(t, 7, controller.SubscriberCount(nil))
+	assert.Equal(t, 3, controller.SubscriberCount(filter.Namespace("a")))
+	assert.Equal(t, 3, controller.SubscriberCount(filter.Or(filter.Namespace("a"))))
+	assert.Equal(t, 0, controller.SubscriberCount(filter.Namespace("b")))
+	assert.Equal(t, 0, controller.SubscriberCount(filter.FN(func(metav1.Object) bool { return true })))
+
+	// clones count their own subscriptions.
+	assert.Equal(t, 1, clone.SubscriberCount(filter.Null()))
+	assert.Equal(t, 1, fclone.SubscriberCount(filter.Null()))
+
+	require.NoError(t, fsub2.Refilter(filter.Namespace("b")))
+	assert.Equal(t, 2, controller.SubscriberCount(filter.Namespace("a")))
+	assert.Equal(t, 1, controller.SubscriberCount(filter.Namespace("b")))
+
+	fsub1.Close()
+	waitCount(filter.Namespace("a"), 1)
+	waitCount(filter.Null(), 6)
+
+	controller.Close()
+	testutil.AssertDone(t, "controller", controller)
+	waitCount(filter.Null(), 0)
+}
+
+func TestController_SubscriberCount_builderFilter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	controller := testNewController(t, testContext(ctx), testBuild(func(b Builder) {
+		b.Filter(filter.Labels(map[string]string{"app": "web"}))
+	}))
+	defer controller.Close()
+	testutil.AssertReady(t, "controller", controller)
+
+	sub, err := controller.Subscribe()
+	require.NoError(t, err)
+	fsub, err := controller.SubscribeWithFilter(filter.Namespace("a"))
+	require.NoError(t, err)
+
+	// subscriptions are counted by their own filter, not the intersection
+	// with the controller's.
+	assert.NotEqual(t, filter.Namespace("a"), fsub.Filter())
+	assert.Equal(t, 1, controller.SubscriberCount(filter.Namespace("a")))
+	assert.Equal(t, 0, controller.SubscriberCount(fsub.Filter()))
+	assert.Equal(t, 0, controller.SubscriberCount(sub.Filter()))
+	assert.Equal(t, 2, controller.SubscriberCount(nil))
+}
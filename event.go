@@ -2,8 +2,10 @@ package kcache
 
 import (
 	"fmt"
+	"time"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 type EventType string
@@ -16,16 +18,34 @@ const (
 
 type Event interface {
 	Type() EventType
+
+	// Resource() returns the object, which is shared with the cache and
+	// with every other subscriber.  It must not be modified.
 	Resource() v1.Object
+
+	// DeepCopyResource() returns a copy of Resource() that may be modified.
+	DeepCopyResource() v1.Object
+
+	// ObservedAt() returns the time at which the controller observed
+	// the change, such as when it was received from the watch.
+	ObservedAt() time.Time
 }
 
 type event struct {
-	eventType EventType
-	resource  v1.Object
+	eventType  EventType
+	resource   v1.Object
+	observedAt time.Time
 }
 
+// NewEvent() returns an event observed now.
 func NewEvent(et EventType, resource v1.Object) Event {
-	return event{et, resource}
+	return event{et, resource, time.Now()}
+}
+
+// deriveEvent() returns an event for resource that retains the
+// observation time of evt.
+func deriveEvent(et EventType, resource v1.Object, evt Event) Event {
+	return event{et, resource, evt.ObservedAt()}
 }
 
 func (e event) Type() EventType {
@@ -36,7 +56,30 @@ func (e event) Resource() v1.Object {
 	return e.resource
 }
 
+func (e event) DeepCopyResource() v1.Object {
+	return deepCopyObject(e.resource)
+}
+
+func (e event) ObservedAt() time.Time {
+	return e.observedAt
+}
+
 func (e event) String() string {
 	return fmt.Sprintf(
 		"Event{%v %v/%v}", e.eventType, e.Resource().GetNamespace(), e.resource.GetName())
 }
+
+// resyncEvent is an update event for an unchanged object,
+// delivered when the controller resyncs.
+type resyncEvent struct {
+	Event
+}
+
+func deepCopyObject(obj v1.Object) v1.Object {
+	if robj, ok := obj.(runtime.Object); ok {
+		if copy, ok := robj.DeepCopyObject().(v1.Object); ok {
+			return copy
+		}
+	}
+	return obj
+}
@@ -0,0 +1,44 @@
+package filter
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Annotations() returns a filter which returns true if
+// the provided map is a subset of the object's annotations.
+func Annotations(match map[string]string) ComparableFilter {
+	return annotationsFilter(match)
+}
+
+type annotationsFilter map[string]string
+
+func (f annotationsFilter) Accept(obj metav1.Object) bool {
+	if len(f) == 0 {
+		return true
+	}
+
+	annotations := obj.GetAnnotations()
+
+	for k, v := range f {
+		if val, ok := annotations[k]; !ok || val != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (f annotationsFilter) Equals(other Filter) bool {
+	if other, ok := other.(annotationsFilter); ok {
+		return labels.Equals(labels.Set(f), labels.Set(other))
+	}
+	return false
+}
+
+func (f annotationsFilter) String() string {
+	return "Annotations(" + labels.Set(f).String() + ")"
+}
+
+func (f annotationsFilter) Hash() uint64 {
+	return hashString(f.String())
+}
@@ -0,0 +1,49 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/boz/kcache/filter"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAnnotations(t *testing.T) {
+
+	target := map[string]string{"a": "1"}
+	tsuper := map[string]string{"a": "1", "b": "2"}
+	tmiss := map[string]string{"a": "2"}
+
+	f := filter.Annotations(target)
+	fnil := filter.Annotations(nil)
+	fempty := filter.Annotations(map[string]string{})
+
+	gen := func(annotations map[string]string) metav1.Object {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}
+	}
+
+	assert.True(t, f.Accept(gen(target)))
+	assert.True(t, f.Accept(gen(tsuper)))
+	assert.False(t, f.Accept(gen(tmiss)))
+	assert.False(t, f.Accept(gen(nil)))
+
+	assert.True(t, fnil.Accept(gen(target)))
+	assert.True(t, fnil.Accept(gen(nil)))
+	assert.True(t, fempty.Accept(gen(tmiss)))
+	assert.True(t, fempty.Accept(gen(nil)))
+
+	// values that are not valid label values still match.
+	long := map[string]string{"a": "{\"spec\": \"not a label value\"}"}
+	assert.True(t, filter.Annotations(long).Accept(gen(long)))
+	assert.False(t, filter.Annotations(long).Accept(gen(target)))
+
+	assert.True(t, f.Equals(f))
+	assert.True(t, f.Equals(filter.Annotations(map[string]string{"a": "1"})))
+	assert.False(t, f.Equals(filter.Annotations(tsuper)))
+	assert.False(t, f.Equals(fnil))
+	assert.False(t, f.Equals(filter.Labels(target)))
+
+	assert.True(t, fnil.Equals(fempty))
+	assert.True(t, fempty.Equals(fnil))
+}
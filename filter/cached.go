@@ -0,0 +1,116 @@
+package filter
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// CachedSize is the number of objects whose results are retained by a
+// filter returned by Cached().
+const CachedSize = 4096
+
+// Cached() returns a filter that remembers the result of child's Accept()
+// for each object, identified by its UID, until the object's resource
+// version changes.  Results for the CachedSize most recently evaluated
+// objects are retained.  Objects without a UID or resource version are
+// always evaluated by child.
+//
+// child must be pure: its result may depend only on the object.  Every
+// filter in this package is pure.
+//
+// Cached() filters are equal if their children are equal according to
+// FiltersEqual().
+func Cached(child Filter) ComparableFilter {
+	return &cachedFilter{
+		child:   child,
+		size:    CachedSize,
+		entries: make(map[types.UID]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+type cachedFilter struct {
+	child Filter
+	size  int
+
+	// most recently used first.
+	entries map[types.UID]*list.Element
+	lru     *list.List
+	mtx     sync.Mutex
+}
+
+type cachedResult struct {
+	uid     types.UID
+	version string
+	accept  bool
+}
+
+func (f *cachedFilter) Accept(obj metav1.Object) bool {
+	uid, version := obj.GetUID(), obj.GetResourceVersion()
+	if uid == "" || version == "" {
+		return f.child.Accept(obj)
+	}
+
+	if accept, ok := f.get(uid, version); ok {
+		return accept
+	}
+
+	accept := f.child.Accept(obj)
+	f.put(uid, version, accept)
+	return accept
+}
+
+func (f *cachedFilter) get(uid types.UID, version string) (bool, bool) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	elem, ok := f.entries[uid]
+	if !ok {
+		return false, false
+	}
+	result := elem.Value.(*cachedResult)
+	if result.version != version {
+		return false, false
+	}
+	f.lru.MoveToFront(elem)
+	return result.accept, true
+}
+
+func (f *cachedFilter) put(uid types.UID, version string, accept bool) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if elem, ok := f.entries[uid]; ok {
+		result := elem.Value.(*cachedResult)
+		result.version, result.accept = version, accept
+		f.lru.MoveToFront(elem)
+		return
+	}
+
+	f.entries[uid] = f.lru.PushFront(&cachedResult{uid, version, accept})
+
+	for f.lru.Len() > f.size {
+		elem := f.lru.Back()
+		f.lru.Remove(elem)
+		delete(f.entries, elem.Value.(*cachedResult).uid)
+	}
+}
+
+func (f *cachedFilter) Equals(other Filter) bool {
+	if other, ok := other.(*cachedFilter); ok {
+		return FiltersEqual(f.child, other.child)
+	}
+	return false
+}
+
+func (f *cachedFilter) String() string {
+	return fmt.Sprintf("Cached(%v)", f.child)
+}
+
+func (f *cachedFilter) Hash() uint64 {
+	return hashFilterList("Cached", []Filter{f.child})
+}
@@ -0,0 +1,85 @@
+package filter_test
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/boz/kcache/filter"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type countingFilter struct {
+	filter.Filter
+	calls int
+}
+
+func (f *countingFilter) Accept(obj metav1.Object) bool {
+	f.calls++
+	return f.Filter.Accept(obj)
+}
+
+func TestCached(t *testing.T) {
+	gen := func(uid, vsn string, labels map[string]string) metav1.Object {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:            "x",
+			UID:             types.UID(uid),
+			ResourceVersion: vsn,
+			Labels:          labels,
+		}}
+	}
+
+	web := map[string]string{"app": "web"}
+	db := map[string]string{"app": "db"}
+
+	child := &countingFilter{Filter: filter.Labels(web)}
+	f := filter.Cached(child)
+
+	assert.True(t, f.Accept(gen("a", "1", web)))
+	assert.True(t, f.Accept(gen("a", "1", web)))
+	assert.Equal(t, 1, child.calls)
+
+	// new version.
+	assert.False(t, f.Accept(gen("a", "2", db)))
+	assert.False(t, f.Accept(gen("a", "2", db)))
+	assert.Equal(t, 2, child.calls)
+
+	// other object.
+	assert.True(t, f.Accept(gen("b", "2", web)))
+	assert.Equal(t, 3, child.calls)
+
+	// not cacheable.
+	assert.True(t, f.Accept(gen("", "1", web)))
+	assert.True(t, f.Accept(gen("c", "", web)))
+	assert.True(t, f.Accept(gen("c", "", web)))
+	assert.Equal(t, 6, child.calls)
+
+	f = filter.Cached(filter.Labels(web))
+	assert.True(t, f.Equals(filter.Cached(filter.Labels(web))))
+	assert.False(t, f.Equals(filter.Cached(filter.Labels(db))))
+	assert.False(t, f.Equals(filter.Labels(web)))
+	assert.Equal(t, "Cached(Labels(app=web))", fmt.Sprint(f))
+}
+
+func TestCached_evict(t *testing.T) {
+	gen := func(i int) metav1.Object {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{UID: types.UID(strconv.Itoa(i)), ResourceVersion: "1"}}
+	}
+
+	child := &countingFilter{Filter: filter.Null()}
+	f := filter.Cached(child)
+
+	for i := 0; i <= filter.CachedSize; i++ {
+		f.Accept(gen(i))
+	}
+	assert.Equal(t, filter.CachedSize+1, child.calls)
+
+	// most recent retained; least recent evicted.
+	f.Accept(gen(filter.CachedSize))
+	assert.Equal(t, filter.CachedSize+1, child.calls)
+	f.Accept(gen(0))
+	assert.Equal(t, filter.CachedSize+2, child.calls)
+}
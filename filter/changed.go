@@ -0,0 +1,27 @@
+package filter
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ChangeFunc returns true if next, a later version of prev, differs from
+// prev in a way that should be reported.  Unlike a Filter, a ChangeFunc
+// sees both versions of an updated object.
+type ChangeFunc func(prev, next metav1.Object) bool
+
+// Changed() returns a ChangeFunc that reports a change when the value
+// returned by field differs between versions according to
+// reflect.DeepEqual().
+//
+// For example, to report only changes to a pod's phase:
+//
+//	filter.Changed(func(obj metav1.Object) interface{} {
+//	  return obj.(*v1.Pod).Status.Phase
+//	})
+func Changed(field func(metav1.Object) interface{}) ChangeFunc {
+	return func(prev, next metav1.Object) bool {
+		return !reflect.DeepEqual(field(prev), field(next))
+	}
+}
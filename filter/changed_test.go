@@ -0,0 +1,26 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/boz/kcache/filter"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestChanged(t *testing.T) {
+	gen := func(vsn string, phase v1.PodPhase) metav1.Object {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "a", ResourceVersion: vsn},
+			Status:     v1.PodStatus{Phase: phase},
+		}
+	}
+
+	fn := filter.Changed(func(obj metav1.Object) interface{} {
+		return obj.(*v1.Pod).Status.Phase
+	})
+
+	assert.False(t, fn(gen("1", v1.PodPending), gen("2", v1.PodPending)))
+	assert.True(t, fn(gen("1", v1.PodPending), gen("2", v1.PodRunning)))
+}
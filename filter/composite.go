@@ -1,13 +1,22 @@
 package filter
 
 import (
+	"fmt"
+	"strings"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type andFilter []Filter
 
+// And() returns a filter whose Accept() returns true if
+// all of the given filters accept the object.  Evaluation stops
+// at the first child that rejects the object.
+//
+// And() with no children accepts everything.  nil children are
+// ignored.
 func And(children ...Filter) ComparableFilter {
-	return andFilter(children)
+	return andFilter(withoutNil(children))
 }
 
 func (f andFilter) Accept(obj metav1.Object) bool {
@@ -26,10 +35,24 @@ func (f andFilter) Equals(other Filter) bool {
 	return false
 }
 
+func (f andFilter) String() string {
+	return formatFilterList("And", f)
+}
+
+func (f andFilter) Hash() uint64 {
+	return hashFilterList("And", f)
+}
+
 type orFilter []Filter
 
+// Or() returns a filter whose Accept() returns true if
+// any of the given filters accept the object.  Evaluation stops
+// at the first child that accepts the object.
+//
+// Or() with no children rejects everything.  nil children are
+// ignored, so Or(nil) also rejects everything.
 func Or(children ...Filter) ComparableFilter {
-	return orFilter(children)
+	return orFilter(withoutNil(children))
 }
 
 func (f orFilter) Accept(obj metav1.Object) bool {
@@ -48,6 +71,34 @@ func (f orFilter) Equals(other Filter) bool {
 	return false
 }
 
+func (f orFilter) String() string {
+	return formatFilterList("Or", f)
+}
+
+func (f orFilter) Hash() uint64 {
+	return hashFilterList("Or", f)
+}
+
+// withoutNil() returns children without nil filters.
+func withoutNil(children []Filter) []Filter {
+	for idx, child := range children {
+		if child != nil {
+			continue
+		}
+		result := append([]Filter(nil), children[:idx]...)
+		for _, child := range children[idx+1:] {
+			if child != nil {
+				result = append(result, child)
+			}
+		}
+		return result
+	}
+	return children
+}
+
+// compareFilterList() returns true if both lists contain equal
+// filters in the same order.  Filters that are not comparable
+// are never considered equal.
 func compareFilterList(a []Filter, b []Filter) bool {
 	if len(a) != len(b) {
 		return false
@@ -73,3 +124,11 @@ func compareFilterList(a []Filter, b []Filter) bool {
 
 	return true
 }
+
+func formatFilterList(name string, children []Filter) string {
+	parts := make([]string, 0, len(children))
+	for _, child := range children {
+		parts = append(parts, fmt.Sprint(child))
+	}
+	return name + "(" + strings.Join(parts, ", ") + ")"
+}
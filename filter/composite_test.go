@@ -1,11 +1,14 @@
 package filter_test
 
 import (
+	"fmt"
 	"testing"
 
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/nsname"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -51,6 +54,25 @@ func TestAndFilter(t *testing.T) {
 	a = filter.And()
 	b = filter.Or()
 	assert.False(t, a.Equals(b))
+
+	fn := filter.FN(func(_ metav1.Object) bool { return true })
+	a = filter.And(filter.Null(), fn)
+	b = filter.And(filter.Null(), fn)
+	assert.False(t, a.Equals(b))
+}
+
+func TestAndFilter_shortCircuit(t *testing.T) {
+	calls := 0
+	counter := filter.FN(func(_ metav1.Object) bool {
+		calls++
+		return true
+	})
+
+	assert.False(t, filter.And(filter.All(), counter).Accept(&v1.Pod{}))
+	assert.Equal(t, 0, calls)
+
+	assert.True(t, filter.And(filter.Null(), counter).Accept(&v1.Pod{}))
+	assert.Equal(t, 1, calls)
 }
 
 func TestOrFilter(t *testing.T) {
@@ -69,4 +91,69 @@ func TestOrFilter(t *testing.T) {
 	a := filter.Or()
 	b := filter.And()
 	assert.False(t, a.Equals(b))
+
+	a = filter.Or(filter.Null())
+	b = filter.Or(filter.Null())
+	assert.True(t, a.Equals(b))
+
+	a = filter.Or(filter.Null(), filter.All())
+	b = filter.Or(filter.All(), filter.Null())
+	assert.False(t, a.Equals(b))
+}
+
+func TestOrFilter_shortCircuit(t *testing.T) {
+	calls := 0
+	counter := filter.FN(func(_ metav1.Object) bool {
+		calls++
+		return false
+	})
+
+	assert.True(t, filter.Or(filter.Null(), counter).Accept(&v1.Pod{}))
+	assert.Equal(t, 0, calls)
+
+	assert.False(t, filter.Or(filter.All(), counter).Accept(&v1.Pod{}))
+	assert.Equal(t, 1, calls)
+}
+
+func TestCompositeFilter_nil(t *testing.T) {
+	obj := &v1.Pod{}
+
+	assert.NotPanics(t, func() {
+		assert.True(t, filter.And(nil).Accept(obj))
+		assert.False(t, filter.And(nil, filter.All(), nil).Accept(obj))
+		assert.True(t, filter.And(filter.Null(), nil).Accept(obj))
+
+		assert.False(t, filter.Or(nil).Accept(obj))
+		assert.True(t, filter.Or(nil, filter.Null(), nil).Accept(obj))
+		assert.False(t, filter.Or(filter.All(), nil).Accept(obj))
+	})
+
+	assert.True(t, filter.And(nil, filter.Null()).Equals(filter.And(filter.Null())))
+	assert.True(t, filter.Or(nil).Equals(filter.Or()))
+	assert.Equal(t, "And(Null())", fmt.Sprint(filter.And(nil, filter.Null(), nil)))
+
+	// the caller's arguments are not modified.
+	children := []filter.Filter{filter.Null(), nil, filter.All()}
+	filter.And(children...)
+	assert.Equal(t, []filter.Filter{filter.Null(), nil, filter.All()}, children)
+}
+
+func TestCompositeFilter_nested(t *testing.T) {
+	n1 := filter.NSName(nsname.New("a", "1"))
+	n2 := filter.NSName(nsname.New("a", "2"))
+
+	a := filter.Or(filter.And(n1, filter.Null()), n2)
+	b := filter.Or(filter.And(n1, filter.Null()), n2)
+	assert.True(t, a.Equals(b))
+	assert.True(t, b.Equals(a))
+
+	b = filter.Or(filter.And(n2, filter.Null()), n2)
+	assert.False(t, a.Equals(b))
+
+	b = filter.Or(filter.Or(n1, filter.Null()), n2)
+	assert.False(t, a.Equals(b))
+
+	a = filter.And(filter.Or(n1, n2), filter.Not(n1))
+	b = filter.And(filter.Or(n1, n2), filter.Not(n1))
+	assert.True(t, a.Equals(b))
 }
@@ -0,0 +1,106 @@
+package filter
+
+import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// Fields() returns a filter which returns true if the
+// object's fields match the given selector.
+//
+// The metadata.name and metadata.namespace fields are available
+// for all objects.  Pods additionally expose spec.nodeName and
+// status.phase.
+//
+// Fields(nil) accepts everything.
+func Fields(selector fields.Selector) ComparableFilter {
+	if selector == nil {
+		selector = fields.Everything()
+	}
+	return &fieldsFilter{selector}
+}
+
+type fieldsFilter struct {
+	selector fields.Selector
+}
+
+func (f *fieldsFilter) Accept(obj metav1.Object) bool {
+	return f.selector.Matches(objectFieldSet(obj))
+}
+
+func (f *fieldsFilter) Equals(other Filter) bool {
+	if other, ok := other.(*fieldsFilter); ok {
+		return f.selector.String() == other.selector.String()
+	}
+	return false
+}
+
+func (f *fieldsFilter) String() string {
+	return "Fields(" + f.selector.String() + ")"
+}
+
+func (f *fieldsFilter) Hash() uint64 {
+	return hashString(f.String())
+}
+
+func objectFieldSet(obj metav1.Object) fields.Set {
+	set := fields.Set{
+		"metadata.name":      obj.GetName(),
+		"metadata.namespace": obj.GetNamespace(),
+	}
+
+	if pod, ok := obj.(*v1.Pod); ok {
+		set["spec.nodeName"] = pod.Spec.NodeName
+		set["status.phase"] = string(pod.Status.Phase)
+	}
+
+	return set
+}
+
+// FieldIn() returns a filter whose Accept() returns true if the value
+// that accessor extracts from the object is one of values.
+//
+// FieldIn() with no values accepts everything, as Name() does.
+//
+// accessor can't be compared, so FieldIn() filters do not implement
+// ComparableFilter; prefer a comparable filter such as Fields() or
+// Labels() where one applies.  FieldIn() panics if accessor is nil.
+func FieldIn(accessor func(metav1.Object) string, values ...string) Filter {
+	return newFieldSetFilter(accessor, values, false)
+}
+
+// FieldNotIn() returns a filter whose Accept() returns true if the value
+// that accessor extracts from the object is not one of values.
+// FieldNotIn() with no values accepts everything.  See FieldIn().
+func FieldNotIn(accessor func(metav1.Object) string, values ...string) Filter {
+	return newFieldSetFilter(accessor, values, true)
+}
+
+func newFieldSetFilter(accessor func(metav1.Object) string, values []string, negate bool) *fieldSetFilter {
+	if accessor == nil {
+		panic("filter: nil field accessor")
+	}
+	return &fieldSetFilter{accessor, newStringSet(values), negate}
+}
+
+type fieldSetFilter struct {
+	accessor func(metav1.Object) string
+	values   stringSet
+	negate   bool
+}
+
+func (f *fieldSetFilter) Accept(obj metav1.Object) bool {
+	if len(f.values) == 0 {
+		return true
+	}
+	_, ok := f.values[f.accessor(obj)]
+	return ok != f.negate
+}
+
+func (f *fieldSetFilter) String() string {
+	if f.negate {
+		return "FieldNotIn(" + f.values.String() + ")"
+	}
+	return "FieldIn(" + f.values.String() + ")"
+}
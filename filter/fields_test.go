@@ -0,0 +1,92 @@
+package filter_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/boz/kcache/filter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+func TestFields(t *testing.T) {
+	genpod := func(ns, name, node string, phase v1.PodPhase) metav1.Object {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+			Spec:       v1.PodSpec{NodeName: node},
+			Status:     v1.PodStatus{Phase: phase},
+		}
+	}
+	gensvc := func(ns, name string) metav1.Object {
+		return &v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}
+	}
+
+	parse := func(expr string) filter.ComparableFilter {
+		sel, err := fields.ParseSelector(expr)
+		require.NoError(t, err)
+		return filter.Fields(sel)
+	}
+
+	f := parse("metadata.namespace=a,metadata.name=x")
+	assert.True(t, f.Accept(genpod("a", "x", "", "")))
+	assert.True(t, f.Accept(gensvc("a", "x")))
+	assert.False(t, f.Accept(genpod("b", "x", "", "")))
+	assert.False(t, f.Accept(gensvc("a", "y")))
+
+	f = parse("spec.nodeName=n1,status.phase!=Failed")
+	assert.True(t, f.Accept(genpod("a", "x", "n1", v1.PodRunning)))
+	assert.False(t, f.Accept(genpod("a", "x", "n2", v1.PodRunning)))
+	assert.False(t, f.Accept(genpod("a", "x", "n1", v1.PodFailed)))
+	assert.False(t, f.Accept(gensvc("a", "x")))
+
+	assert.True(t, filter.Fields(fields.Everything()).Accept(gensvc("a", "x")))
+	assert.True(t, filter.Fields(nil).Accept(gensvc("a", "x")))
+	assert.True(t, filter.Fields(nil).Equals(filter.Fields(fields.Everything())))
+	assert.Equal(t, "Fields()", fmt.Sprint(filter.Fields(nil)))
+
+	assert.True(t, f.Equals(parse("status.phase!=Failed,spec.nodeName=n1")))
+	assert.False(t, f.Equals(parse("spec.nodeName=n1")))
+	assert.False(t, f.Equals(filter.Null()))
+
+	assert.Equal(t, "Fields(metadata.name=x)", fmt.Sprint(parse("metadata.name=x")))
+}
+
+func TestFieldIn(t *testing.T) {
+	node := func(obj metav1.Object) string {
+		if pod, ok := obj.(*v1.Pod); ok {
+			return pod.Spec.NodeName
+		}
+		return ""
+	}
+	genpod := func(node string) metav1.Object {
+		return &v1.Pod{Spec: v1.PodSpec{NodeName: node}}
+	}
+
+	in := filter.FieldIn(node, "a", "b")
+	assert.True(t, in.Accept(genpod("a")))
+	assert.True(t, in.Accept(genpod("b")))
+	assert.False(t, in.Accept(genpod("c")))
+	assert.False(t, in.Accept(genpod("")))
+	assert.False(t, in.Accept(&v1.Service{}))
+	assert.True(t, filter.FieldIn(node).Accept(genpod("a")))
+
+	notIn := filter.FieldNotIn(node, "a", "b")
+	assert.False(t, notIn.Accept(genpod("a")))
+	assert.False(t, notIn.Accept(genpod("b")))
+	assert.True(t, notIn.Accept(genpod("c")))
+	assert.True(t, notIn.Accept(&v1.Service{}))
+	assert.True(t, filter.FieldNotIn(node).Accept(genpod("a")))
+
+	_, ok := in.(filter.ComparableFilter)
+	assert.False(t, ok)
+	assert.False(t, filter.FiltersEqual(in, in))
+
+	assert.Equal(t, "FieldIn(a,b)", fmt.Sprint(filter.FieldIn(node, "b", "a")))
+	assert.Equal(t, "FieldNotIn(a)", fmt.Sprint(filter.FieldNotIn(node, "a")))
+
+	assert.Panics(t, func() { filter.FieldIn(nil, "a") })
+	assert.Panics(t, func() { filter.FieldNotIn(nil) })
+}
@@ -1,7 +1,9 @@
 package filter
 
 import (
-	"reflect"
+	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/boz/kcache/nsname"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -34,6 +36,14 @@ func (nullFilter) Equals(other Filter) bool {
 	return ok
 }
 
+func (nullFilter) String() string {
+	return "Null()"
+}
+
+func (nullFilter) Hash() uint64 {
+	return hashString("Null()")
+}
+
 type allFilter struct{}
 
 // All() returns a filter whose Accept() is always false.
@@ -50,7 +60,26 @@ func (allFilter) Equals(other Filter) bool {
 	return ok
 }
 
+func (allFilter) String() string {
+	return "All()"
+}
+
+func (allFilter) Hash() uint64 {
+	return hashString("All()")
+}
+
+// Not() returns a filter whose Accept() returns the negation
+// of the given filter's Accept().
+//
+// Not(Null()) returns All() and Not(All()) returns Null().  A nil
+// child is treated as Null().
 func Not(child Filter) ComparableFilter {
+	switch child.(type) {
+	case nil, nullFilter:
+		return All()
+	case allFilter:
+		return Null()
+	}
 	return &notFilter{child}
 }
 
@@ -71,6 +100,14 @@ func (f *notFilter) Equals(other Filter) bool {
 	return false
 }
 
+func (f *notFilter) String() string {
+	return fmt.Sprintf("Not(%v)", f.child)
+}
+
+func (f *notFilter) Hash() uint64 {
+	return hashFilterList("Not", []Filter{f.child})
+}
+
 // NSName() returns a filter whose Accept() returns true
 // if the object's namespace and name matches one of the given
 // NSNames.
@@ -94,8 +131,10 @@ type nsNameFilter struct {
 }
 
 func (f nsNameFilter) Accept(obj metav1.Object) bool {
-	key := nsname.ForObject(obj)
+	return f.acceptID(nsname.ForObject(obj))
+}
 
+func (f nsNameFilter) acceptID(key nsname.NSName) bool {
 	if _, ok := f.fullset[key]; ok {
 		return true
 	}
@@ -116,7 +155,138 @@ func (f nsNameFilter) Accept(obj metav1.Object) bool {
 }
 
 func (f nsNameFilter) Equals(other Filter) bool {
-	return reflect.DeepEqual(f, other)
+	o, ok := other.(nsNameFilter)
+	if !ok {
+		return false
+	}
+
+	if len(f.fullset) != len(o.fullset) || len(f.partials) != len(o.partials) {
+		return false
+	}
+
+	for id := range f.fullset {
+		if _, ok := o.fullset[id]; !ok {
+			return false
+		}
+	}
+
+	// partials must be in the same order
+	for idx, id := range f.partials {
+		if o.partials[idx] != id {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f nsNameFilter) String() string {
+	ids := make([]string, 0, len(f.fullset)+len(f.partials))
+	for id := range f.fullset {
+		ids = append(ids, id.String())
+	}
+	sort.Strings(ids)
+	for _, id := range f.partials {
+		ids = append(ids, id.String())
+	}
+	return "NSName(" + strings.Join(ids, ", ") + ")"
+}
+
+func (f nsNameFilter) Hash() uint64 {
+	return hashString(f.String())
+}
+
+// Union() returns a filter that accepts objects accepted by either
+// of the given filters.
+//
+// If both filters are NSName() filters the result is a single
+// NSName() filter containing the ids of both; otherwise it is
+// equivalent to Or(a, b).
+func Union(a, b Filter) ComparableFilter {
+	fa, aok := a.(nsNameFilter)
+	fb, bok := b.(nsNameFilter)
+	if !aok || !bok {
+		return Or(a, b)
+	}
+
+	ids := make([]nsname.NSName, 0, len(fa.fullset)+len(fb.fullset)+len(fa.partials)+len(fb.partials))
+	for id := range fa.fullset {
+		ids = append(ids, id)
+	}
+	for id := range fb.fullset {
+		ids = append(ids, id)
+	}
+	ids = append(ids, fa.partials...)
+	ids = append(ids, fb.partials...)
+
+	return newNSNameFilter(ids)
+}
+
+// Intersect() returns a filter that accepts objects accepted by both
+// of the given filters.
+//
+// If both filters are NSName() filters the result is a single
+// NSName() filter; otherwise it is equivalent to And(a, b).
+func Intersect(a, b Filter) ComparableFilter {
+	fa, aok := a.(nsNameFilter)
+	fb, bok := b.(nsNameFilter)
+	if !aok || !bok || fa.hasEmptyPartial() || fb.hasEmptyPartial() {
+		return And(a, b)
+	}
+
+	var ids []nsname.NSName
+
+	for id := range fa.fullset {
+		if fb.acceptID(id) {
+			ids = append(ids, id)
+		}
+	}
+	for id := range fb.fullset {
+		if fa.acceptID(id) {
+			ids = append(ids, id)
+		}
+	}
+
+	for _, pa := range fa.partials {
+		for _, pb := range fb.partials {
+			switch {
+			case pa == pb:
+				ids = append(ids, pa)
+			case pa.Name == "" && pb.Namespace == "":
+				ids = append(ids, nsname.New(pa.Namespace, pb.Name))
+			case pa.Namespace == "" && pb.Name == "":
+				ids = append(ids, nsname.New(pb.Namespace, pa.Name))
+			}
+		}
+	}
+
+	return newNSNameFilter(ids)
+}
+
+// newNSNameFilter() is like NSName() but drops duplicate partials.
+func newNSNameFilter(ids []nsname.NSName) nsNameFilter {
+	f := NSName(ids...).(nsNameFilter)
+
+	seen := make(map[nsname.NSName]bool, len(f.partials))
+	partials := f.partials[:0]
+	for _, id := range f.partials {
+		if !seen[id] {
+			seen[id] = true
+			partials = append(partials, id)
+		}
+	}
+	f.partials = partials
+
+	return f
+}
+
+func (f nsNameFilter) hasEmptyPartial() bool {
+	for _, id := range f.partials {
+		if id.Namespace == "" && id.Name == "" {
+			return true
+		}
+	}
+	return false
 }
 
 func FiltersEqual(f1, f2 Filter) bool {
@@ -135,12 +305,52 @@ func FiltersEqual(f1, f2 Filter) bool {
 	return false
 }
 
+// AcceptsAny() returns true if f accepts any of objs.  It returns
+// false if objs is empty.
+func AcceptsAny(f Filter, objs []metav1.Object) bool {
+	for _, obj := range objs {
+		if f.Accept(obj) {
+			return true
+		}
+	}
+	return false
+}
+
+// AcceptsAll() returns true if f accepts every one of objs.  It returns
+// true if objs is empty.
+func AcceptsAll(f Filter, objs []metav1.Object) bool {
+	for _, obj := range objs {
+		if !f.Accept(obj) {
+			return false
+		}
+	}
+	return true
+}
+
+// FN() returns a filter that accepts objects for which fn returns true.
+//
+// FN filters are opaque: they do not implement ComparableFilter, so
+// they are never equal to another filter (including themselves) and any
+// And(), Or(), or Not() containing one is never equal either.  Refilter
+// operations using them will always be treated as a change.
+//
+// Deprecated: use Func()
 func FN(fn func(metav1.Object) bool) Filter {
 	return fnFilter(fn)
 }
 
+// Func() returns a filter that accepts objects for which fn returns true.
+// Its filters are opaque; see FN().
+func Func(fn func(metav1.Object) bool) Filter {
+	return FN(fn)
+}
+
 type fnFilter func(metav1.Object) bool
 
 func (f fnFilter) Accept(obj metav1.Object) bool {
 	return f(obj)
 }
+
+func (f fnFilter) String() string {
+	return "FN()"
+}
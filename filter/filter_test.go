@@ -1,9 +1,13 @@
 package filter_test
 
 import (
+	"fmt"
+	"reflect"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -35,6 +39,13 @@ func TestAllFilter(t *testing.T) {
 	assert.False(t, f.Equals(filter.Null()))
 }
 
+func TestNotFilter_nil(t *testing.T) {
+	assert.NotPanics(t, func() {
+		assert.False(t, filter.Not(nil).Accept(&v1.Pod{}))
+	})
+	assert.True(t, filter.Not(nil).Equals(filter.All()))
+}
+
 func TestNotFilter(t *testing.T) {
 	f1 := filter.Not(filter.All())
 	f2 := filter.Not(filter.Null())
@@ -44,6 +55,23 @@ func TestNotFilter(t *testing.T) {
 
 	assert.True(t, f1.Equals(f1))
 	assert.False(t, f1.Equals(f2))
+
+	assert.True(t, f1.Equals(filter.Null()))
+	assert.True(t, f2.Equals(filter.All()))
+
+	n1 := filter.NSName(nsname.New("a", "1"))
+	n2 := filter.NSName(nsname.New("a", "2"))
+	o1 := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "1"}}
+
+	assert.False(t, filter.Not(n1).Accept(o1))
+	assert.True(t, filter.Not(n2).Accept(o1))
+
+	assert.True(t, filter.Not(n1).Equals(filter.Not(n1)))
+	assert.False(t, filter.Not(n1).Equals(filter.Not(n2)))
+	assert.False(t, filter.Not(n1).Equals(n1))
+
+	fn := filter.FN(func(_ metav1.Object) bool { return true })
+	assert.False(t, filter.Not(fn).Equals(filter.Not(fn)))
 }
 
 func TestNSName_fullset(t *testing.T) {
@@ -94,6 +122,72 @@ func TestNSName_partials(t *testing.T) {
 	assert.False(t, filter.NSName(n1, n2).Equals(filter.NSName(n2, n1)))
 }
 
+func TestNSName_equals(t *testing.T) {
+	n1 := nsname.New("a", "1")
+	n2 := nsname.New("a", "2")
+	p1 := nsname.New("a", "")
+
+	assert.True(t, filter.NSName(n1, n2).Equals(filter.NSName(n2, n1)))
+	assert.True(t, filter.NSName(n1, n1).Equals(filter.NSName(n1)))
+	assert.False(t, filter.NSName(n1, n2).Equals(filter.NSName(n1)))
+	assert.False(t, filter.NSName(n1).Equals(filter.NSName(n1, n2)))
+	assert.True(t, filter.NSName(n1, p1).Equals(filter.NSName(p1, n1)))
+	assert.False(t, filter.NSName(n1, p1).Equals(filter.NSName(n1)))
+	assert.False(t, filter.NSName(n1).Equals(filter.NSName(n1, p1)))
+	assert.False(t, filter.NSName(n1).Equals(filter.Null()))
+}
+
+func TestUnion(t *testing.T) {
+	n1 := nsname.New("a", "1")
+	n2 := nsname.New("a", "2")
+	n3 := nsname.New("b", "3")
+	pa := nsname.New("a", "")
+	pn := nsname.New("", "3")
+
+	f := filter.Union(filter.NSName(n1), filter.NSName(n2, n3))
+	assert.True(t, f.Equals(filter.NSName(n1, n2, n3)))
+
+	f = filter.Union(filter.NSName(n1, pa), filter.NSName(n1, pa, pn))
+	assert.True(t, f.Equals(filter.NSName(n1, pa, pn)))
+
+	o3 := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "c", Name: "3"}}
+	assert.True(t, f.Accept(o3))
+
+	f = filter.Union(filter.NSName(n1), filter.Namespace("b"))
+	assert.True(t, f.Equals(filter.Or(filter.NSName(n1), filter.Namespace("b"))))
+	assert.False(t, f.Accept(o3))
+}
+
+func TestIntersect(t *testing.T) {
+	n1 := nsname.New("a", "1")
+	n2 := nsname.New("a", "2")
+	n3 := nsname.New("b", "3")
+	pa := nsname.New("a", "")
+	pb := nsname.New("b", "")
+	p3 := nsname.New("", "3")
+
+	f := filter.Intersect(filter.NSName(n1, n2), filter.NSName(n2, n3))
+	assert.True(t, f.Equals(filter.NSName(n2)))
+
+	f = filter.Intersect(filter.NSName(n1, n2, n3), filter.NSName(pa))
+	assert.True(t, f.Equals(filter.NSName(n1, n2)))
+
+	f = filter.Intersect(filter.NSName(pa, pb), filter.NSName(pb))
+	assert.True(t, f.Equals(filter.NSName(pb)))
+
+	f = filter.Intersect(filter.NSName(pb), filter.NSName(p3))
+	assert.True(t, f.Equals(filter.NSName(n3)))
+
+	f = filter.Intersect(filter.NSName(p3), filter.NSName(pb))
+	assert.True(t, f.Equals(filter.NSName(n3)))
+
+	f = filter.Intersect(filter.NSName(pa), filter.NSName(pb))
+	assert.True(t, f.Equals(filter.NSName()))
+
+	f = filter.Intersect(filter.NSName(n1), filter.Namespace("a"))
+	assert.True(t, f.Equals(filter.And(filter.NSName(n1), filter.Namespace("a"))))
+}
+
 func TestFiltersEqual(t *testing.T) {
 
 	assert.True(t, filter.FiltersEqual(nil, nil))
@@ -119,3 +213,116 @@ func TestFN(t *testing.T) {
 	assert.False(t, filter.FiltersEqual(f1, f1))
 	assert.False(t, filter.FiltersEqual(f1, filter.All()))
 }
+
+func TestFunc(t *testing.T) {
+	o1 := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "1"}}
+	o2 := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "b", Name: "1"}}
+
+	f1 := filter.Func(func(obj metav1.Object) bool {
+		return obj.GetNamespace() == "a"
+	})
+
+	assert.True(t, f1.Accept(o1))
+	assert.False(t, f1.Accept(o2))
+
+	_, ok := f1.(filter.ComparableFilter)
+	assert.False(t, ok)
+
+	assert.False(t, filter.FiltersEqual(f1, f1))
+	assert.False(t, filter.And(f1).Equals(filter.And(f1)))
+	assert.False(t, filter.Or(filter.All(), f1).Equals(filter.Or(filter.All(), f1)))
+	assert.False(t, filter.Not(f1).Equals(filter.Not(f1)))
+}
+
+func TestString(t *testing.T) {
+	assert.Equal(t, "Null()", fmt.Sprint(filter.Null()))
+	assert.Equal(t, "All()", fmt.Sprint(filter.All()))
+	assert.Equal(t, "FN()", fmt.Sprint(filter.FN(func(_ metav1.Object) bool { return true })))
+
+	assert.Equal(t, "Labels(app=web)", fmt.Sprint(filter.Labels(map[string]string{"app": "web"})))
+	assert.Equal(t, "Labels(a=1,b=2)", fmt.Sprint(filter.Labels(map[string]string{"b": "2", "a": "1"})))
+	assert.Equal(t, "Labels()", fmt.Sprint(filter.Labels(nil)))
+	assert.Equal(t, "Labels(<none>)", fmt.Sprint(filter.LabelSelector(nil)))
+
+	assert.Equal(t, "Annotations(a=1)", fmt.Sprint(filter.Annotations(map[string]string{"a": "1"})))
+	assert.Equal(t, "Namespace(a,b)", fmt.Sprint(filter.Namespace("b", "a")))
+	assert.Equal(t, "Name(x)", fmt.Sprint(filter.Name("x")))
+	assert.Equal(t, "OwnerRef(abc)", fmt.Sprint(filter.OwnerRef("abc")))
+	assert.Equal(t, "ControllerRef(abc)", fmt.Sprint(filter.ControllerRef("abc")))
+
+	re, err := filter.NameRegex("^nginx-")
+	require.NoError(t, err)
+	assert.Equal(t, "NameRegex(^nginx-)", fmt.Sprint(re))
+
+	assert.Equal(t, "NSName(a/1, b/2, x, c/)", fmt.Sprint(filter.NSName(
+		nsname.New("b", "2"), nsname.New("", "x"), nsname.New("a", "1"), nsname.New("c", ""))))
+
+	assert.Equal(t, "Not(Namespace(a))", fmt.Sprint(filter.Not(filter.Namespace("a"))))
+	assert.Equal(t, "And()", fmt.Sprint(filter.And()))
+	assert.Equal(t, "Or()", fmt.Sprint(filter.Or(nil)))
+	assert.Equal(t, "And(Namespace(default), Labels(tier=fe))",
+		fmt.Sprint(filter.And(filter.Namespace("default"), filter.Labels(map[string]string{"tier": "fe"}))))
+	assert.Equal(t, "Or(And(Null(), All()), Name(x))",
+		fmt.Sprint(filter.Or(filter.And(filter.Null(), filter.All()), filter.Name("x"))))
+}
+
+func benchmarkNSNameFilters(size int) (filter.Filter, filter.Filter) {
+	a := make([]nsname.NSName, 0, size)
+	b := make([]nsname.NSName, 0, size)
+	for i := 0; i < size; i++ {
+		a = append(a, nsname.New("ns", strconv.Itoa(i)))
+		b = append(b, nsname.New("ns", strconv.Itoa(size-i-1)))
+	}
+	return filter.NSName(a...), filter.NSName(b...)
+}
+
+func BenchmarkNSName_Equals(b *testing.B) {
+	f1, f2 := benchmarkNSNameFilters(10000)
+	f := f1.(filter.ComparableFilter)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !f.Equals(f2) {
+			b.Fatal("filters not equal")
+		}
+	}
+}
+
+// BenchmarkNSName_DeepEqual measures the previous
+// reflect.DeepEqual-based implementation for comparison.
+func BenchmarkNSName_DeepEqual(b *testing.B) {
+	f1, f2 := benchmarkNSNameFilters(10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !reflect.DeepEqual(f1, f2) {
+			b.Fatal("filters not equal")
+		}
+	}
+}
+
+func TestAcceptsAnyAll(t *testing.T) {
+	a := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a"}}
+	b := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "b"}}
+
+	calls := 0
+	f := filter.FN(func(obj metav1.Object) bool {
+		calls++
+		return obj.GetName() == "a"
+	})
+
+	assert.True(t, filter.AcceptsAny(f, []metav1.Object{a, b}))
+	assert.Equal(t, 1, calls)
+	assert.True(t, filter.AcceptsAny(f, []metav1.Object{b, a}))
+	assert.False(t, filter.AcceptsAny(f, []metav1.Object{b}))
+	assert.False(t, filter.AcceptsAny(f, nil))
+	assert.False(t, filter.AcceptsAny(filter.Null(), nil))
+
+	calls = 0
+	assert.False(t, filter.AcceptsAll(f, []metav1.Object{b, a}))
+	assert.Equal(t, 1, calls)
+	assert.False(t, filter.AcceptsAll(f, []metav1.Object{a, b}))
+	assert.True(t, filter.AcceptsAll(f, []metav1.Object{a, a}))
+	assert.True(t, filter.AcceptsAll(f, nil))
+	assert.True(t, filter.AcceptsAll(filter.All(), nil))
+}
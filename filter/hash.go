@@ -0,0 +1,80 @@
+package filter
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// HashableFilter is a ComparableFilter that can be grouped with the
+// filters it may equal without comparing it to every other filter.
+// Every comparable filter in this package is hashable.
+type HashableFilter interface {
+	ComparableFilter
+
+	// Hash() returns the same value for filters that are equal
+	// according to Equals().  Unequal filters may share a hash.
+	Hash() uint64
+}
+
+// Distinct() returns filters with those equal to an earlier filter
+// according to FiltersEqual() removed.  Hashable filters are compared
+// only with filters that share their hash.  Filters that are not
+// comparable are always retained.
+func Distinct(filters []Filter) []Filter {
+	var result []Filter
+	buckets := make(map[uint64][]ComparableFilter)
+
+	// comparable filters that are not hashable.
+	var others []ComparableFilter
+
+	contains := func(candidates []ComparableFilter, f Filter) bool {
+		for _, candidate := range candidates {
+			if candidate.Equals(f) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, f := range filters {
+		switch cf := f.(type) {
+		case HashableFilter:
+			hash := cf.Hash()
+			if contains(buckets[hash], cf) {
+				continue
+			}
+			buckets[hash] = append(buckets[hash], cf)
+		case ComparableFilter:
+			if contains(others, cf) {
+				continue
+			}
+			others = append(others, cf)
+		}
+		result = append(result, f)
+	}
+	return result
+}
+
+func hashString(value string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(value))
+	return h.Sum64()
+}
+
+// hashFilterList() combines the hashes of children in order.  Children
+// that are not hashable contribute zero.
+func hashFilterList(name string, children []Filter) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+
+	var buf [8]byte
+	for _, child := range children {
+		var value uint64
+		if child, ok := child.(HashableFilter); ok {
+			value = child.Hash()
+		}
+		binary.LittleEndian.PutUint64(buf[:], value)
+		h.Write(buf[:])
+	}
+	return h.Sum64()
+}
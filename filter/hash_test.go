@@ -0,0 +1,95 @@
+package filter_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/nsname"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestHash(t *testing.T) {
+	nameRegex := func(pattern string) filter.Filter {
+		f, err := filter.NameRegex(pattern)
+		require.NoError(t, err)
+		return f
+	}
+
+	// each function returns equal filters for equal ids.
+	gens := []func(id string) filter.Filter{
+		func(id string) filter.Filter { return filter.Labels(map[string]string{"a": id}) },
+		func(id string) filter.Filter { return filter.Annotations(map[string]string{"a": id}) },
+		func(id string) filter.Filter { return filter.Fields(fields.OneTermEqualSelector("metadata.name", id)) },
+		func(id string) filter.Filter { return filter.Namespace(id, "x") },
+		func(id string) filter.Filter { return filter.Name(id) },
+		func(id string) filter.Filter { return filter.UID(types.UID(id), "x") },
+		func(id string) filter.Filter { return filter.NSName(nsname.New(id, "b"), nsname.New("", id)) },
+		func(id string) filter.Filter { return filter.Kind(id) },
+		func(id string) filter.Filter { return filter.OwnerRef(types.UID(id)) },
+		func(id string) filter.Filter { return filter.HasFinalizer(id) },
+		func(id string) filter.Filter { return filter.GenerationAtLeast(int64(len(id))) },
+		func(id string) filter.Filter { return nameRegex("^" + id + "$") },
+		func(id string) filter.Filter { return filter.Not(filter.Name(id)) },
+		func(id string) filter.Filter { return filter.And(filter.Name(id), filter.Namespace("x")) },
+		func(id string) filter.Filter { return filter.Or(filter.Namespace("x"), filter.Name(id)) },
+		func(id string) filter.Filter { return filter.Cached(filter.Name(id)) },
+	}
+
+	for idx, gen := range gens {
+		for _, id := range []string{"a", "bb"} {
+			a, b := gen(id), gen(id)
+			require.True(t, filter.FiltersEqual(a, b), "%v: %v", idx, a)
+
+			ha, ok := a.(filter.HashableFilter)
+			require.True(t, ok, "%v: %v not hashable", idx, a)
+			hb := b.(filter.HashableFilter)
+			assert.Equal(t, ha.Hash(), hb.Hash(), "%v: %v", idx, a)
+		}
+	}
+
+	for _, f := range []filter.Filter{filter.Null(), filter.All(), filter.DeletionPending(), filter.ControllerRef("x")} {
+		ha, ok := f.(filter.HashableFilter)
+		require.True(t, ok, "%v not hashable", f)
+		assert.Equal(t, ha.Hash(), ha.Hash())
+	}
+
+	hash := func(f filter.Filter) uint64 { return f.(filter.HashableFilter).Hash() }
+	assert.NotEqual(t, hash(filter.Name("a")), hash(filter.Name("b")))
+	assert.NotEqual(t, hash(filter.Name("a")), hash(filter.Namespace("a")))
+	assert.NotEqual(t, hash(filter.And(filter.Name("a"), filter.Name("b"))), hash(filter.And(filter.Name("b"), filter.Name("a"))))
+	assert.NotEqual(t, hash(filter.And(filter.Name("a"))), hash(filter.Or(filter.Name("a"))))
+}
+
+func TestDistinct(t *testing.T) {
+	fn := filter.FN(func(_ metav1.Object) bool { return true })
+
+	filters := []filter.Filter{
+		filter.Namespace("a"),
+		filter.Name("x"),
+		fn,
+		filter.Namespace("a"),
+		filter.And(filter.Name("x"), filter.Namespace("b")),
+		fn,
+		filter.Name("x"),
+		filter.And(filter.Name("x"), filter.Namespace("b")),
+	}
+
+	assert.Equal(t,
+		[]string{"Namespace(a)", "Name(x)", "FN()", "And(Name(x), Namespace(b))", "FN()"},
+		stringsOf(filter.Distinct(filters)))
+
+	assert.Empty(t, filter.Distinct(nil))
+}
+
+func stringsOf(filters []filter.Filter) []string {
+	var values []string
+	for _, f := range filters {
+		values = append(values, fmt.Sprint(f))
+	}
+	return values
+}
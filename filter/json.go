@@ -0,0 +1,195 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/boz/kcache/nsname"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	jsonTypeNull        = "null"
+	jsonTypeAll         = "all"
+	jsonTypeLabels      = "labels"
+	jsonTypeAnnotations = "annotations"
+	jsonTypeNamespace   = "namespace"
+	jsonTypeName        = "name"
+	jsonTypeNSName      = "nsname"
+	jsonTypeUID         = "uid"
+	jsonTypeAnd         = "and"
+	jsonTypeOr          = "or"
+	jsonTypeNot         = "not"
+)
+
+type jsonFilter struct {
+	Type     string            `json:"type"`
+	Selector *string           `json:"selector,omitempty"`
+	Match    map[string]string `json:"match,omitempty"`
+	Values   []string          `json:"values,omitempty"`
+	Children []jsonFilter      `json:"children,omitempty"`
+}
+
+// Marshal() returns the JSON encoding of the given filter.
+//
+// Supported filters are Null(), All(), Labels() (and other
+// label selector filters), Annotations(), Namespace(), Name(),
+// NSName(), UID(), And(), Or(), and Not().
+func Marshal(f Filter) ([]byte, error) {
+	jf, err := toJSONFilter(f)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jf)
+}
+
+// Unmarshal() returns the filter encoded by Marshal().
+func Unmarshal(data []byte) (ComparableFilter, error) {
+	var jf jsonFilter
+	if err := json.Unmarshal(data, &jf); err != nil {
+		return nil, err
+	}
+	return fromJSONFilter(jf)
+}
+
+func toJSONFilter(f Filter) (jsonFilter, error) {
+	switch f := f.(type) {
+	case nullFilter:
+		return jsonFilter{Type: jsonTypeNull}, nil
+	case allFilter:
+		return jsonFilter{Type: jsonTypeAll}, nil
+	case *selectorFilter:
+		jf := jsonFilter{Type: jsonTypeLabels}
+		// a selector that matches nothing has no string representation.
+		if str := f.selector.String(); str != "" || f.selector.Empty() {
+			jf.Selector = &str
+		}
+		return jf, nil
+	case annotationsFilter:
+		return jsonFilter{Type: jsonTypeAnnotations, Match: f}, nil
+	case namespaceFilter:
+		return jsonFilter{Type: jsonTypeNamespace, Values: stringSet(f).values()}, nil
+	case nameFilter:
+		return jsonFilter{Type: jsonTypeName, Values: stringSet(f).values()}, nil
+	case uidFilter:
+		return jsonFilter{Type: jsonTypeUID, Values: stringSet(f).values()}, nil
+	case nsNameFilter:
+		values := make([]string, 0, len(f.fullset)+len(f.partials))
+		for id := range f.fullset {
+			values = append(values, id.String())
+		}
+		sort.Strings(values)
+		for _, id := range f.partials {
+			values = append(values, id.String())
+		}
+		return jsonFilter{Type: jsonTypeNSName, Values: values}, nil
+	case andFilter:
+		return toJSONFilterList(jsonTypeAnd, f)
+	case orFilter:
+		return toJSONFilterList(jsonTypeOr, f)
+	case *notFilter:
+		return toJSONFilterList(jsonTypeNot, []Filter{f.child})
+	default:
+		return jsonFilter{}, fmt.Errorf("filter type not supported: %T", f)
+	}
+}
+
+func toJSONFilterList(t string, filters []Filter) (jsonFilter, error) {
+	children := make([]jsonFilter, 0, len(filters))
+	for _, f := range filters {
+		child, err := toJSONFilter(f)
+		if err != nil {
+			return jsonFilter{}, err
+		}
+		children = append(children, child)
+	}
+	return jsonFilter{Type: t, Children: children}, nil
+}
+
+func fromJSONFilter(jf jsonFilter) (ComparableFilter, error) {
+	switch jf.Type {
+	case jsonTypeNull:
+		return Null(), nil
+	case jsonTypeAll:
+		return All(), nil
+	case jsonTypeLabels:
+		if jf.Selector == nil {
+			return Selector(labels.Nothing()), nil
+		}
+		return SelectorFromString(*jf.Selector)
+	case jsonTypeAnnotations:
+		return Annotations(jf.Match), nil
+	case jsonTypeNamespace:
+		return Namespace(jf.Values...), nil
+	case jsonTypeName:
+		return Name(jf.Values...), nil
+	case jsonTypeUID:
+		uids := make([]types.UID, 0, len(jf.Values))
+		for _, value := range jf.Values {
+			uids = append(uids, types.UID(value))
+		}
+		return UID(uids...), nil
+	case jsonTypeNSName:
+		ids := make([]nsname.NSName, 0, len(jf.Values))
+		for _, value := range jf.Values {
+			id, err := parseNSName(value)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		return NSName(ids...), nil
+	case jsonTypeAnd:
+		children, err := fromJSONFilterList(jf.Children)
+		if err != nil {
+			return nil, err
+		}
+		return And(children...), nil
+	case jsonTypeOr:
+		children, err := fromJSONFilterList(jf.Children)
+		if err != nil {
+			return nil, err
+		}
+		return Or(children...), nil
+	case jsonTypeNot:
+		if len(jf.Children) != 1 {
+			return nil, fmt.Errorf("not filter requires exactly one child (found %v)", len(jf.Children))
+		}
+		child, err := fromJSONFilter(jf.Children[0])
+		if err != nil {
+			return nil, err
+		}
+		return Not(child), nil
+	default:
+		return nil, fmt.Errorf("unknown filter type: %q", jf.Type)
+	}
+}
+
+func fromJSONFilterList(jfs []jsonFilter) ([]Filter, error) {
+	filters := make([]Filter, 0, len(jfs))
+	for _, jf := range jfs {
+		f, err := fromJSONFilter(jf)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// parseNSName() parses an NSName() filter value.  Unlike nsname.Parse(),
+// the name may be empty to match every object in the namespace.
+func parseNSName(value string) (nsname.NSName, error) {
+	parts := strings.Split(value, "/")
+	switch len(parts) {
+	case 1:
+		return nsname.New("", parts[0]), nil
+	case 2:
+		return nsname.New(parts[0], parts[1]), nil
+	default:
+		return nsname.NSName{}, fmt.Errorf("invalid nsname %q", value)
+	}
+}
@@ -0,0 +1,87 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/nsname"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestJSON_roundTrip(t *testing.T) {
+	sel, err := filter.SelectorFromString("app in (web,api),!tier")
+	require.NoError(t, err)
+
+	filters := []filter.ComparableFilter{
+		filter.Null(),
+		filter.All(),
+		filter.Labels(nil),
+		filter.Labels(map[string]string{"a": "1", "b": "2"}),
+		filter.LabelSelector(nil),
+		filter.LabelSelector(&metav1.LabelSelector{
+			MatchLabels: map[string]string{"app": "web"},
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "x", Operator: metav1.LabelSelectorOpExists},
+				{Key: "tier", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"db", "cache"}},
+			},
+		}),
+		sel,
+		filter.Annotations(map[string]string{"a": "{\"x\": 1}"}),
+		filter.Namespace(),
+		filter.Namespace("a", "b"),
+		filter.Name("x"),
+		filter.UID("1", "2"),
+		filter.NSName(),
+		filter.NSName(nsname.New("a", "1"), nsname.New("b", ""), nsname.New("", "2"), nsname.New("a", "2")),
+		filter.And(),
+		filter.Or(filter.Null()),
+		filter.Not(filter.Name("x")),
+		filter.Or(
+			filter.And(filter.Namespace("default"), filter.Labels(map[string]string{"tier": "fe"})),
+			filter.Not(filter.Or(filter.Name("a"), filter.All())),
+		),
+	}
+
+	for _, f := range filters {
+		data, err := filter.Marshal(f)
+		require.NoError(t, err, "%v", f)
+
+		decoded, err := filter.Unmarshal(data)
+		require.NoError(t, err, "%v: %s", f, data)
+
+		assert.True(t, f.Equals(decoded), "%v != %v (%s)", f, decoded, data)
+		assert.True(t, decoded.Equals(f), "%v != %v (%s)", decoded, f, data)
+	}
+}
+
+func TestJSON_errors(t *testing.T) {
+	fn := filter.FN(func(_ metav1.Object) bool { return true })
+
+	_, err := filter.Marshal(fn)
+	assert.Error(t, err)
+
+	_, err = filter.Marshal(filter.And(filter.Null(), fn))
+	assert.Error(t, err)
+
+	_, err = filter.Unmarshal([]byte(`{"type":"bogus"}`))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "bogus")
+	}
+
+	_, err = filter.Unmarshal([]byte(`{"type":"and","children":[{"type":"bogus"}]}`))
+	assert.Error(t, err)
+
+	_, err = filter.Unmarshal([]byte(`{"type":"not"}`))
+	assert.Error(t, err)
+
+	_, err = filter.Unmarshal([]byte(`{"type":"nsname","values":["a/b/c"]}`))
+	assert.Error(t, err)
+
+	_, err = filter.Unmarshal([]byte(`{"type":"labels","selector":"a in (b"}`))
+	assert.Error(t, err)
+
+	_, err = filter.Unmarshal([]byte(`not json`))
+	assert.Error(t, err)
+}
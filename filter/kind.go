@@ -0,0 +1,46 @@
+package filter
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Kind() returns a filter whose Accept() returns true if the object's
+// kind, as reported by its GroupVersionKind, is one of the given kinds.
+//
+// Objects whose kind can't be determined are rejected.  Typed objects
+// usually have an empty TypeMeta once decoded, so Kind() is intended for
+// unstructured objects.  Kind() with no kinds accepts every object with
+// a kind.
+func Kind(kinds ...string) ComparableFilter {
+	return kindFilter(newStringSet(kinds))
+}
+
+type kindFilter stringSet
+
+func (f kindFilter) Accept(obj metav1.Object) bool {
+	robj, ok := obj.(runtime.Object)
+	if !ok {
+		return false
+	}
+	kind := robj.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" {
+		return false
+	}
+	return stringSet(f).acceptValue(kind)
+}
+
+func (f kindFilter) Equals(other Filter) bool {
+	if other, ok := other.(kindFilter); ok {
+		return stringSet(f).equals(stringSet(other))
+	}
+	return false
+}
+
+func (f kindFilter) String() string {
+	return "Kind(" + stringSet(f).String() + ")"
+}
+
+func (f kindFilter) Hash() uint64 {
+	return hashString(f.String())
+}
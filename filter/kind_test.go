@@ -0,0 +1,42 @@
+package filter_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/boz/kcache/filter"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestKind(t *testing.T) {
+	gen := func(apiVersion, kind string) metav1.Object {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(apiVersion)
+		obj.SetKind(kind)
+		obj.SetName("x")
+		return obj
+	}
+
+	f := filter.Kind("Pod", "Service")
+
+	assert.True(t, f.Accept(gen("v1", "Pod")))
+	assert.True(t, f.Accept(gen("v1", "Service")))
+	assert.False(t, f.Accept(gen("apps/v1", "Deployment")))
+
+	// unknown kinds.
+	assert.False(t, f.Accept(gen("v1", "")))
+	assert.False(t, f.Accept(&v1.Pod{}))
+	assert.False(t, filter.Kind().Accept(&v1.Pod{}))
+
+	assert.True(t, f.Accept(&v1.Pod{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"}}))
+	assert.True(t, filter.Kind().Accept(gen("apps/v1", "Deployment")))
+
+	assert.True(t, f.Equals(filter.Kind("Service", "Pod")))
+	assert.False(t, f.Equals(filter.Kind("Pod")))
+	assert.False(t, f.Equals(filter.Namespace("Pod", "Service")))
+
+	assert.Equal(t, "Kind(Pod,Service)", fmt.Sprint(f))
+}
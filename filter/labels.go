@@ -1,8 +1,6 @@
 package filter
 
 import (
-	"reflect"
-
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 )
@@ -22,6 +20,19 @@ func LabelSelector(ls *metav1.LabelSelector) ComparableFilter {
 	return Selector(selector)
 }
 
+// SelectorFromString() returns a filter which returns true if
+// the object's labels match the given selector expression
+// (for example, "app in (web,api),!tier").
+func SelectorFromString(expr string) (ComparableFilter, error) {
+	selector, err := labels.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	return Selector(selector), nil
+}
+
+// Selector() returns a filter which returns true if
+// the object's labels match the given selector.
 func Selector(selector labels.Selector) ComparableFilter {
 	// assumes selector is sorted
 	return &selectorFilter{selector}
@@ -35,9 +46,21 @@ func (f *selectorFilter) Accept(obj metav1.Object) bool {
 	return f.selector.Matches(labels.Set(obj.GetLabels()))
 }
 
+func (f *selectorFilter) String() string {
+	if str := f.selector.String(); str != "" || f.selector.Empty() {
+		return "Labels(" + str + ")"
+	}
+	return "Labels(<none>)"
+}
+
+func (f *selectorFilter) Hash() uint64 {
+	return hashString(f.String())
+}
+
 func (f *selectorFilter) Equals(other Filter) bool {
 	if other, ok := other.(*selectorFilter); ok {
-		return reflect.DeepEqual(f.selector, other.selector)
+		// String() distinguishes selectors that match nothing from empty selectors.
+		return f.String() == other.String()
 	}
 	return false
 }
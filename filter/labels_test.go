@@ -5,6 +5,7 @@ import (
 
 	"github.com/boz/kcache/filter"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -92,3 +93,45 @@ func TestLabelSelector(t *testing.T) {
 	assert.False(t, fexpr.Equals(fmatch))
 	assert.False(t, fexpr.Equals(filter.All()))
 }
+
+func TestSelectorFromString(t *testing.T) {
+	gen := func(labels map[string]string) metav1.Object {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: labels}}
+	}
+
+	f, err := filter.SelectorFromString("app in (web,api),!tier")
+	require.NoError(t, err)
+
+	assert.True(t, f.Accept(gen(map[string]string{"app": "web"})))
+	assert.True(t, f.Accept(gen(map[string]string{"app": "api", "x": "y"})))
+	assert.False(t, f.Accept(gen(map[string]string{"app": "db"})))
+	assert.False(t, f.Accept(gen(map[string]string{"app": "web", "tier": "fe"})))
+	assert.False(t, f.Accept(gen(nil)))
+
+	other, err := filter.SelectorFromString("!tier,app in (api,web)")
+	require.NoError(t, err)
+	assert.True(t, f.Equals(other))
+
+	fset, err := filter.SelectorFromString("a=1")
+	require.NoError(t, err)
+	assert.True(t, fset.Equals(filter.Labels(map[string]string{"a": "1"})))
+	assert.False(t, fset.Equals(f))
+
+	expr, err := filter.SelectorFromString("app in (web,api),!tier,x")
+	require.NoError(t, err)
+	ls := filter.LabelSelector(&metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "app", Operator: metav1.LabelSelectorOpIn, Values: []string{"web", "api"}},
+			{Key: "tier", Operator: metav1.LabelSelectorOpDoesNotExist},
+			{Key: "x", Operator: metav1.LabelSelectorOpExists},
+		},
+	})
+	assert.True(t, expr.Equals(ls))
+	assert.True(t, ls.Equals(expr))
+
+	assert.False(t, filter.LabelSelector(nil).Equals(filter.Labels(nil)))
+	assert.True(t, filter.LabelSelector(nil).Equals(filter.LabelSelector(nil)))
+
+	_, err = filter.SelectorFromString("app in (web")
+	assert.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package filter
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// FromListOptions() returns a filter equivalent to the label and
+// field selectors of the given list options.
+//
+// Only the metadata.name and metadata.namespace fields are supported;
+// an error is returned for any other field.
+func FromListOptions(opts metav1.ListOptions) (ComparableFilter, error) {
+	var filters []Filter
+
+	if opts.LabelSelector != "" {
+		f, err := SelectorFromString(opts.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector %q: %v", opts.LabelSelector, err)
+		}
+		filters = append(filters, f)
+	}
+
+	if opts.FieldSelector != "" {
+		sel, err := fields.ParseSelector(opts.FieldSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid field selector %q: %v", opts.FieldSelector, err)
+		}
+		for _, req := range sel.Requirements() {
+			f, err := fieldRequirementFilter(req)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, f)
+		}
+	}
+
+	switch len(filters) {
+	case 0:
+		return Null(), nil
+	case 1:
+		return filters[0].(ComparableFilter), nil
+	default:
+		return And(filters...), nil
+	}
+}
+
+func fieldRequirementFilter(req fields.Requirement) (ComparableFilter, error) {
+	var f ComparableFilter
+
+	switch req.Field {
+	case "metadata.name":
+		f = Name(req.Value)
+	case "metadata.namespace":
+		f = Namespace(req.Value)
+	default:
+		return nil, fmt.Errorf("unsupported field selector: %v", req.Field)
+	}
+
+	switch req.Operator {
+	case selection.Equals, selection.DoubleEquals:
+		return f, nil
+	case selection.NotEquals:
+		return Not(f), nil
+	default:
+		return nil, fmt.Errorf("unsupported field selector operator: %v", req.Operator)
+	}
+}
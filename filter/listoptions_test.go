@@ -0,0 +1,67 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/boz/kcache/filter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFromListOptions(t *testing.T) {
+	gen := func(ns, name string, labels map[string]string) metav1.Object {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name, Labels: labels}}
+	}
+
+	{
+		f, err := filter.FromListOptions(metav1.ListOptions{})
+		require.NoError(t, err)
+		assert.True(t, f.Equals(filter.Null()))
+	}
+
+	{
+		f, err := filter.FromListOptions(metav1.ListOptions{LabelSelector: "app=web"})
+		require.NoError(t, err)
+		assert.True(t, f.Accept(gen("a", "x", map[string]string{"app": "web"})))
+		assert.False(t, f.Accept(gen("a", "x", map[string]string{"app": "db"})))
+		assert.True(t, f.Equals(filter.Labels(map[string]string{"app": "web"})))
+	}
+
+	{
+		f, err := filter.FromListOptions(metav1.ListOptions{
+			LabelSelector: "app in (web,api)",
+			FieldSelector: "metadata.namespace=a,metadata.name!=y",
+		})
+		require.NoError(t, err)
+		assert.True(t, f.Accept(gen("a", "x", map[string]string{"app": "web"})))
+		assert.False(t, f.Accept(gen("b", "x", map[string]string{"app": "web"})))
+		assert.False(t, f.Accept(gen("a", "y", map[string]string{"app": "web"})))
+		assert.False(t, f.Accept(gen("a", "x", map[string]string{"app": "db"})))
+
+		same, err := filter.FromListOptions(metav1.ListOptions{
+			LabelSelector: "app in (web,api)",
+			FieldSelector: "metadata.namespace=a,metadata.name!=y",
+		})
+		require.NoError(t, err)
+		assert.True(t, f.Equals(same))
+	}
+
+	{
+		f, err := filter.FromListOptions(metav1.ListOptions{FieldSelector: "metadata.name==x"})
+		require.NoError(t, err)
+		assert.True(t, f.Equals(filter.Name("x")))
+	}
+
+	{
+		_, err := filter.FromListOptions(metav1.ListOptions{FieldSelector: "spec.nodeName=n1"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "spec.nodeName")
+	}
+
+	{
+		_, err := filter.FromListOptions(metav1.ListOptions{LabelSelector: "app in (web"})
+		assert.Error(t, err)
+	}
+}
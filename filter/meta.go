@@ -0,0 +1,316 @@
+package filter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Namespace() returns a filter whose Accept() returns true
+// if the object's namespace is one of the given namespaces.
+//
+// Namespace() with no namespaces accepts everything.
+func Namespace(namespaces ...string) ComparableFilter {
+	return namespaceFilter(newStringSet(namespaces))
+}
+
+// SameNamespace() returns a filter whose Accept() returns true if the
+// object is in the namespace of ref.  It is Namespace(ref.GetNamespace()):
+// if ref is cluster-scoped, only cluster-scoped objects are accepted.
+func SameNamespace(ref metav1.Object) ComparableFilter {
+	return Namespace(ref.GetNamespace())
+}
+
+type namespaceFilter stringSet
+
+func (f namespaceFilter) Accept(obj metav1.Object) bool {
+	return stringSet(f).acceptValue(obj.GetNamespace())
+}
+
+func (f namespaceFilter) Equals(other Filter) bool {
+	if other, ok := other.(namespaceFilter); ok {
+		return stringSet(f).equals(stringSet(other))
+	}
+	return false
+}
+
+func (f namespaceFilter) String() string {
+	return "Namespace(" + stringSet(f).String() + ")"
+}
+
+func (f namespaceFilter) Hash() uint64 {
+	return hashString(f.String())
+}
+
+// NamespacesOf() returns the namespaces that contain every object accepted
+// by f.  ok is false if f may accept objects in any namespace.
+//
+// Only Namespace() and NSName() filters are bounded by namespace.
+func NamespacesOf(f Filter) (namespaces []string, ok bool) {
+	switch f := f.(type) {
+	case namespaceFilter:
+		if len(f) == 0 {
+			return nil, false
+		}
+		return stringSet(f).values(), true
+
+	case nsNameFilter:
+		set := make(stringSet)
+		for id := range f.fullset {
+			set[id.Namespace] = struct{}{}
+		}
+		for _, id := range f.partials {
+			if id.Namespace == "" {
+				return nil, false
+			}
+			set[id.Namespace] = struct{}{}
+		}
+		return set.values(), true
+	}
+	return nil, false
+}
+
+// Name() returns a filter whose Accept() returns true
+// if the object's name is one of the given names, regardless
+// of the object's namespace.
+//
+// Name() with no names accepts everything.
+func Name(names ...string) ComparableFilter {
+	return nameFilter(newStringSet(names))
+}
+
+type nameFilter stringSet
+
+func (f nameFilter) Accept(obj metav1.Object) bool {
+	return stringSet(f).acceptValue(obj.GetName())
+}
+
+func (f nameFilter) Equals(other Filter) bool {
+	if other, ok := other.(nameFilter); ok {
+		return stringSet(f).equals(stringSet(other))
+	}
+	return false
+}
+
+func (f nameFilter) String() string {
+	return "Name(" + stringSet(f).String() + ")"
+}
+
+func (f nameFilter) Hash() uint64 {
+	return hashString(f.String())
+}
+
+// UID() returns a filter whose Accept() returns true
+// if the object's UID is one of the given UIDs.  Unlike Name()
+// and NSName(), it rejects an object recreated with the same name.
+//
+// UID() with no UIDs accepts everything.
+func UID(uids ...types.UID) ComparableFilter {
+	values := make([]string, 0, len(uids))
+	for _, uid := range uids {
+		values = append(values, string(uid))
+	}
+	return uidFilter(newStringSet(values))
+}
+
+type uidFilter stringSet
+
+func (f uidFilter) Accept(obj metav1.Object) bool {
+	return stringSet(f).acceptValue(string(obj.GetUID()))
+}
+
+func (f uidFilter) Equals(other Filter) bool {
+	if other, ok := other.(uidFilter); ok {
+		return stringSet(f).equals(stringSet(other))
+	}
+	return false
+}
+
+func (f uidFilter) String() string {
+	return "UID(" + stringSet(f).String() + ")"
+}
+
+func (f uidFilter) Hash() uint64 {
+	return hashString(f.String())
+}
+
+// OwnerRef() returns a filter whose Accept() returns true
+// if the object has an owner reference to the given UID.
+func OwnerRef(uid types.UID) ComparableFilter {
+	return &ownerRefFilter{uid, false}
+}
+
+// ControllerRef() returns a filter whose Accept() returns true
+// if the object is controlled by the given UID.
+func ControllerRef(uid types.UID) ComparableFilter {
+	return &ownerRefFilter{uid, true}
+}
+
+type ownerRefFilter struct {
+	uid        types.UID
+	controller bool
+}
+
+func (f *ownerRefFilter) Accept(obj metav1.Object) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID != f.uid {
+			continue
+		}
+		if !f.controller || (ref.Controller != nil && *ref.Controller) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *ownerRefFilter) Equals(other Filter) bool {
+	if other, ok := other.(*ownerRefFilter); ok {
+		return *f == *other
+	}
+	return false
+}
+
+func (f *ownerRefFilter) String() string {
+	if f.controller {
+		return fmt.Sprintf("ControllerRef(%v)", f.uid)
+	}
+	return fmt.Sprintf("OwnerRef(%v)", f.uid)
+}
+
+func (f *ownerRefFilter) Hash() uint64 {
+	return hashString(f.String())
+}
+
+// DeletionPending() returns a filter whose Accept() returns true
+// if the object has been marked for deletion but is still present,
+// e.g. while waiting for finalizers to complete.
+func DeletionPending() ComparableFilter {
+	return deletionPendingFilter{}
+}
+
+type deletionPendingFilter struct{}
+
+func (deletionPendingFilter) Accept(obj metav1.Object) bool {
+	return obj.GetDeletionTimestamp() != nil
+}
+
+func (deletionPendingFilter) Equals(other Filter) bool {
+	_, ok := other.(deletionPendingFilter)
+	return ok
+}
+
+func (deletionPendingFilter) String() string {
+	return "DeletionPending()"
+}
+
+func (deletionPendingFilter) Hash() uint64 {
+	return hashString("DeletionPending()")
+}
+
+// HasFinalizer() returns a filter whose Accept() returns true
+// if the object's finalizers include the given finalizer.
+func HasFinalizer(name string) ComparableFilter {
+	return finalizerFilter(name)
+}
+
+type finalizerFilter string
+
+func (f finalizerFilter) Accept(obj metav1.Object) bool {
+	for _, finalizer := range obj.GetFinalizers() {
+		if finalizer == string(f) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f finalizerFilter) Equals(other Filter) bool {
+	if other, ok := other.(finalizerFilter); ok {
+		return f == other
+	}
+	return false
+}
+
+func (f finalizerFilter) String() string {
+	return fmt.Sprintf("HasFinalizer(%v)", string(f))
+}
+
+func (f finalizerFilter) Hash() uint64 {
+	return hashString(f.String())
+}
+
+// GenerationAtLeast() returns a filter whose Accept() returns true
+// if the object's generation is at least n.  Objects that do not
+// track their generation report zero.
+func GenerationAtLeast(n int64) ComparableFilter {
+	return generationFilter(n)
+}
+
+type generationFilter int64
+
+func (f generationFilter) Accept(obj metav1.Object) bool {
+	return obj.GetGeneration() >= int64(f)
+}
+
+func (f generationFilter) Equals(other Filter) bool {
+	if other, ok := other.(generationFilter); ok {
+		return f == other
+	}
+	return false
+}
+
+func (f generationFilter) String() string {
+	return fmt.Sprintf("GenerationAtLeast(%v)", int64(f))
+}
+
+func (f generationFilter) Hash() uint64 {
+	return hashString(f.String())
+}
+
+type stringSet map[string]struct{}
+
+func newStringSet(values []string) stringSet {
+	set := make(stringSet, len(values))
+	for _, value := range values {
+		set[value] = struct{}{}
+	}
+	return set
+}
+
+// acceptValue() returns true if the set is empty or contains value.
+func (s stringSet) acceptValue(value string) bool {
+	if len(s) == 0 {
+		return true
+	}
+	_, ok := s[value]
+	return ok
+}
+
+func (s stringSet) equals(other stringSet) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for k := range s {
+		if _, ok := other[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (s stringSet) String() string {
+	return strings.Join(s.values(), ",")
+}
+
+// values() returns the members of the set in sorted order.
+func (s stringSet) values() []string {
+	values := make([]string, 0, len(s))
+	for k := range s {
+		values = append(values, k)
+	}
+	sort.Strings(values)
+	return values
+}
@@ -0,0 +1,202 @@
+package filter_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/nsname"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestNamespace(t *testing.T) {
+	gen := func(ns string) metav1.Object {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "x"}}
+	}
+
+	assert.True(t, filter.Namespace("a").Accept(gen("a")))
+	assert.True(t, filter.Namespace("a", "b").Accept(gen("b")))
+	assert.False(t, filter.Namespace("a").Accept(gen("b")))
+	assert.False(t, filter.Namespace("a", "c").Accept(gen("b")))
+	assert.False(t, filter.Namespace("a").Accept(gen("")))
+
+	assert.True(t, filter.Namespace().Accept(gen("a")))
+	assert.True(t, filter.Namespace().Accept(gen("")))
+
+	assert.True(t, filter.Namespace().Equals(filter.Namespace()))
+	assert.True(t, filter.Namespace("a").Equals(filter.Namespace("a")))
+	assert.True(t, filter.Namespace("a", "b").Equals(filter.Namespace("b", "a")))
+	assert.True(t, filter.Namespace("a", "a").Equals(filter.Namespace("a")))
+	assert.False(t, filter.Namespace("a").Equals(filter.Namespace("a", "b")))
+	assert.False(t, filter.Namespace("a").Equals(filter.Namespace("b")))
+	assert.False(t, filter.Namespace("a").Equals(nil))
+}
+
+func TestSameNamespace(t *testing.T) {
+	gen := func(ns string) metav1.Object {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: "x"}}
+	}
+
+	f := filter.SameNamespace(&v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "y"}})
+	assert.True(t, f.Accept(gen("a")))
+	assert.False(t, f.Accept(gen("b")))
+	assert.False(t, f.Accept(gen("")))
+
+	cluster := filter.SameNamespace(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n"}})
+	assert.True(t, cluster.Accept(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "m"}}))
+	assert.False(t, cluster.Accept(gen("a")))
+
+	assert.True(t, f.Equals(filter.SameNamespace(gen("a"))))
+	assert.True(t, f.Equals(filter.Namespace("a")))
+	assert.False(t, f.Equals(filter.SameNamespace(gen("b"))))
+	assert.False(t, f.Equals(cluster))
+}
+
+func TestNamespacesOf(t *testing.T) {
+	for _, test := range []struct {
+		f          filter.Filter
+		namespaces []string
+		ok         bool
+	}{
+		{filter.Namespace("b", "a"), []string{"a", "b"}, true},
+		{filter.Namespace(), nil, false},
+		{filter.NSName(nsname.New("b", "1"), nsname.New("a", ""), nsname.New("b", "2")), []string{"a", "b"}, true},
+		{filter.NSName(), []string{}, true},
+		{filter.NSName(nsname.New("a", "1"), nsname.New("", "2")), nil, false},
+		{filter.Null(), nil, false},
+		{filter.And(filter.Namespace("a")), nil, false},
+		{nil, nil, false},
+	} {
+		namespaces, ok := filter.NamespacesOf(test.f)
+		assert.Equal(t, test.ok, ok, "%v", test.f)
+		assert.Equal(t, test.namespaces, namespaces, "%v", test.f)
+	}
+}
+
+func TestName(t *testing.T) {
+	gen := func(ns, name string) metav1.Object {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}
+	}
+
+	assert.True(t, filter.Name("x").Accept(gen("a", "x")))
+	assert.True(t, filter.Name("x").Accept(gen("b", "x")))
+	assert.True(t, filter.Name("x", "y").Accept(gen("a", "y")))
+	assert.False(t, filter.Name("x").Accept(gen("a", "y")))
+	assert.True(t, filter.Name().Accept(gen("a", "y")))
+
+	assert.True(t, filter.Name().Equals(filter.Name()))
+	assert.True(t, filter.Name("x", "y").Equals(filter.Name("y", "x")))
+	assert.False(t, filter.Name("x").Equals(filter.Name("y")))
+	assert.False(t, filter.Name("x").Equals(filter.Namespace("x")))
+	assert.False(t, filter.Namespace("x").Equals(filter.Name("x")))
+
+	f := filter.And(filter.Namespace("kube-system"), filter.Name("coredns"))
+	assert.True(t, f.Accept(gen("kube-system", "coredns")))
+	assert.False(t, f.Accept(gen("default", "coredns")))
+	assert.False(t, f.Accept(gen("kube-system", "kube-dns")))
+	assert.True(t, f.Equals(filter.And(filter.Namespace("kube-system"), filter.Name("coredns"))))
+	assert.False(t, f.Equals(filter.And(filter.Namespace("kube-system"), filter.Name("kube-dns"))))
+}
+
+func TestUID(t *testing.T) {
+	gen := func(name string, uid types.UID) metav1.Object {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: name, UID: uid}}
+	}
+
+	assert.True(t, filter.UID("1").Accept(gen("x", "1")))
+	assert.True(t, filter.UID("1", "2").Accept(gen("y", "2")))
+	assert.False(t, filter.UID("1").Accept(gen("x", "2")))
+	assert.False(t, filter.UID("1").Accept(gen("x", "")))
+	assert.True(t, filter.UID().Accept(gen("x", "2")))
+
+	assert.True(t, filter.UID().Equals(filter.UID()))
+	assert.True(t, filter.UID("1", "2").Equals(filter.UID("2", "1")))
+	assert.False(t, filter.UID("1").Equals(filter.UID("2")))
+	assert.False(t, filter.UID("x").Equals(filter.Name("x")))
+	assert.False(t, filter.Name("x").Equals(filter.UID("x")))
+
+	assert.Equal(t, "UID(1,2)", fmt.Sprint(filter.UID("2", "1")))
+}
+
+func TestOwnerRef(t *testing.T) {
+	yes := true
+	no := false
+
+	gen := func(refs ...metav1.OwnerReference) metav1.Object {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{OwnerReferences: refs}}
+	}
+
+	owner := metav1.OwnerReference{UID: types.UID("a")}
+	controller := metav1.OwnerReference{UID: types.UID("a"), Controller: &yes}
+	notController := metav1.OwnerReference{UID: types.UID("a"), Controller: &no}
+	other := metav1.OwnerReference{UID: types.UID("b"), Controller: &yes}
+
+	assert.True(t, filter.OwnerRef("a").Accept(gen(owner)))
+	assert.True(t, filter.OwnerRef("a").Accept(gen(controller)))
+	assert.True(t, filter.OwnerRef("a").Accept(gen(other, notController)))
+	assert.False(t, filter.OwnerRef("a").Accept(gen(other)))
+	assert.False(t, filter.OwnerRef("a").Accept(gen()))
+
+	assert.True(t, filter.ControllerRef("a").Accept(gen(controller)))
+	assert.True(t, filter.ControllerRef("a").Accept(gen(other, controller)))
+	assert.False(t, filter.ControllerRef("a").Accept(gen(owner)))
+	assert.False(t, filter.ControllerRef("a").Accept(gen(notController)))
+	assert.False(t, filter.ControllerRef("a").Accept(gen(other)))
+
+	assert.True(t, filter.OwnerRef("a").Equals(filter.OwnerRef("a")))
+	assert.False(t, filter.OwnerRef("a").Equals(filter.OwnerRef("b")))
+	assert.False(t, filter.OwnerRef("a").Equals(filter.ControllerRef("a")))
+	assert.True(t, filter.ControllerRef("a").Equals(filter.ControllerRef("a")))
+	assert.False(t, filter.ControllerRef("a").Equals(filter.OwnerRef("a")))
+}
+
+func TestDeletionPending(t *testing.T) {
+	now := metav1.Now()
+
+	deleting := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "1", DeletionTimestamp: &now}}
+	present := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "2"}}
+
+	assert.True(t, filter.DeletionPending().Accept(deleting))
+	assert.False(t, filter.DeletionPending().Accept(present))
+
+	assert.True(t, filter.DeletionPending().Equals(filter.DeletionPending()))
+	assert.False(t, filter.DeletionPending().Equals(filter.All()))
+	assert.False(t, filter.DeletionPending().Equals(nil))
+}
+
+func TestHasFinalizer(t *testing.T) {
+	gen := func(finalizers ...string) metav1.Object {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "1", Finalizers: finalizers}}
+	}
+
+	assert.True(t, filter.HasFinalizer("x").Accept(gen("x")))
+	assert.True(t, filter.HasFinalizer("x").Accept(gen("y", "x")))
+	assert.False(t, filter.HasFinalizer("x").Accept(gen("y")))
+	assert.False(t, filter.HasFinalizer("x").Accept(gen()))
+
+	assert.True(t, filter.HasFinalizer("x").Equals(filter.HasFinalizer("x")))
+	assert.False(t, filter.HasFinalizer("x").Equals(filter.HasFinalizer("y")))
+	assert.False(t, filter.HasFinalizer("x").Equals(filter.DeletionPending()))
+	assert.False(t, filter.HasFinalizer("x").Equals(nil))
+}
+
+func TestGenerationAtLeast(t *testing.T) {
+	gen := func(generation int64) metav1.Object {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "1", Generation: generation}}
+	}
+
+	assert.True(t, filter.GenerationAtLeast(2).Accept(gen(2)))
+	assert.True(t, filter.GenerationAtLeast(2).Accept(gen(3)))
+	assert.False(t, filter.GenerationAtLeast(2).Accept(gen(1)))
+	assert.False(t, filter.GenerationAtLeast(1).Accept(gen(0)))
+	assert.True(t, filter.GenerationAtLeast(0).Accept(gen(0)))
+
+	assert.True(t, filter.GenerationAtLeast(2).Equals(filter.GenerationAtLeast(2)))
+	assert.False(t, filter.GenerationAtLeast(2).Equals(filter.GenerationAtLeast(3)))
+	assert.False(t, filter.GenerationAtLeast(2).Equals(filter.HasFinalizer("2")))
+
+	assert.Equal(t, "GenerationAtLeast(2)", fmt.Sprint(filter.GenerationAtLeast(2)))
+}
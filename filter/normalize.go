@@ -0,0 +1,129 @@
+package filter
+
+// Normalize() returns a filter that accepts the same objects as f in a
+// canonical form, so that more equivalent filters compare equal with
+// FiltersEqual():
+//
+//   - Not(Not(a)) becomes a.
+//   - Not(And(a, b)) becomes Or(Not(a), Not(b)), and Not(Or(a, b))
+//     becomes And(Not(a), Not(b)).
+//   - Nested And() and Or() filters are flattened into their parent.
+//   - Null() is removed from And() and All() from Or(); And() containing
+//     All() becomes All() and Or() containing Null() becomes Null().
+//   - And() and Or() with a single child become that child.
+//
+// Children keep their order, which determines the cost of evaluation.
+// f is returned unchanged unless it and all of its descendants are
+// comparable.
+func Normalize(f Filter) Filter {
+	if !comparable(f) {
+		return f
+	}
+	return normalize(f)
+}
+
+func normalize(f Filter) Filter {
+	switch f := f.(type) {
+	case *notFilter:
+		return normalizeNot(f)
+	case andFilter:
+		return normalizeList(f, true)
+	case orFilter:
+		return normalizeList(f, false)
+	}
+	return f
+}
+
+func normalizeNot(f *notFilter) Filter {
+	switch child := f.child.(type) {
+	case *notFilter:
+		return normalize(child.child)
+	case andFilter:
+		return normalize(orFilter(negateList(child)))
+	case orFilter:
+		return normalize(andFilter(negateList(child)))
+	}
+	return Not(normalize(f.child))
+}
+
+// normalizeList() normalizes the children of an And() filter if and is
+// true, or of an Or() filter otherwise.
+func normalizeList(children []Filter, and bool) Filter {
+	var result []Filter
+	for _, child := range children {
+		child = normalize(child)
+
+		switch child := child.(type) {
+		case nullFilter:
+			if !and {
+				return Null()
+			}
+			continue
+		case allFilter:
+			if and {
+				return All()
+			}
+			continue
+		case andFilter:
+			if and {
+				result = append(result, child...)
+				continue
+			}
+		case orFilter:
+			if !and {
+				result = append(result, child...)
+				continue
+			}
+		}
+		result = append(result, child)
+	}
+
+	switch {
+	case len(result) == 0 && and:
+		return Null()
+	case len(result) == 0:
+		return All()
+	case len(result) == 1:
+		return result[0]
+	}
+	return compose(result, and)
+}
+
+func compose(children []Filter, and bool) Filter {
+	if and {
+		return andFilter(children)
+	}
+	return orFilter(children)
+}
+
+func negateList(children []Filter) []Filter {
+	result := make([]Filter, 0, len(children))
+	for _, child := range children {
+		result = append(result, Not(child))
+	}
+	return result
+}
+
+// comparable() returns true if f and all of its descendants are
+// comparable.
+func comparable(f Filter) bool {
+	switch f := f.(type) {
+	case *notFilter:
+		return comparable(f.child)
+	case andFilter:
+		return comparableList(f)
+	case orFilter:
+		return comparableList(f)
+	}
+	_, ok := f.(ComparableFilter)
+	return ok
+}
+
+func comparableList(children []Filter) bool {
+	for _, child := range children {
+		if !comparable(child) {
+			return false
+		}
+	}
+	return true
+}
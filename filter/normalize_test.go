@@ -0,0 +1,60 @@
+package filter_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/boz/kcache/filter"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNormalize(t *testing.T) {
+	a := filter.Labels(map[string]string{"a": "1"})
+	b := filter.Labels(map[string]string{"b": "2"})
+	c := filter.Namespace("c")
+
+	for _, test := range []struct {
+		name     string
+		f        filter.Filter
+		expected filter.Filter
+	}{
+		{"plain", a, a},
+		{"not not", filter.Not(filter.Not(a)), a},
+		{"not not not", filter.Not(filter.Not(filter.Not(a))), filter.Not(a)},
+		{"de morgan and", filter.Not(filter.And(a, b)), filter.Or(filter.Not(a), filter.Not(b))},
+		{"de morgan or", filter.Not(filter.Or(a, filter.Not(b))), filter.And(filter.Not(a), b)},
+		{"flatten and", filter.And(a, filter.And(b, c)), filter.And(a, b, c)},
+		{"flatten or", filter.Or(filter.Or(a, b), c), filter.Or(a, b, c)},
+		{"nested not flattened", filter.Not(filter.Or(a, filter.Not(filter.And(b, c)))),
+			filter.And(filter.Not(a), b, c)},
+		{"and identity", filter.And(filter.Null(), a), a},
+		{"and all", filter.And(a, filter.All()), filter.All()},
+		{"or identity", filter.Or(a, filter.All(), b), filter.Or(a, b)},
+		{"or null", filter.Or(a, filter.Null()), filter.Null()},
+		{"empty and", filter.And(), filter.Null()},
+		{"empty or", filter.Or(), filter.All()},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			actual := filter.Normalize(test.f)
+			assert.True(t, filter.FiltersEqual(test.expected, actual), "%v: %v != %v", test.f, actual, test.expected)
+		})
+	}
+
+	t.Run("incomparable", func(t *testing.T) {
+		fn := filter.FN(func(_ metav1.Object) bool { return true })
+
+		for _, f := range []filter.Filter{
+			fn,
+			filter.Not(filter.Not(fn)),
+			filter.Not(filter.And(a, fn)),
+			filter.And(filter.Null(), filter.And(a, fn)),
+		} {
+			assert.Equal(t, fmt.Sprint(f), fmt.Sprint(filter.Normalize(f)))
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		assert.Nil(t, filter.Normalize(nil))
+	})
+}
@@ -0,0 +1,75 @@
+package filter
+
+import (
+	"regexp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NameRegex() returns a filter whose Accept() returns true
+// if the object's name matches the given regular expression.
+func NameRegex(pattern string) (ComparableFilter, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &nameRegexFilter{pattern, re}, nil
+}
+
+type nameRegexFilter struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+func (f *nameRegexFilter) Accept(obj metav1.Object) bool {
+	return f.re.MatchString(obj.GetName())
+}
+
+func (f *nameRegexFilter) Equals(other Filter) bool {
+	if other, ok := other.(*nameRegexFilter); ok {
+		return f.pattern == other.pattern
+	}
+	return false
+}
+
+func (f *nameRegexFilter) String() string {
+	return "NameRegex(" + f.pattern + ")"
+}
+
+func (f *nameRegexFilter) Hash() uint64 {
+	return hashString(f.String())
+}
+
+// NamespaceRegex() returns a filter whose Accept() returns true
+// if the object's namespace matches the given regular expression.
+func NamespaceRegex(pattern string) (ComparableFilter, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &namespaceRegexFilter{pattern, re}, nil
+}
+
+type namespaceRegexFilter struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+func (f *namespaceRegexFilter) Accept(obj metav1.Object) bool {
+	return f.re.MatchString(obj.GetNamespace())
+}
+
+func (f *namespaceRegexFilter) Equals(other Filter) bool {
+	if other, ok := other.(*namespaceRegexFilter); ok {
+		return f.pattern == other.pattern
+	}
+	return false
+}
+
+func (f *namespaceRegexFilter) String() string {
+	return "NamespaceRegex(" + f.pattern + ")"
+}
+
+func (f *namespaceRegexFilter) Hash() uint64 {
+	return hashString(f.String())
+}
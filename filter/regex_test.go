@@ -0,0 +1,66 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/boz/kcache/filter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNameRegex(t *testing.T) {
+	gen := func(ns, name string) metav1.Object {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}
+	}
+
+	f, err := filter.NameRegex("^nginx-")
+	require.NoError(t, err)
+
+	assert.True(t, f.Accept(gen("a", "nginx-1234")))
+	assert.True(t, f.Accept(gen("b", "nginx-")))
+	assert.False(t, f.Accept(gen("a", "nginx")))
+	assert.False(t, f.Accept(gen("a", "my-nginx-1234")))
+
+	same, err := filter.NameRegex("^nginx-")
+	require.NoError(t, err)
+	other, err := filter.NameRegex("nginx-")
+	require.NoError(t, err)
+
+	assert.True(t, f.Equals(f))
+	assert.True(t, f.Equals(same))
+	assert.False(t, f.Equals(other))
+	assert.False(t, f.Equals(filter.Name("nginx-")))
+
+	_, err = filter.NameRegex("nginx-(")
+	assert.Error(t, err)
+}
+
+func TestNamespaceRegex(t *testing.T) {
+	gen := func(ns, name string) metav1.Object {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}
+	}
+
+	f, err := filter.NamespaceRegex("^tenant-[0-9]+$")
+	require.NoError(t, err)
+
+	assert.True(t, f.Accept(gen("tenant-1", "a")))
+	assert.True(t, f.Accept(gen("tenant-42", "b")))
+	assert.False(t, f.Accept(gen("tenant-", "a")))
+	assert.False(t, f.Accept(gen("tenant-1-staging", "a")))
+	assert.False(t, f.Accept(gen("default", "tenant-1")))
+
+	same, err := filter.NamespaceRegex("^tenant-[0-9]+$")
+	require.NoError(t, err)
+	name, err := filter.NameRegex("^tenant-[0-9]+$")
+	require.NoError(t, err)
+
+	assert.True(t, f.Equals(same))
+	assert.False(t, f.Equals(name))
+	assert.False(t, name.Equals(f))
+	assert.False(t, f.Equals(filter.Namespace("tenant-1")))
+
+	_, err = filter.NamespaceRegex("tenant-(")
+	assert.Error(t, err)
+}
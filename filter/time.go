@@ -0,0 +1,38 @@
+package filter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/boz/kcache/clock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CreatedWithin() returns a filter whose Accept() returns true
+// if the object was created within the last d.
+//
+// The result of Accept() depends on the time it is called, so
+// the returned filter is not a ComparableFilter and should not
+// be used where the filter needs to be stable, e.g. with Refilter().
+func CreatedWithin(d time.Duration) Filter {
+	return CreatedWithinClock(d, clock.Real())
+}
+
+// CreatedWithinClock() is like CreatedWithin() but reads the current
+// time from c, so that tests can drive it with a clock.Fake.
+func CreatedWithinClock(d time.Duration, c clock.Clock) Filter {
+	return createdWithinFilter{d, c}
+}
+
+type createdWithinFilter struct {
+	d     time.Duration
+	clock clock.Clock
+}
+
+func (f createdWithinFilter) Accept(obj metav1.Object) bool {
+	return f.clock.Now().Sub(obj.GetCreationTimestamp().Time) <= f.d
+}
+
+func (f createdWithinFilter) String() string {
+	return fmt.Sprintf("CreatedWithin(%v)", f.d)
+}
@@ -0,0 +1,57 @@
+package filter_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/boz/kcache/clock"
+	"github.com/boz/kcache/filter"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCreatedWithin(t *testing.T) {
+	gen := func(age time.Duration) metav1.Object {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "a",
+			Name:              "1",
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+		}}
+	}
+
+	f := filter.CreatedWithin(5 * time.Minute)
+
+	assert.True(t, f.Accept(gen(0)))
+	assert.True(t, f.Accept(gen(time.Minute)))
+	assert.False(t, f.Accept(gen(10*time.Minute)))
+
+	_, ok := f.(filter.ComparableFilter)
+	assert.False(t, ok)
+	assert.False(t, filter.FiltersEqual(f, f))
+
+	assert.Equal(t, "CreatedWithin(5m0s)", fmt.Sprint(f))
+}
+
+func TestCreatedWithinClock(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := clock.NewFake(start)
+
+	obj := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace:         "a",
+		Name:              "1",
+		CreationTimestamp: metav1.NewTime(start),
+	}}
+
+	f := filter.CreatedWithinClock(5*time.Minute, c)
+	assert.True(t, f.Accept(obj))
+
+	c.Advance(5 * time.Minute)
+	assert.True(t, f.Accept(obj))
+
+	c.Advance(time.Second)
+	assert.False(t, f.Accept(obj))
+
+	assert.Equal(t, "CreatedWithin(5m0s)", fmt.Sprint(f))
+}
@@ -0,0 +1,32 @@
+package kcache
+
+import (
+	"context"
+
+	logutil "github.com/boz/go-logutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+)
+
+// FromInformer() returns a controller that shares informer's watch and
+// indexers, so that consumers of an existing informer can adopt
+// subscriptions and filters one at a time.  See Builder.Informer().
+func FromInformer(ctx context.Context, log logutil.Log, informer toolscache.SharedIndexInformer) (Controller, error) {
+	return NewBuilder().
+		Context(ctx).
+		Log(log).
+		Informer(informer).
+		Create()
+}
+
+// informerIndexFunc() adapts an informer's index function.  Objects for
+// which fn fails are not indexed.
+func informerIndexFunc(fn toolscache.IndexFunc) IndexFunc {
+	return func(obj metav1.Object) []string {
+		values, err := fn(obj)
+		if err != nil {
+			return nil
+		}
+		return values
+	}
+}
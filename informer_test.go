@@ -0,0 +1,97 @@
+package kcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	toolscache "k8s.io/client-go/tools/cache"
+)
+
+func TestFromInformer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	genpod := func(name, vsn, node string) *v1.Pod {
+		pod := testGenPod("ns", name, vsn)
+		pod.Spec.NodeName = node
+		return pod
+	}
+
+	fw := watch.NewFake()
+	lw := &toolscache.ListWatch{
+		ListFunc: func(metav1.ListOptions) (runtime.Object, error) {
+			return &v1.PodList{
+				ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+				Items:    []v1.Pod{*genpod("a", "1", "n1")},
+			}, nil
+		},
+		WatchFunc: func(metav1.ListOptions) (watch.Interface, error) {
+			return fw, nil
+		},
+	}
+
+	informer := toolscache.NewSharedIndexInformer(lw, &v1.Pod{}, 0, toolscache.Indexers{
+		"node": func(obj interface{}) ([]string, error) {
+			return []string{obj.(*v1.Pod).Spec.NodeName}, nil
+		},
+	})
+
+	_, err := NewBuilder().Informer(informer).LabelSelector(labels.SelectorFromSet(labels.Set{"a": "b"})).Create()
+	assert.Error(t, err)
+
+	controller, err := FromInformer(ctx, logutil.Default(), informer)
+	require.NoError(t, err)
+	defer controller.Close()
+
+	go informer.Run(ctx.Done())
+
+	sctx, scancel := context.WithTimeout(ctx, 5*time.Second)
+	defer scancel()
+	require.True(t, controller.WaitForSync(sctx))
+
+	obj, err := controller.Cache().Get("ns", "a")
+	require.NoError(t, err)
+	require.NotNil(t, obj)
+
+	list, err := controller.Cache().ByIndex("node", "n1")
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	sub, err := controller.Subscribe()
+	require.NoError(t, err)
+	testutil.AssertReady(t, "sub", sub)
+
+	read := func(etype EventType, name, vsn string) {
+		select {
+		case evt := <-sub.Events():
+			assert.Equal(t, etype, evt.Type())
+			assert.Equal(t, name, evt.Resource().GetName())
+			assert.Equal(t, vsn, evt.Resource().GetResourceVersion())
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "no event", "%v %v", etype, name)
+		}
+	}
+
+	fw.Add(genpod("b", "2", "n2"))
+	read(EventTypeCreate, "b", "2")
+
+	fw.Modify(genpod("b", "3", "n1"))
+	read(EventTypeUpdate, "b", "3")
+
+	list, err = controller.Cache().ByIndex("node", "n1")
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	fw.Delete(genpod("a", "4", "n1"))
+	read(EventTypeDelete, "a", "4")
+}
@@ -0,0 +1,159 @@
+package kcache
+
+import (
+	"sync"
+
+	"github.com/boz/kcache/filter"
+	"github.com/pkg/errors"
+)
+
+// interestTracker maintains the union of the filters of a controller's
+// subscribers for Builder.FilteredCache().  A nil tracker tracks nothing.
+type interestTracker struct {
+	// in subscription order, so that unions of the same filters are equal.
+	interests []interest
+
+	// true if a filter was added or replaced since the last union().
+	broadened bool
+
+	changech chan struct{}
+	mtx      sync.Mutex
+}
+
+type interest struct {
+	sub    Subscription
+	filter filter.Filter
+}
+
+func newInterestTracker() *interestTracker {
+	return &interestTracker{changech: make(chan struct{}, 1)}
+}
+
+// add() records the filter of a new subscription.  A nil filter
+// is interested in every object.
+func (t *interestTracker) add(sub Subscription, f filter.Filter) {
+	if t == nil {
+		return
+	}
+	if f == nil {
+		f = filter.Null()
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	t.interests = append(t.interests, interest{sub, f})
+	t.broadened = true
+	t.notify()
+}
+
+// update() replaces the filter of sub if it is still tracked.  A nil
+// filter is interested in every object.
+func (t *interestTracker) update(sub Subscription, f filter.Filter) {
+	if t == nil {
+		return
+	}
+	if f == nil {
+		f = filter.Null()
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for idx := range t.interests {
+		if t.interests[idx].sub == sub {
+			t.interests[idx].filter = f
+			t.broadened = true
+			t.notify()
+			return
+		}
+	}
+}
+
+func (t *interestTracker) remove(sub Subscription) {
+	if t == nil {
+		return
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for idx := range t.interests {
+		if t.interests[idx].sub == sub {
+			t.interests = append(t.interests[:idx], t.interests[idx+1:]...)
+			t.notify()
+			return
+		}
+	}
+}
+
+// changed() is signalled when the union may have changed.
+func (t *interestTracker) changed() <-chan struct{} {
+	if t == nil {
+		return nil
+	}
+	return t.changech
+}
+
+// union() returns a filter accepting the objects accepted by any
+// tracked filter, including each distinct filter once, and whether
+// filters were added or replaced since the last call.
+func (t *interestTracker) union() (filter.Filter, bool) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	broadened := t.broadened
+	t.broadened = false
+
+	filters := make([]filter.Filter, 0, len(t.interests))
+	for _, interest := range t.interests {
+		if filter.FiltersEqual(interest.filter, filter.Null()) {
+			return filter.Null(), broadened
+		}
+		filters = append(filters, interest.filter)
+	}
+	return filter.Or(filter.Distinct(filters)...), broadened
+}
+
+func (t *interestTracker) notify() {
+	select {
+	case t.changech <- struct{}{}:
+	default:
+	}
+}
+
+// interestSubscription updates the tracked filter of the subscription
+// key when it is refiltered.
+type interestSubscription struct {
+	FilterSubscription
+	key      Subscription
+	interest *interestTracker
+}
+
+func (s *interestSubscription) Refilter(f filter.Filter) error {
+	return s.RefilterWithRate(f, 0)
+}
+
+func (s *interestSubscription) RefilterWithRate(f filter.Filter, limit float64) error {
+	if err := s.FilterSubscription.RefilterWithRate(f, limit); err != nil {
+		return err
+	}
+	s.interest.update(s.key, f)
+	return nil
+}
+
+func (s *interestSubscription) markSnapshot(m *snapshotMarker) error {
+	parent, ok := s.FilterSubscription.(snapshotMarkable)
+	if !ok {
+		return errors.Errorf("snapshot not supported by %T", s.FilterSubscription)
+	}
+	return parent.markSnapshot(m)
+}
+
+func (s *interestSubscription) markFlush(m *flushMarker) error {
+	parent, ok := s.FilterSubscription.(flushMarkable)
+	if !ok {
+		return errors.Errorf("flush not supported by %T", s.FilterSubscription)
+	}
+	return parent.markFlush(m)
+}
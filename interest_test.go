@@ -0,0 +1,171 @@
+package kcache
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/client/mocks"
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestInterestTracker(t *testing.T) {
+	var empty *interestTracker
+	empty.add(nil, filter.Null())
+	empty.remove(nil)
+	assert.Nil(t, empty.changed())
+
+	a, _, _ := testNewSubscription(t, logutil.Default(), filter.Null())
+	b, _, _ := testNewSubscription(t, logutil.Default(), filter.Null())
+	c, _, _ := testNewSubscription(t, logutil.Default(), filter.Null())
+	defer a.Close()
+	defer b.Close()
+	defer c.Close()
+
+	tracker := newInterestTracker()
+
+	f, broadened := tracker.union()
+	assert.True(t, filter.Or().Equals(f))
+	assert.False(t, broadened)
+
+	tracker.add(a, filter.Namespace("x"))
+	tracker.add(b, filter.Namespace("y"))
+	<-tracker.changed()
+
+	f, broadened = tracker.union()
+	assert.True(t, filter.Or(filter.Namespace("x"), filter.Namespace("y")).Equals(f))
+	assert.True(t, broadened)
+
+	// equal filters are evaluated once.
+	tracker.add(c, filter.Namespace("x"))
+	f, _ = tracker.union()
+	assert.True(t, filter.Or(filter.Namespace("x"), filter.Namespace("y")).Equals(f))
+	tracker.remove(c)
+
+	tracker.update(a, nil)
+	f, broadened = tracker.union()
+	assert.True(t, filter.Null().Equals(f))
+	assert.True(t, broadened)
+
+	tracker.remove(a)
+	f, broadened = tracker.union()
+	assert.True(t, filter.Or(filter.Namespace("y")).Equals(f))
+	assert.False(t, broadened)
+
+	// removed subscriptions are not updated.
+	tracker.update(a, filter.Namespace("z"))
+	f, _ = tracker.union()
+	assert.True(t, filter.Or(filter.Namespace("y")).Equals(f))
+}
+
+func TestController_FilteredCache(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	list := &v1.PodList{
+		ListMeta: metav1.ListMeta{ResourceVersion: "3"},
+		Items: []v1.Pod{
+			*testGenPod("x", "a", "1"),
+			*testGenPod("y", "b", "2"),
+			*testGenPod("z", "c", "3"),
+		},
+	}
+
+	client := &mocks.Client{}
+	client.On("Watch", mock.Anything, mock.AnythingOfType("v1.ListOptions")).Return(testutil.MockWatch(make(chan watch.Event)), nil)
+	var lists int32
+	client.On("List", mock.Anything, mock.AnythingOfType("v1.ListOptions")).
+		Run(func(mock.Arguments) { atomic.AddInt32(&lists, 1) }).
+		Return(list, nil)
+
+	controller := testNewController(t, testContext(ctx), testClient(client), testBuild(func(b Builder) {
+		b.Filter(filter.Not(filter.Name("c"))).FilteredCache()
+	}))
+	defer controller.Close()
+
+	testutil.AssertReady(t, "controller", controller)
+
+	names := func() []string {
+		objs, err := controller.Cache().List()
+		require.NoError(t, err)
+		var names []string
+		for _, obj := range objs {
+			names = append(names, obj.GetName())
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	waitCache := func(t *testing.T, expected ...string) {
+		timeout := testutil.Timerch(ctx, time.Second)
+		for {
+			current := names()
+			if assert.ObjectsAreEqual(expected, current) {
+				return
+			}
+			select {
+			case <-time.After(time.Millisecond):
+			case <-timeout:
+				require.Equal(t, expected, current)
+				return
+			}
+		}
+	}
+
+	assert.Empty(t, names())
+
+	// broadened: relisted.
+	sx, err := controller.SubscribeWithFilter(filter.Namespace("x"))
+	require.NoError(t, err)
+	waitCache(t, "a")
+
+	// listed when ready or delivered as an event.
+	timeout := testutil.Timerch(ctx, time.Second)
+	for {
+		_, err := sx.Cache().Get("x", "a")
+		if err == nil {
+			break
+		}
+		require.True(t, IsNotFound(err), "%v", err)
+		select {
+		case <-time.After(time.Millisecond):
+		case <-timeout:
+			require.Fail(t, "relisted object not in subscription")
+		}
+	}
+
+	sy, err := controller.SubscribeWithFilter(filter.Namespace("y"))
+	require.NoError(t, err)
+	waitCache(t, "a", "b")
+
+	// narrowed: evicted without relisting.
+	calls := atomic.LoadInt32(&lists)
+	sx.Close()
+	waitCache(t, "b")
+	assert.Equal(t, calls, atomic.LoadInt32(&lists))
+
+	// refiltered; rejected by Builder.Filter().
+	require.NoError(t, sy.Refilter(filter.Namespace("z")))
+	waitCache(t)
+
+	require.NoError(t, sy.Refilter(filter.Namespace("x")))
+	waitCache(t, "a")
+
+	// interested in everything.
+	sub, err := controller.Subscribe()
+	require.NoError(t, err)
+	waitCache(t, "a", "b")
+
+	sub.Close()
+	waitCache(t, "a")
+}
@@ -2,7 +2,12 @@ package join
 
 import (
 	"context"
+	"sort"
 
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache"
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/nsname"
 	"github.com/boz/kcache/types/daemonset"
 	"github.com/boz/kcache/types/deployment"
 	"github.com/boz/kcache/types/ingress"
@@ -10,8 +15,68 @@ import (
 	"github.com/boz/kcache/types/replicaset"
 	"github.com/boz/kcache/types/replicationcontroller"
 	"github.com/boz/kcache/types/service"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// Join() returns a controller for the objects from dst that are
+// accepted by the filter that fn returns for any object in src.
+//
+// The returned controller is refiltered whenever an object in src is
+// created, updated, or deleted, so its subscribers see objects enter
+// and leave the join.  Closing the returned controller stops the join.
+func Join(ctx context.Context,
+	src kcache.Controller, dst kcache.Publisher,
+	fn func(metav1.Object) filter.Filter) (kcache.FilterController, error) {
+
+	log := logutil.FromContextOrDefault(ctx)
+
+	joined, err := dst.CloneForFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	refilter := func(objs []metav1.Object) {
+		// sorted so that unchanged joins produce equal filters.
+		sort.Slice(objs, func(i, j int) bool {
+			return nsname.ForObject(objs[i]).Less(nsname.ForObject(objs[j]))
+		})
+		filters := make([]filter.Filter, 0, len(objs))
+		for _, obj := range objs {
+			filters = append(filters, fn(obj))
+		}
+		joined.Refilter(filter.Or(filters...))
+	}
+
+	update := func(_ metav1.Object) {
+		objs, err := src.Cache().List()
+		if err != nil {
+			log.Err(err, "join: cache list")
+			return
+		}
+		refilter(objs)
+	}
+
+	handler := kcache.BuildHandler().
+		OnInitialize(refilter).
+		OnCreate(update).
+		OnUpdate(update).
+		OnDelete(update).
+		Create()
+
+	monitor, err := kcache.NewMonitor(src, handler)
+	if err != nil {
+		joined.Close()
+		return nil, log.Err(err, "join: monitor")
+	}
+
+	go func() {
+		<-joined.Done()
+		monitor.Close()
+	}()
+
+	return joined, nil
+}
+
 func ServicePods(ctx context.Context,
 	src service.Controller, dst pod.Publisher) (pod.Controller, error) {
 	return ServicePodsWith(ctx, src, dst, service.PodsFilter)
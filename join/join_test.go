@@ -0,0 +1,107 @@
+package join_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/boz/kcache"
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/join"
+	"github.com/boz/kcache/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestJoin(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	gensvc := func(name, vsn, app string) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, ResourceVersion: vsn},
+			Spec:       v1.ServiceSpec{Selector: map[string]string{"app": app}},
+		}
+	}
+	genpod := func(name, vsn, app string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "ns",
+				Name:            name,
+				ResourceVersion: vsn,
+				Labels:          map[string]string{"app": app},
+			},
+		}
+	}
+
+	build := func(list runtime.Object, eventch chan watch.Event) kcache.Controller {
+		client := testutil.MockClient(list, eventch)
+		controller, err := kcache.NewBuilder().Context(ctx).Client(client).Create()
+		require.NoError(t, err)
+		return controller
+	}
+
+	svcch := make(chan watch.Event, 10)
+	svcs := build(&v1.ServiceList{
+		ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+		Items:    []v1.Service{*gensvc("svc-a", "1", "a")},
+	}, svcch)
+	defer svcs.Close()
+
+	pods := build(&v1.PodList{
+		ListMeta: metav1.ListMeta{ResourceVersion: "2"},
+		Items:    []v1.Pod{*genpod("pod-a", "1", "a"), *genpod("pod-b", "2", "b")},
+	}, make(chan watch.Event))
+	defer pods.Close()
+
+	joined, err := join.Join(ctx, svcs, pods, func(obj metav1.Object) filter.Filter {
+		return filter.Labels(obj.(*v1.Service).Spec.Selector)
+	})
+	require.NoError(t, err)
+	defer joined.Close()
+
+	sub, err := joined.Subscribe()
+	require.NoError(t, err)
+
+	testutil.AssertReady(t, "joined", joined)
+
+	names := func() []string {
+		objs, err := joined.Cache().List()
+		require.NoError(t, err)
+		var names []string
+		for _, obj := range objs {
+			names = append(names, obj.GetName())
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	assert.Equal(t, []string{"pod-a"}, names())
+
+	svcch <- watch.Event{Type: watch.Added, Object: gensvc("svc-b", "3", "b")}
+
+	select {
+	case ev := <-sub.Events():
+		assert.Equal(t, kcache.EventTypeCreate, ev.Type())
+		assert.Equal(t, "pod-b", ev.Resource().GetName())
+	case <-testutil.Timerch(ctx, time.Second):
+		require.Fail(t, "no join event")
+	}
+	assert.Equal(t, []string{"pod-a", "pod-b"}, names())
+
+	svcch <- watch.Event{Type: watch.Deleted, Object: gensvc("svc-a", "4", "a")}
+
+	select {
+	case ev := <-sub.Events():
+		assert.Equal(t, kcache.EventTypeDelete, ev.Type())
+		assert.Equal(t, "pod-a", ev.Resource().GetName())
+	case <-testutil.Timerch(ctx, time.Second):
+		require.Fail(t, "no join event")
+	}
+	assert.Equal(t, []string{"pod-b"}, names())
+}
@@ -0,0 +1,239 @@
+// Package kcachetest provides in-memory implementations of kcache
+// interfaces for use in tests.
+package kcachetest
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/boz/kcache/client"
+	"github.com/boz/kcache/nsname"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// Client is a client.Client backed by an in-memory set of objects.
+//
+// Create(), Update(), and Delete() modify the set and are delivered to
+// watches of the client in order.  Watches started from a resource version
+// replay every change made after that version, so a controller that lists
+// and then watches observes every change exactly once.
+//
+// Objects are copied on the way in and out, and their resource versions
+// are assigned by the client.
+type Client interface {
+	client.Client
+
+	Create(obj metav1.Object) error
+	Update(obj metav1.Object) error
+	Delete(obj metav1.Object) error
+}
+
+type fakeClient struct {
+	version int
+	objects map[nsname.NSName]runtime.Object
+
+	// every change, in order.
+	history []watch.Event
+
+	// closed and replaced when history changes.
+	updatech chan struct{}
+
+	mtx sync.Mutex
+}
+
+// NewClient() returns a Client whose initial contents are objs.
+func NewClient(objs ...metav1.Object) (Client, error) {
+	c := &fakeClient{
+		objects:  make(map[nsname.NSName]runtime.Object),
+		updatech: make(chan struct{}),
+	}
+	for _, obj := range objs {
+		robj, err := c.copy(obj)
+		if err != nil {
+			return nil, err
+		}
+		id := nsname.ForObject(obj)
+		if _, ok := c.objects[id]; ok {
+			return nil, errors.Errorf("duplicate object %v", id)
+		}
+		c.version++
+		if err := setResourceVersion(robj, c.version); err != nil {
+			return nil, err
+		}
+		c.objects[id] = robj
+	}
+	return c, nil
+}
+
+func (c *fakeClient) Create(obj metav1.Object) error {
+	return c.apply(watch.Added, obj)
+}
+
+func (c *fakeClient) Update(obj metav1.Object) error {
+	return c.apply(watch.Modified, obj)
+}
+
+func (c *fakeClient) Delete(obj metav1.Object) error {
+	return c.apply(watch.Deleted, obj)
+}
+
+func (c *fakeClient) List(_ context.Context, _ metav1.ListOptions) (runtime.Object, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	ids := make([]nsname.NSName, 0, len(c.objects))
+	for id := range c.objects {
+		ids = append(ids, id)
+	}
+	nsname.Sort(ids)
+
+	list := &metav1.List{
+		ListMeta: metav1.ListMeta{ResourceVersion: strconv.Itoa(c.version)},
+		Items:    make([]runtime.RawExtension, 0, len(ids)),
+	}
+	for _, id := range ids {
+		list.Items = append(list.Items, runtime.RawExtension{Object: c.objects[id].DeepCopyObject()})
+	}
+	return list, nil
+}
+
+func (c *fakeClient) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	version := 0
+	if opts.ResourceVersion != "" {
+		var err error
+		if version, err = strconv.Atoi(opts.ResourceVersion); err != nil {
+			return nil, errors.Wrapf(err, "invalid resource version %q", opts.ResourceVersion)
+		}
+	}
+
+	c.mtx.Lock()
+	pos := sort.Search(len(c.history), func(i int) bool {
+		return resourceVersion(c.history[i]) > version
+	})
+	c.mtx.Unlock()
+
+	w := &fakeWatch{
+		ch:     make(chan watch.Event),
+		stopch: make(chan struct{}),
+	}
+	go w.run(ctx, c, pos)
+	return w, nil
+}
+
+func (c *fakeClient) apply(et watch.EventType, obj metav1.Object) error {
+	robj, err := c.copy(obj)
+	if err != nil {
+		return err
+	}
+
+	id := nsname.ForObject(obj)
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	current, found := c.objects[id]
+
+	switch {
+	case et == watch.Added && found:
+		return errors.Errorf("create %v: already exists", id)
+	case et != watch.Added && !found:
+		return errors.Errorf("%v %v: not found", et, id)
+	case et == watch.Deleted:
+		// deletions carry the last known state of the object.
+		robj = current.DeepCopyObject()
+	}
+
+	c.version++
+	if err := setResourceVersion(robj, c.version); err != nil {
+		return err
+	}
+
+	if et == watch.Deleted {
+		delete(c.objects, id)
+	} else {
+		c.objects[id] = robj
+	}
+
+	c.history = append(c.history, watch.Event{Type: et, Object: robj})
+	close(c.updatech)
+	c.updatech = make(chan struct{})
+	return nil
+}
+
+// since() returns the changes made after the first pos changes and a
+// channel that is closed when more are made.
+func (c *fakeClient) since(pos int) ([]watch.Event, <-chan struct{}) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.history[pos:], c.updatech
+}
+
+func (c *fakeClient) copy(obj metav1.Object) (runtime.Object, error) {
+	robj, ok := obj.(runtime.Object)
+	if !ok {
+		return nil, errors.Errorf("%v: not a runtime.Object (%T)", nsname.ForObject(obj), obj)
+	}
+	return robj.DeepCopyObject(), nil
+}
+
+type fakeWatch struct {
+	ch       chan watch.Event
+	stopch   chan struct{}
+	stopOnce sync.Once
+}
+
+func (w *fakeWatch) ResultChan() <-chan watch.Event {
+	return w.ch
+}
+
+func (w *fakeWatch) Stop() {
+	w.stopOnce.Do(func() { close(w.stopch) })
+}
+
+func (w *fakeWatch) run(ctx context.Context, c *fakeClient, pos int) {
+	defer close(w.ch)
+	for {
+		events, updatech := c.since(pos)
+		for _, evt := range events {
+			select {
+			case w.ch <- watch.Event{Type: evt.Type, Object: evt.Object.DeepCopyObject()}:
+				pos++
+			case <-w.stopch:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case <-updatech:
+		case <-w.stopch:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func resourceVersion(evt watch.Event) int {
+	obj, err := meta.Accessor(evt.Object)
+	if err != nil {
+		return 0
+	}
+	version, _ := strconv.Atoi(obj.GetResourceVersion())
+	return version
+}
+
+func setResourceVersion(obj runtime.Object, version int) error {
+	mobj, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	mobj.SetResourceVersion(strconv.Itoa(version))
+	return nil
+}
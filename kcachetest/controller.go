@@ -0,0 +1,71 @@
+package kcachetest
+
+import (
+	"context"
+
+	"github.com/boz/kcache"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Controller is a kcache.Controller backed by a Client.  Subscriptions
+// are the same as those of any other controller, including filtering.
+type Controller interface {
+	kcache.Controller
+
+	// Create(), Update(), and Delete() modify the objects of the
+	// controller's Client.  The resulting events are delivered to
+	// subscribers asynchronously.
+	Create(obj metav1.Object) error
+	Update(obj metav1.Object) error
+	Delete(obj metav1.Object) error
+}
+
+type controller struct {
+	kcache.Controller
+	Client
+}
+
+// NewController() returns a Controller whose initial contents are objs.
+//
+// Use NewClient() with kcache.NewBuilder() for other builder options.
+func NewController(ctx context.Context, objs ...metav1.Object) (Controller, error) {
+	client, err := NewClient(objs...)
+	if err != nil {
+		return nil, err
+	}
+
+	parent, err := kcache.NewBuilder().
+		Context(ctx).
+		Client(client).
+		Create()
+	if err != nil {
+		return nil, err
+	}
+
+	return &controller{parent, client}, nil
+}
+
+// NewSubscription() returns a subscription to a controller whose contents
+// are objs.  The controller is closed when the subscription is closed.
+func NewSubscription(ctx context.Context, objs ...metav1.Object) (kcache.Subscription, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	controller, err := NewController(ctx, objs...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	sub, err := controller.Subscribe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		<-sub.Done()
+		cancel()
+	}()
+
+	return sub, nil
+}
@@ -0,0 +1,107 @@
+package kcachetest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/boz/kcache"
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/kcachetest"
+	"github.com/boz/kcache/nsname"
+	"github.com/boz/kcache/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func genPod(ns, name string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}
+}
+
+func readEvent(t *testing.T, ctx context.Context, name string, sub kcache.Subscription) kcache.Event {
+	select {
+	case ev, ok := <-sub.Events():
+		require.True(t, ok, name)
+		return ev
+	case <-testutil.Timerch(ctx, time.Second):
+		require.Fail(t, "no event", name)
+		return nil
+	}
+}
+
+func TestController(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pa, pb := genPod("ns", "a"), genPod("ns", "b")
+
+	controller, err := kcachetest.NewController(ctx, pa, pb)
+	require.NoError(t, err)
+	defer controller.Close()
+
+	sub, err := controller.Subscribe()
+	require.NoError(t, err)
+	fsub, err := controller.SubscribeWithFilter(filter.NSName(nsname.New("ns", "a")))
+	require.NoError(t, err)
+
+	testutil.AssertReady(t, "sub", sub)
+	testutil.AssertReady(t, "fsub", fsub)
+
+	objs, err := sub.Cache().List()
+	require.NoError(t, err)
+	assert.Len(t, objs, 2)
+
+	assert.Error(t, controller.Create(pa))
+	assert.Error(t, controller.Update(genPod("ns", "x")))
+	assert.Error(t, controller.Delete(genPod("ns", "x")))
+
+	pa.Labels = map[string]string{"a": "1"}
+	require.NoError(t, controller.Create(genPod("ns", "c")))
+	require.NoError(t, controller.Update(pa))
+	require.NoError(t, controller.Delete(pb))
+
+	for _, expect := range []struct {
+		et   kcache.EventType
+		name string
+	}{
+		{kcache.EventTypeCreate, "c"},
+		{kcache.EventTypeUpdate, "a"},
+		{kcache.EventTypeDelete, "b"},
+	} {
+		ev := readEvent(t, ctx, "sub", sub)
+		assert.Equal(t, expect.et, ev.Type())
+		assert.Equal(t, expect.name, ev.Resource().GetName())
+	}
+
+	ev := readEvent(t, ctx, "fsub", fsub)
+	assert.Equal(t, kcache.EventTypeUpdate, ev.Type())
+	assert.Equal(t, pa.Labels, ev.Resource().GetLabels())
+
+	objs, err = fsub.Cache().List()
+	require.NoError(t, err)
+	assert.Len(t, objs, 1)
+}
+
+func TestNewSubscription(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := kcachetest.NewSubscription(ctx, genPod("ns", "a"), genPod("ns", "b"))
+	require.NoError(t, err)
+
+	testutil.AssertReady(t, "sub", sub)
+
+	obj, err := sub.Cache().Get("ns", "a")
+	require.NoError(t, err)
+	assert.NotNil(t, obj)
+
+	sub.Close()
+	testutil.AssertDone(t, "sub", sub)
+}
+
+func TestNewClient_duplicate(t *testing.T) {
+	_, err := kcachetest.NewClient(genPod("ns", "a"), genPod("ns", "a"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,56 @@
+package kcache
+
+import (
+	logutil "github.com/boz/go-logutil"
+)
+
+// objectLimit reports when the number of objects in a cache exceeds max.
+// A nil objectLimit reports nothing.  objectLimit is not safe for
+// concurrent use; it is owned by the controller loop.
+type objectLimit struct {
+	max      int
+	onExceed func(int)
+
+	// true while the count is above max.
+	exceeded bool
+}
+
+// copy() returns a copy of l whose callback is guarded by panics.
+func (l *objectLimit) copy(panics panicGuard) *objectLimit {
+	if l == nil {
+		return nil
+	}
+	return &objectLimit{max: l.max, onExceed: panics.countFunc("object limit callback", l.onExceed)}
+}
+
+// check() reports count if it exceeds the limit and did not at the
+// previous check.
+func (l *objectLimit) check(log logutil.Log, count int) {
+	if l == nil {
+		return
+	}
+	if count <= l.max {
+		l.exceeded = false
+		return
+	}
+	if l.exceeded {
+		return
+	}
+	l.exceeded = true
+	log.Warnf("cache holds %v objects: limit %v exceeded", count, l.max)
+	if l.onExceed != nil {
+		l.onExceed(count)
+	}
+}
+
+// cacheCount() returns the number of objects in reader.
+func cacheCount(reader CacheReader) int {
+	if c, ok := reader.(cache); ok {
+		return c.count()
+	}
+	list, err := reader.List()
+	if err != nil {
+		return 0
+	}
+	return len(list)
+}
@@ -0,0 +1,127 @@
+package kcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/boz/kcache/client/mocks"
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestController_ObjectLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventch := make(chan watch.Event)
+	list := &v1.PodList{
+		ListMeta: metav1.ListMeta{ResourceVersion: "2"},
+		Items:    []v1.Pod{*testGenPod("a", "a", "1"), *testGenPod("b", "b", "2")},
+	}
+
+	exceeded := make(chan int, 10)
+
+	controller := testNewController(t, testContext(ctx), testEvents(eventch), testList(list),
+		testBuild(func(b Builder) { b.ObjectLimit(2, func(count int) { exceeded <- count }) }))
+	defer controller.Close()
+	testutil.AssertReady(t, "controller", controller)
+
+	assert.Equal(t, 2, controller.ObjectCount())
+
+	clone, err := controller.CloneWithFilter(filter.Namespace("a"))
+	require.NoError(t, err)
+	testutil.AssertReady(t, "clone", clone)
+
+	send := func(etype watch.EventType, ns, vsn string) {
+		select {
+		case eventch <- watch.Event{Type: etype, Object: testGenPod(ns, ns, vsn)}:
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "event not received")
+		}
+	}
+
+	assertExceeded := func(count int) {
+		select {
+		case n := <-exceeded:
+			assert.Equal(t, count, n)
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "limit not reported", "%v objects", count)
+		}
+	}
+
+	assertNotExceeded := func() {
+		select {
+		case n := <-exceeded:
+			assert.Fail(t, "limit reported", "%v objects", n)
+		case <-testutil.AsyncWaitch(ctx):
+		}
+	}
+
+	assertNotExceeded()
+
+	send(watch.Added, "c", "3")
+	assertExceeded(3)
+	assert.Equal(t, 3, controller.ObjectCount())
+
+	// reported once until the count falls back to the limit.
+	send(watch.Added, "d", "4")
+	assertNotExceeded()
+
+	send(watch.Deleted, "c", "5")
+	send(watch.Deleted, "d", "6")
+	send(watch.Added, "e", "7")
+	assertExceeded(3)
+
+	clone.Close()
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewBuilder().Client(&mocks.Client{}).ObjectLimit(-1, nil).Create()
+		assert.Error(t, err)
+	})
+}
+
+func TestController_ObjectCount_clone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	list := &v1.PodList{
+		ListMeta: metav1.ListMeta{ResourceVersion: "3"},
+		Items: []v1.Pod{
+			*testGenPod("a", "x", "1"),
+			*testGenPod("a", "y", "2"),
+			*testGenPod("b", "z", "3"),
+		},
+	}
+
+	controller := testNewController(t, testContext(ctx), testList(list))
+	defer controller.Close()
+	testutil.AssertReady(t, "controller", controller)
+
+	assert.Equal(t, 3, controller.ObjectCount())
+
+	clone, err := controller.Clone()
+	require.NoError(t, err)
+	testutil.AssertReady(t, "clone", clone)
+	assert.Equal(t, 3, clone.ObjectCount())
+
+	fclone, err := controller.CloneWithFilter(filter.Namespace("a"))
+	require.NoError(t, err)
+	testutil.AssertReady(t, "fclone", fclone)
+	assert.Equal(t, 2, fclone.ObjectCount())
+
+	require.NoError(t, fclone.Refilter(filter.Namespace("b")))
+	timeout := testutil.Timerch(ctx, time.Second)
+	for fclone.ObjectCount() != 1 {
+		select {
+		case <-time.After(time.Millisecond):
+		case <-timeout:
+			require.Fail(t, "refilter not applied", "%v objects", fclone.ObjectCount())
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -12,6 +13,7 @@ import (
 	lifecycle "github.com/boz/go-lifecycle"
 	logutil "github.com/boz/go-logutil"
 	"github.com/boz/kcache/client"
+	"github.com/boz/kcache/clock"
 	"github.com/pkg/errors"
 )
 
@@ -26,6 +28,9 @@ const (
 
 type lister interface {
 	Result() <-chan listResult
+
+	// relist() starts a list immediately unless one is in progress.
+	relist()
 	Done() <-chan struct{}
 	Error() error
 }
@@ -38,20 +43,29 @@ type listResult struct {
 type _lister struct {
 	client   client.ListClient
 	period   time.Duration
+	pageSize int
+	clock    clock.Clock
 	resultch chan listResult
+	relistch chan struct{}
 
 	log logutil.Log
 	lc  lifecycle.Lifecycle
 	ctx context.Context
 }
 
-func newLister(ctx context.Context, log logutil.Log, stopch <-chan struct{}, period time.Duration, client client.ListClient) *_lister {
+// newLister() returns a lister that lists with client every period, as
+// timed by clock.  If pageSize is positive, lists are requested in chunks
+// of at most pageSize objects.
+func newLister(ctx context.Context, log logutil.Log, stopch <-chan struct{}, period time.Duration, pageSize int, clock clock.Clock, client client.ListClient) *_lister {
 	log = log.WithComponent("lister")
 
 	l := &_lister{
 		client:   client,
 		period:   period,
+		pageSize: pageSize,
+		clock:    clock,
 		resultch: make(chan listResult),
+		relistch: make(chan struct{}, 1),
 		log:      log,
 		lc:       lifecycle.New(),
 		ctx:      ctx,
@@ -69,6 +83,13 @@ func (l *_lister) Result() <-chan listResult {
 	return l.resultch
 }
 
+func (l *_lister) relist() {
+	select {
+	case l.relistch <- struct{}{}:
+	default:
+	}
+}
+
 func (l *_lister) Done() <-chan struct{} {
 	return l.lc.Done()
 }
@@ -85,7 +106,7 @@ func (l *_lister) run() {
 
 	runch, donech := l.list()
 
-	ticker := newTicker(l.period, defaultRefreshFuzz)
+	ticker := newTicker(l.period, defaultRefreshFuzz, l.clock)
 	var tickch <-chan int
 
 mainloop:
@@ -95,6 +116,16 @@ mainloop:
 			runch, donech = l.list()
 			tickch = nil
 
+		case <-l.relistch:
+			if tickch == nil {
+				// list in progress or result not yet delivered.
+				continue
+			}
+			l.log.Debugf("relist requested")
+			ticker.Reset()
+			runch, donech = l.list()
+			tickch = nil
+
 		case result = <-runch:
 			resultch = l.resultch
 			runch = nil
@@ -137,7 +168,14 @@ func (l *_lister) list() (<-chan listResult, <-chan struct{}) {
 }
 
 func (l *_lister) executeList(ctx context.Context) listResult {
-	list, err := l.client.List(ctx, v1.ListOptions{})
+	var list runtime.Object
+	var err error
+
+	if l.pageSize > 0 {
+		list, err = l.listPages(ctx)
+	} else {
+		list, err = l.client.List(ctx, v1.ListOptions{})
+	}
 
 	if err != nil {
 		if err != context.Canceled {
@@ -153,3 +191,58 @@ func (l *_lister) executeList(ctx context.Context) listResult {
 
 	return listResult{list, nil}
 }
+
+// listPages() lists in chunks of l.pageSize objects and returns the first
+// chunk containing the items of every chunk.  The list is restarted if
+// the continue token expires before it is complete.
+func (l *_lister) listPages(ctx context.Context) (runtime.Object, error) {
+restart:
+	for {
+		var first runtime.Object
+		var items []runtime.Object
+
+		opts := v1.ListOptions{Limit: int64(l.pageSize)}
+
+		for {
+			page, err := l.client.List(ctx, opts)
+			if (apierrors.IsResourceExpired(err) || apierrors.IsGone(err)) && opts.Continue != "" {
+				l.log.Warnf("list continue token expired after %v items: restarting", len(items))
+				continue restart
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			pitems, err := meta.ExtractList(page)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, pitems...)
+
+			pmeta, err := meta.ListAccessor(page)
+			if err != nil {
+				return nil, err
+			}
+
+			if first == nil {
+				first = page
+			}
+
+			if opts.Continue = pmeta.GetContinue(); opts.Continue == "" {
+				break
+			}
+		}
+
+		if err := meta.SetList(first, items); err != nil {
+			return nil, err
+		}
+
+		fmeta, err := meta.ListAccessor(first)
+		if err != nil {
+			return nil, err
+		}
+		fmeta.SetContinue("")
+
+		return first, nil
+	}
+}
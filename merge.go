@@ -0,0 +1,163 @@
+package kcache
+
+import (
+	"context"
+	"sync"
+)
+
+// MergedSubscription delivers the events of several subscriptions
+// through a single channel.  See Merge().
+type MergedSubscription interface {
+	// Ready() is closed once every merged subscription is ready.
+	Ready() <-chan struct{}
+
+	// Events() returns the events of every merged subscription.  Events
+	// from the same subscription are delivered in order; Source()
+	// returns the subscription that delivered an event.
+	//
+	// Events() is closed once every merged subscription has shut down.
+	Events() <-chan Event
+
+	// EventsContext() returns Events() and closes the merged
+	// subscription when ctx is done.
+	EventsContext(ctx context.Context) <-chan Event
+
+	// Close() closes every merged subscription.  Events that have not
+	// been forwarded to Events() are discarded.
+	Close()
+
+	// Done() is closed once Events() is closed and every merged
+	// subscription is done.
+	Done() <-chan struct{}
+
+	// Error() returns the first error reported by a merged subscription.
+	Error() error
+}
+
+// mergedEvent is an event delivered by a MergedSubscription.
+type mergedEvent struct {
+	Event
+	source Subscription
+}
+
+// Source() returns the subscription that delivered evt to a
+// MergedSubscription, or nil if evt was not delivered by one.
+func Source(evt Event) Subscription {
+	if evt, ok := evt.(mergedEvent); ok {
+		return evt.source
+	}
+	return nil
+}
+
+type mergedSubscription struct {
+	subs []Subscription
+
+	outch   chan Event
+	readych chan struct{}
+	stopch  chan struct{}
+	donech  chan struct{}
+
+	closeOnce sync.Once
+}
+
+// Merge() returns a subscription that delivers the events of subs.
+//
+// The merged subscription closes every input when any of them shuts
+// down, so that consumers do not silently stop receiving events for a
+// resource type.
+func Merge(subs ...Subscription) MergedSubscription {
+	s := &mergedSubscription{
+		subs:    subs,
+		outch:   make(chan Event, EventBufsiz),
+		readych: make(chan struct{}),
+		stopch:  make(chan struct{}),
+		donech:  make(chan struct{}),
+	}
+
+	var wg sync.WaitGroup
+	for _, sub := range subs {
+		wg.Add(1)
+		go func(sub Subscription) {
+			defer wg.Done()
+			s.forward(sub)
+		}(sub)
+	}
+
+	go s.waitReady()
+
+	go func() {
+		wg.Wait()
+		close(s.outch)
+		for _, sub := range subs {
+			<-sub.Done()
+		}
+		close(s.donech)
+	}()
+
+	return s
+}
+
+func (s *mergedSubscription) Ready() <-chan struct{} {
+	return s.readych
+}
+
+func (s *mergedSubscription) Events() <-chan Event {
+	return s.outch
+}
+
+func (s *mergedSubscription) EventsContext(ctx context.Context) <-chan Event {
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Close()
+		case <-s.Done():
+		}
+	}()
+	return s.Events()
+}
+
+func (s *mergedSubscription) Close() {
+	s.closeOnce.Do(func() {
+		close(s.stopch)
+		for _, sub := range s.subs {
+			sub.Close()
+		}
+	})
+}
+
+func (s *mergedSubscription) Done() <-chan struct{} {
+	return s.donech
+}
+
+func (s *mergedSubscription) Error() error {
+	for _, sub := range s.subs {
+		if err := sub.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *mergedSubscription) forward(sub Subscription) {
+	// an input shutting down shuts down the merge.
+	defer s.Close()
+
+	for evt := range sub.Events() {
+		select {
+		case s.outch <- mergedEvent{evt, sub}:
+		case <-s.stopch:
+			return
+		}
+	}
+}
+
+func (s *mergedSubscription) waitReady() {
+	for _, sub := range s.subs {
+		select {
+		case <-sub.Ready():
+		case <-s.stopch:
+			return
+		}
+	}
+	close(s.readych)
+}
@@ -0,0 +1,113 @@
+package kcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	log := logutil.Default()
+
+	newSub := func(t *testing.T) (subscription, chan struct{}) {
+		sub, _, readych := testNewSubscription(t, log, filter.Null())
+		return sub, readych
+	}
+
+	t.Run("events", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		a, areadych := newSub(t)
+		b, breadych := newSub(t)
+
+		merged := Merge(a, b)
+		defer merged.Close()
+
+		close(areadych)
+		testutil.AssertNotReady(t, "merged", merged)
+		close(breadych)
+		testutil.AssertReady(t, "merged", merged)
+
+		a.send(testGenEvent(EventTypeCreate, "ns", "a", "1"))
+		b.send(testGenEvent(EventTypeCreate, "ns", "b", "1"))
+		a.send(replayedEvent{testGenEvent(EventTypeUpdate, "ns", "a", "2")})
+
+		sources := make(map[string][]Subscription)
+		var replayed []string
+		for i := 0; i < 3; i++ {
+			select {
+			case evt := <-merged.Events():
+				name := evt.Resource().GetName()
+				sources[name] = append(sources[name], Source(evt))
+				if Replayed(evt) {
+					replayed = append(replayed, evt.Resource().GetResourceVersion())
+				}
+			case <-testutil.Timerch(ctx, time.Second):
+				require.Fail(t, "no event")
+			}
+		}
+		assert.Equal(t, map[string][]Subscription{"a": {a, a}, "b": {b}}, sources)
+		assert.Equal(t, []string{"2"}, replayed)
+		assert.Nil(t, Source(testGenEvent(EventTypeCreate, "ns", "a", "1")))
+	})
+
+	t.Run("close", func(t *testing.T) {
+		a, _ := newSub(t)
+		b, _ := newSub(t)
+
+		merged := Merge(a, b)
+		merged.Close()
+
+		testutil.AssertDone(t, "a", a)
+		testutil.AssertDone(t, "b", b)
+		testutil.AssertDone(t, "merged", merged)
+		_, ok := <-merged.Events()
+		assert.False(t, ok)
+	})
+
+	t.Run("input closed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		a, areadych := newSub(t)
+		b, breadych := newSub(t)
+		close(areadych)
+		close(breadych)
+
+		merged := Merge(a, b)
+
+		a.send(testGenEvent(EventTypeCreate, "ns", "a", "1"))
+		a.Close()
+
+		// buffered events are delivered.
+		select {
+		case evt, ok := <-merged.Events():
+			require.True(t, ok)
+			assert.Equal(t, "a", evt.Resource().GetName())
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "no event")
+		}
+
+		testutil.AssertDone(t, "b", b)
+		testutil.AssertDone(t, "merged", merged)
+	})
+
+	t.Run("context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		a, _ := newSub(t)
+		merged := Merge(a)
+		merged.EventsContext(ctx)
+
+		cancel()
+		testutil.AssertDone(t, "merged", merged)
+		testutil.AssertDone(t, "a", a)
+	})
+}
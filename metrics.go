@@ -0,0 +1,37 @@
+package kcache
+
+import "time"
+
+// Metrics receives instrumentation callbacks from a controller and
+// its subscriptions.  Implementations must be safe for concurrent use
+// and must not block.
+//
+// kcache does not depend on any metrics library; adapt Metrics to
+// prometheus or similar and install it with Builder.Metrics().  Queue
+// depths are not reported through Metrics: an adapter that exports them
+// polls Controller.Stats().QueueDepths.
+type Metrics interface {
+	// EventPublished() is called for each event the controller publishes.
+	EventPublished(EventType)
+
+	// SubscriberAdded() and SubscriberRemoved() are called when a
+	// subscription is created and when it is done.
+	SubscriberAdded()
+	SubscriberRemoved()
+
+	// EventDropped() is called when a subscription discards an event
+	// because its buffer is full.
+	EventDropped()
+
+	// WatchReconnected() is called when a watch is re-established,
+	// with the time elapsed since the previous watch ended.
+	WatchReconnected(time.Duration)
+}
+
+type nullMetrics struct{}
+
+func (nullMetrics) EventPublished(EventType)       {}
+func (nullMetrics) SubscriberAdded()               {}
+func (nullMetrics) SubscriberRemoved()             {}
+func (nullMetrics) EventDropped()                  {}
+func (nullMetrics) WatchReconnected(time.Duration) {}
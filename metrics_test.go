@@ -0,0 +1,130 @@
+package kcache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boz/kcache/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+type testMetrics struct {
+	published   map[EventType]int
+	subscribers int
+	dropped     int
+	mtx         sync.Mutex
+
+	// signalled after each change.
+	changed chan struct{}
+}
+
+func newTestMetrics() *testMetrics {
+	return &testMetrics{
+		published: make(map[EventType]int),
+		changed:   make(chan struct{}, 1),
+	}
+}
+
+func (m *testMetrics) EventPublished(et EventType) {
+	defer m.signal()
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.published[et]++
+}
+
+func (m *testMetrics) SubscriberAdded() {
+	defer m.signal()
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.subscribers++
+}
+
+func (m *testMetrics) SubscriberRemoved() {
+	defer m.signal()
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.subscribers--
+}
+
+func (m *testMetrics) EventDropped() {
+	defer m.signal()
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.dropped++
+}
+
+func (m *testMetrics) signal() {
+	select {
+	case m.changed <- struct{}{}:
+	default:
+	}
+}
+
+// waitFor() waits until cond is true of m's snapshot().
+func (m *testMetrics) waitFor(t *testing.T, name string, cond func(map[EventType]int, int, int) bool) {
+	timeout := testutil.Timerch(context.Background(), time.Second)
+	for {
+		published, subscribers, dropped := m.snapshot()
+		if cond(published, subscribers, dropped) {
+			return
+		}
+		select {
+		case <-m.changed:
+		case <-timeout:
+			require.Fail(t, "timed out", "%v: published: %v subscribers: %v dropped: %v",
+				name, published, subscribers, dropped)
+		}
+	}
+}
+
+func (m *testMetrics) WatchReconnected(time.Duration) {}
+
+func (m *testMetrics) snapshot() (map[EventType]int, int, int) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	published := make(map[EventType]int)
+	for k, v := range m.published {
+		published[k] = v
+	}
+	return published, m.subscribers, m.dropped
+}
+
+func TestController_metrics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventch := make(chan watch.Event, 10)
+	metrics := newTestMetrics()
+
+	controller := testNewController(t, testContext(ctx), testEvents(eventch),
+		testBuild(func(b Builder) { b.Metrics(metrics) }))
+	defer controller.Close()
+
+	sub, err := controller.SubscribeWithBuffer(1, OverflowDropNewest)
+	require.NoError(t, err)
+	testutil.AssertReady(t, "sub", sub)
+
+	_, subscribers, _ := metrics.snapshot()
+	assert.Equal(t, 1, subscribers)
+
+	eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", "a", "2")}
+	eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", "b", "3")}
+	eventch <- watch.Event{Type: watch.Deleted, Object: testGenPod("ns", "b", "4")}
+
+	metrics.waitFor(t, "recorded", func(published map[EventType]int, _ int, dropped int) bool {
+		return published[EventTypeDelete] == 1 && dropped > 0
+	})
+	published, _, _ := metrics.snapshot()
+	assert.Equal(t, 2, published[EventTypeCreate])
+
+	sub.Close()
+	testutil.AssertDone(t, "sub", sub)
+
+	metrics.waitFor(t, "removed", func(_ map[EventType]int, subscribers int, _ int) bool {
+		return subscribers == 0
+	})
+}
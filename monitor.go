@@ -92,7 +92,7 @@ func NewMonitor(publisher Publisher, handler Handler) (Monitor, error) {
 	if err != nil {
 		return nil, err
 	}
-	m := &monitor{sub, handler, lifecycle.New()}
+	m := &monitor{sub, guardOf(publisher).monitorHandler(handler), lifecycle.New()}
 	go m.run()
 	return m, nil
 }
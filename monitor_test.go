@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/clock"
 	"github.com/boz/kcache/filter"
 	"github.com/boz/kcache/testutil"
 	"github.com/stretchr/testify/assert"
@@ -18,7 +19,7 @@ func TestMonitor(t *testing.T) {
 
 	log := logutil.Default()
 	parent, cache, readych := testNewSubscription(t, log, filter.Null())
-	publisher := newPublisher(log, parent)
+	publisher := newPublisher(log, parent, clock.Real(), nullMetrics{})
 	defer parent.Close()
 
 	icalled := make(chan bool)
@@ -91,7 +92,7 @@ func TestMonitor_lifecycle_close_early(t *testing.T) {
 
 	log := logutil.Default()
 	parent, _, readych := testNewSubscription(t, log, filter.Null())
-	publisher := newPublisher(log, parent)
+	publisher := newPublisher(log, parent, clock.Real(), nullMetrics{})
 
 	calledch := make(chan bool)
 
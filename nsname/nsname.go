@@ -1,10 +1,13 @@
 package nsname
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
+	pkgerrors "github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -15,10 +18,20 @@ type NSName struct {
 	Name      string
 }
 
+// Parse() returns the NSName for a "namespace/name" string.  A string
+// without a "/" is the name of a cluster-scoped object.
+//
+// Errors returned by Parse() have ErrInvalidID as their cause.
 func Parse(id string) (NSName, error) {
 	parts := strings.Split(id, "/")
-	if len(parts) != 2 {
-		return NSName{}, ErrInvalidID
+	switch {
+	case len(parts) > 2:
+		return NSName{}, pkgerrors.Wrapf(ErrInvalidID, "%q: too many '/'", id)
+	case len(parts) == 1:
+		parts = []string{"", parts[0]}
+	}
+	if parts[1] == "" {
+		return NSName{}, pkgerrors.Wrapf(ErrInvalidID, "%q: empty name", id)
 	}
 	return New(parts[0], parts[1]), nil
 }
@@ -31,6 +44,52 @@ func ForObject(obj metav1.Object) NSName {
 	return New(obj.GetNamespace(), obj.GetName())
 }
 
+// String() returns "namespace/name", or "name" if there is no namespace.
 func (obj NSName) String() string {
+	if obj.Namespace == "" {
+		return obj.Name
+	}
 	return fmt.Sprintf("%v/%v", obj.Namespace, obj.Name)
 }
+
+// Less() orders by namespace and then by name.
+func (obj NSName) Less(other NSName) bool {
+	if obj.Namespace != other.Namespace {
+		return obj.Namespace < other.Namespace
+	}
+	return obj.Name < other.Name
+}
+
+// Sort() sorts ids by namespace and then by name.
+func Sort(ids []NSName) {
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].Less(ids[j])
+	})
+}
+
+func (obj NSName) MarshalText() ([]byte, error) {
+	return []byte(obj.String()), nil
+}
+
+// UnmarshalText() parses text with Parse().
+func (obj *NSName) UnmarshalText(text []byte) error {
+	id, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*obj = id
+	return nil
+}
+
+func (obj NSName) MarshalJSON() ([]byte, error) {
+	return json.Marshal(obj.String())
+}
+
+// UnmarshalJSON() parses a JSON string with Parse().
+func (obj *NSName) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	return obj.UnmarshalText([]byte(text))
+}
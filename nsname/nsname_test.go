@@ -1,9 +1,11 @@
 package nsname_test
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/boz/kcache/nsname"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 )
 
@@ -17,38 +19,98 @@ func TestParse(t *testing.T) {
 		require.Equal(t, "foo/bar", id.String())
 	}
 
-	{
-		id, err := nsname.Parse("foo/")
-		require.NoError(t, err)
-		require.Equal(t, "foo", id.Namespace)
-		require.Equal(t, "", id.Name)
-		require.Equal(t, "foo/", id.String())
-	}
-
 	{
 		id, err := nsname.Parse("/bar")
 		require.NoError(t, err)
 		require.Equal(t, "", id.Namespace)
 		require.Equal(t, "bar", id.Name)
-		require.Equal(t, "/bar", id.String())
 	}
 
 	{
-		id, err := nsname.Parse("/")
+		id, err := nsname.Parse("bar")
 		require.NoError(t, err)
 		require.Equal(t, "", id.Namespace)
-		require.Equal(t, "", id.Name)
-		require.Equal(t, "/", id.String())
+		require.Equal(t, "bar", id.Name)
 	}
 
-	{
-		_, err := nsname.Parse("/bar/")
-		require.Equal(t, nsname.ErrInvalidID, err)
+	for _, value := range []string{"", "/", "foo/", "/bar/", "foo/bar/baz"} {
+		_, err := nsname.Parse(value)
+		require.Error(t, err, value)
+		require.Equal(t, nsname.ErrInvalidID, errors.Cause(err), value)
 	}
 
-	{
-		_, err := nsname.Parse("")
-		require.Equal(t, nsname.ErrInvalidID, err)
+}
+
+func TestParse_roundTrip(t *testing.T) {
+	for _, id := range []nsname.NSName{
+		nsname.New("foo", "bar"),
+		nsname.New("", "bar"),
+	} {
+		parsed, err := nsname.Parse(id.String())
+		require.NoError(t, err, id.String())
+		require.Equal(t, id, parsed)
+	}
+}
+
+func TestString(t *testing.T) {
+	require.Equal(t, "foo/bar", nsname.New("foo", "bar").String())
+	require.Equal(t, "bar", nsname.New("", "bar").String())
+}
+
+func TestSort(t *testing.T) {
+	ids := []nsname.NSName{
+		nsname.New("b", "a"),
+		nsname.New("a", "b"),
+		nsname.New("", "z"),
+		nsname.New("a", "a"),
+	}
+	nsname.Sort(ids)
+	require.Equal(t, []nsname.NSName{
+		nsname.New("", "z"),
+		nsname.New("a", "a"),
+		nsname.New("a", "b"),
+		nsname.New("b", "a"),
+	}, ids)
+}
+
+func TestJSON(t *testing.T) {
+	type payload struct {
+		ID  nsname.NSName   `json:"id"`
+		IDs []nsname.NSName `json:"ids"`
+	}
+
+	obj := payload{
+		ID:  nsname.New("foo", "bar"),
+		IDs: []nsname.NSName{nsname.New("", "baz"), nsname.New("a", "b")},
+	}
+
+	data, err := json.Marshal(obj)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id":"foo/bar","ids":["baz","a/b"]}`, string(data))
+
+	var decoded payload
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, obj, decoded)
+
+	for _, data := range []string{`{"id":"a/b/c"}`, `{"id":""}`, `{"id":1}`} {
+		require.Error(t, json.Unmarshal([]byte(data), &decoded), data)
+	}
+}
+
+func TestText(t *testing.T) {
+	for _, id := range []nsname.NSName{
+		nsname.New("foo", "bar"),
+		nsname.New("", "bar"),
+	} {
+		text, err := id.MarshalText()
+		require.NoError(t, err)
+		require.Equal(t, id.String(), string(text))
+
+		var parsed nsname.NSName
+		require.NoError(t, parsed.UnmarshalText(text))
+		require.Equal(t, id, parsed)
 	}
 
+	var parsed nsname.NSName
+	require.Error(t, parsed.UnmarshalText([]byte("foo/")))
 }
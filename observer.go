@@ -0,0 +1,56 @@
+package kcache
+
+import (
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// eventObserver is a callback registered with Publisher.OnEvent().
+type eventObserver struct {
+	fn func(Event)
+
+	// set once the observer has been deregistered.
+	stopped int32
+}
+
+func (o *eventObserver) notify(evt Event) {
+	if atomic.LoadInt32(&o.stopped) == 0 {
+		o.fn(evt)
+	}
+}
+
+func (s *publisher) OnEvent(fn func(Event)) (func(), error) {
+	if fn == nil {
+		return nil, errors.New("nil callback")
+	}
+
+	o := &eventObserver{fn: s.panics.eventFunc("OnEvent() callback", fn)}
+
+	select {
+	case <-s.lc.ShuttingDown():
+		return nil, errors.WithStack(ErrNotRunning)
+	case s.observech <- o:
+	}
+
+	return func() {
+		if !atomic.CompareAndSwapInt32(&o.stopped, 0, 1) {
+			return
+		}
+		// may be called from the callback itself; don't block the loop.
+		go func() {
+			select {
+			case s.unobservech <- o:
+			case <-s.lc.ShuttingDown():
+			}
+		}()
+	}, nil
+}
+
+func (c *filterController) OnEvent(fn func(Event)) (func(), error) {
+	return c.parent.OnEvent(fn)
+}
+
+func (c *controller) OnEvent(fn func(Event)) (func(), error) {
+	return c.publisher.OnEvent(fn)
+}
@@ -0,0 +1,84 @@
+package kcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/boz/kcache/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestController_OnEvent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventch := make(chan watch.Event, 3)
+
+	controller := testNewController(t, testContext(ctx), testEvents(eventch))
+	testutil.AssertReady(t, "controller", controller)
+
+	_, err := controller.OnEvent(nil)
+	assert.Error(t, err)
+
+	// unbuffered: fn runs before the event reaches subscriptions.
+	observedch := make(chan string)
+	stop, err := controller.OnEvent(func(evt Event) {
+		observedch <- evt.Resource().GetName()
+	})
+	require.NoError(t, err)
+
+	// deregisters itself after the first event.
+	oncech := make(chan string, 3)
+	var once func()
+	once, err = controller.OnEvent(func(evt Event) {
+		oncech <- evt.Resource().GetName()
+		once()
+	})
+	require.NoError(t, err)
+
+	sub, err := controller.Subscribe()
+	require.NoError(t, err)
+
+	read := func(t *testing.T, ch <-chan string) string {
+		select {
+		case name := <-ch:
+			return name
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "no callback")
+			return ""
+		}
+	}
+
+	eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", "a", "2")}
+	assert.Equal(t, "a", read(t, observedch))
+
+	eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", "b", "3")}
+	assert.Equal(t, "b", read(t, observedch))
+
+	stop()
+	stop()
+
+	eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", "c", "4")}
+
+	var names []string
+	for len(names) < 3 {
+		select {
+		case evt := <-sub.Events():
+			names = append(names, evt.Resource().GetName())
+		case name := <-observedch:
+			require.Fail(t, "callback after deregistration", name)
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "no event", "%v/3 events", len(names))
+		}
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, names)
+
+	assert.Equal(t, "a", read(t, oncech))
+	assert.Empty(t, oncech)
+
+	controller.Close()
+	testutil.AssertDone(t, "controller", controller)
+}
@@ -1,8 +1,14 @@
 package kcache
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
 	lifecycle "github.com/boz/go-lifecycle"
 	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/clock"
 	"github.com/boz/kcache/filter"
 	"github.com/pkg/errors"
 )
@@ -10,25 +16,84 @@ import (
 type FilterController interface {
 	Controller
 	Refilter(filter.Filter) error
+
+	// RefilterWithRate() is like Refilter() but paces the resulting
+	// events.  See FilterSubscription.RefilterWithRate().
+	RefilterWithRate(f filter.Filter, limit float64) error
+}
+
+type subscribeRequest struct {
+	size     int
+	policy   OverflowPolicy
+	replay   bool
+	interest filter.Filter
+
+	// true if the subscription's events are read by another subscription
+	// or publisher.
+	relay bool
+
+	// reports the filter of the subscription that wraps the one created.
+	filter *filterRef
+
+	resultch chan<- Subscription
 }
 
 type publisher struct {
 	parent Subscription
 
-	subscribech   chan chan<- Subscription
+	subscribech   chan subscribeRequest
 	unsubscribech chan subscription
+	snapshotch    chan *snapshotMarker
+	subscribersch chan chan<- []subscriberInfo
 	subscriptions map[subscription]struct{}
+	filters       map[subscription]*filterRef
+
+	observech   chan *eventObserver
+	unobservech chan *eventObserver
+	observers   map[*eventObserver]struct{}
+
+	// recently published events.
+	replay *eventRing
+
+	// set once a flush marker has been distributed; later events are not.
+	draining bool
+
+	// the filters of subscribers; nil unless the cache is filtered.
+	interest *interestTracker
+
+	clock   clock.Clock
+	panics  panicGuard
+	metrics Metrics
 
 	lc  lifecycle.Lifecycle
 	log logutil.Log
 }
 
-func newPublisher(log logutil.Log, parent Subscription) Controller {
+func newPublisher(log logutil.Log, parent Subscription, clock clock.Clock, metrics Metrics) Controller {
+	return newRootPublisher(log, parent, 0, nil, clock, panicGuard{log: log}, metrics)
+}
+
+// newRootPublisher() returns a publisher that retains the last
+// replaySize events for SubscribeWithReplay() and records the filters
+// of its subscribers in interest.  Panics in OnEvent() callbacks of the
+// publisher and its clones are recovered by panics.
+func newRootPublisher(log logutil.Log, parent Subscription, replaySize int, interest *interestTracker, clock clock.Clock, panics panicGuard, metrics Metrics) *publisher {
 	s := &publisher{
 		parent:        parent,
-		subscribech:   make(chan chan<- Subscription),
+		subscribech:   make(chan subscribeRequest),
 		unsubscribech: make(chan subscription),
+		snapshotch:    make(chan *snapshotMarker),
+		subscribersch: make(chan chan<- []subscriberInfo),
 		subscriptions: make(map[subscription]struct{}),
+		filters:       make(map[subscription]*filterRef),
+		observech:     make(chan *eventObserver),
+		unobservech:   make(chan *eventObserver),
+		observers:     make(map[*eventObserver]struct{}),
+		replay:        newEventRing(replaySize),
+		interest:      interest,
+		clock:         clock,
+		panics:        panics,
+		metrics:       metrics,
 		lc:            lifecycle.New(),
 		log:           log.WithComponent("publisher"),
 	}
@@ -42,10 +107,38 @@ func (s *publisher) Ready() <-chan struct{} {
 	return s.parent.Ready()
 }
 
+func (s *publisher) WaitForSync(ctx context.Context) bool {
+	return waitForSync(ctx, s.Ready(), s.Done())
+}
+
+func (s *publisher) Stats() Stats {
+	return statsOf(s.metrics)
+}
+
+func (s *publisher) Export(w io.Writer) error {
+	return Export(s, w)
+}
+
+func (s *publisher) ObjectCount() int {
+	return cacheCount(s.parent.Cache())
+}
+
+func (s *publisher) timeSource() clock.Clock {
+	return s.clock
+}
+
+func (s *publisher) guard() panicGuard {
+	return s.panics
+}
+
 func (s *publisher) Cache() CacheReader {
 	return s.parent.Cache()
 }
 
+func (s *publisher) AddIndex(name string, fn IndexFunc) error {
+	return s.parent.AddIndex(name, fn)
+}
+
 func (s *publisher) Close() {
 	s.parent.Close()
 }
@@ -59,53 +152,114 @@ func (s *publisher) Error() error {
 }
 
 func (s *publisher) Subscribe() (Subscription, error) {
+	return s.SubscribeWithBuffer(EventBufsiz, OverflowDropNewest)
+}
+
+func (s *publisher) SubscribeWithBuffer(size int, policy OverflowPolicy) (Subscription, error) {
+	if size < 1 {
+		return nil, errors.Errorf("invalid buffer size: %v", size)
+	}
+	return s.subscribe(subscribeRequest{size: size, policy: policy})
+}
+
+func (s *publisher) SubscribeWithReplay() (Subscription, error) {
+	return s.subscribe(subscribeRequest{size: EventBufsiz, policy: OverflowDropNewest, replay: true})
+}
+
+// relaySubscribe() returns a subscription whose events are read by
+// another subscription or publisher.
+func (s *publisher) relaySubscribe() (Subscription, error) {
+	return s.subscribe(subscribeRequest{size: EventBufsiz, policy: OverflowDropNewest, relay: true})
+}
+
+func (s *publisher) subscribe(req subscribeRequest) (Subscription, error) {
 	resultch := make(chan Subscription, 1)
+	req.resultch = resultch
 	select {
 	case <-s.lc.ShuttingDown():
 		return nil, errors.WithStack(ErrNotRunning)
-	case s.subscribech <- resultch:
+	case s.subscribech <- req:
 		return <-resultch, nil
 	}
 }
 
 func (s *publisher) SubscribeWithFilter(f filter.Filter) (FilterSubscription, error) {
-	sub, err := s.Subscribe()
+	return s.subscribeFilter(f, false, false)
+}
+
+func (s *publisher) SubscribeForFilter() (FilterSubscription, error) {
+	return s.subscribeFilter(filter.All(), true, false)
+}
+
+// subscribeFilter() returns a filtered subscription.  relay is true if
+// its events are read by a clone.
+func (s *publisher) subscribeFilter(f filter.Filter, deferReady bool, relay bool) (FilterSubscription, error) {
+	ref := &filterRef{}
+	sub, err := s.subscribe(subscribeRequest{size: EventBufsiz, policy: OverflowDropNewest, interest: f, filter: ref, relay: true})
 	if err != nil {
 		return nil, err
 	}
-	return newFilterSubscription(s.log, sub, f, false), nil
+	fsub := newFilterSubscription(s.log, sub, f, deferReady, relay, s.clock, s.panics, s.metrics)
+	ref.set(fsub.ownFilter)
+	if s.interest == nil {
+		return fsub, nil
+	}
+	return &interestSubscription{fsub, sub, s.interest}, nil
 }
 
-func (s *publisher) SubscribeForFilter() (FilterSubscription, error) {
-	sub, err := s.Subscribe()
+func (s *publisher) SubscribeCoalesced(window time.Duration) (Subscription, error) {
+	sub, err := s.relaySubscribe()
 	if err != nil {
 		return nil, err
 	}
-	return newFilterSubscription(s.log, sub, filter.All(), true), nil
+	return newCoalescedSubscription(s.log, sub, window, s.clock, s.metrics), nil
+}
+
+func (s *publisher) SubscribeChanged(changed filter.ChangeFunc) (Subscription, error) {
+	sub, err := s.relaySubscribe()
+	if err != nil {
+		return nil, err
+	}
+	return newChangedSubscription(s.log, sub, s.panics.changeFunc(changed), s.clock, s.metrics), nil
 }
 
 func (s *publisher) Clone() (Controller, error) {
-	sub, err := s.Subscribe()
+	sub, err := s.relaySubscribe()
 	if err != nil {
 		return nil, err
 	}
-	return newPublisher(s.log, sub), nil
+	return s.child(sub), nil
 }
 
 func (s *publisher) CloneWithFilter(f filter.Filter) (FilterController, error) {
-	sub, err := s.SubscribeWithFilter(f)
+	sub, err := s.subscribeFilter(f, false, true)
 	if err != nil {
 		return nil, err
 	}
-	return newFilterPublisher(s.log, sub), nil
+	return &filterController{sub, s.child(sub)}, nil
 }
 
 func (s *publisher) CloneForFilter() (FilterController, error) {
-	sub, err := s.SubscribeForFilter()
+	sub, err := s.subscribeFilter(filter.All(), true, true)
 	if err != nil {
 		return nil, err
 	}
-	return newFilterPublisher(s.log, sub), nil
+	return &filterController{sub, s.child(sub)}, nil
+}
+
+// child() returns a publisher for a clone subscribed to s.
+func (s *publisher) child(sub Subscription) *publisher {
+	return newRootPublisher(s.log, sub, 0, nil, s.clock, s.panics, s.metrics)
+}
+
+func (s *publisher) subscribers() ([]subscriberInfo, error) {
+	resultch := make(chan []subscriberInfo, 1)
+	select {
+	case <-s.lc.ShuttingDown():
+		return nil, errors.WithStack(ErrNotRunning)
+	case s.subscribersch <- resultch:
+		return <-resultch, nil
+	}
 }
 
 func (s *publisher) run() {
@@ -117,14 +271,22 @@ loop:
 		case evt, ok := <-s.parent.Events():
 			if !ok {
 				s.log.Debugf("parent events closed")
-				s.lc.ShutdownInitiated(nil)
+				s.lc.ShutdownInitiated(s.parent.Error())
 				break loop
 			}
 			s.distributeEvent(evt)
-		case resultch := <-s.subscribech:
-			resultch <- s.createSubscription()
+		case req := <-s.subscribech:
+			req.resultch <- s.createSubscription(req)
 		case sub := <-s.unsubscribech:
-			delete(s.subscriptions, sub)
+			s.unsubscribe(sub)
+		case m := <-s.snapshotch:
+			s.forwardSnapshot(m)
+		case resultch := <-s.subscribersch:
+			resultch <- s.subscriberInfo()
+		case o := <-s.observech:
+			s.observers[o] = struct{}{}
+		case o := <-s.unobservech:
+			delete(s.observers, o)
 		}
 	}
 
@@ -132,14 +294,51 @@ loop:
 		s.log.Debugf("draining: %v subscriptions", len(s.subscriptions))
 		select {
 		case sub := <-s.unsubscribech:
-			delete(s.subscriptions, sub)
+			s.unsubscribe(sub)
 		}
 	}
 
 	<-s.parent.Done()
 }
 
+// forwardSnapshot() asks the parent to place m in its event stream.
+func (s *publisher) forwardSnapshot(m *snapshotMarker) {
+	parent, ok := s.parent.(snapshotMarkable)
+	if !ok {
+		m.fail(errors.Errorf("snapshot not supported by %T", s.parent))
+		return
+	}
+	// the parent may be waiting on this loop; don't block it.
+	go func() {
+		if err := parent.markSnapshot(m); err != nil {
+			m.fail(err)
+		}
+	}()
+}
+
 func (s *publisher) distributeEvent(evt Event) {
+	if m, ok := evt.(*snapshotMarker); ok {
+		routeSnapshot(m, s.subscriptions)
+		return
+	}
+
+	if m, ok := evt.(*flushMarker); ok {
+		s.flush(m)
+		return
+	}
+
+	if s.draining {
+		return
+	}
+
+	if _, ok := evt.(resyncEvent); !ok {
+		s.replay.add(evt)
+	}
+
+	for o := range s.observers {
+		o.notify(evt)
+	}
+
 	s.log.Debugf("distribute event: sending %v to %v subscriptions", evt, len(s.subscriptions))
 
 	for sub := range s.subscriptions {
@@ -147,20 +346,65 @@ func (s *publisher) distributeEvent(evt Event) {
 	}
 }
 
-func (s *publisher) createSubscription() Subscription {
+// flush() passes m to every subscription.  Events that follow m are not
+// distributed.
+func (s *publisher) flush(m *flushMarker) {
+	s.log.Debugf("flush: no longer distributing events")
+	s.draining = true
+
+	m.fork(len(s.subscriptions))
+	for sub := range s.subscriptions {
+		if err := sub.send(m); err != nil {
+			m.done()
+		}
+	}
+}
+
+func (s *publisher) subscriberInfo() []subscriberInfo {
+	infos := make([]subscriberInfo, 0, len(s.subscriptions))
+	for sub := range s.subscriptions {
+		f := s.filters[sub].get()
+		infos = append(infos, subscriberInfo{
+			Buffered: len(sub.Events()),
+			Capacity: cap(sub.Events()),
+			Dropped:  sub.Dropped(),
+			Filter:   fmt.Sprint(f),
+			filter:   f,
+		})
+	}
+	return infos
+}
+
+func (s *publisher) createSubscription(req subscribeRequest) Subscription {
 	s.log.Debugf("create subscription: current count %v", len(s.subscriptions))
 
-	sub := newSubscription(s.log, s.lc.ShuttingDown(), s.parent.Ready(), s.parent.Cache())
+	var replay []Event
+	if req.replay {
+		replay = s.replay.list()
+	}
+
+	snapshotfn := sendSnapshotFn(s.snapshotch, s.lc.ShuttingDown())
+	sub := newBufferedSubscription(s.log, s.lc.ShuttingDown(), s.lc.Error, snapshotfn, s.parent.ResourceVersion, s.parent.Filter, s.parent.Ready(), s.parent.Cache(), req.size+len(replay), req.policy, req.relay, s.clock, s.metrics)
 
 	s.subscriptions[sub] = struct{}{}
+	if req.filter != nil {
+		s.filters[sub] = req.filter
+	}
+	s.interest.add(sub, req.interest)
+	s.metrics.SubscriberAdded()
+
+	// buffered before any live event is distributed.
+	for _, evt := range replay {
+		sub.send(replayedEvent{evt})
+	}
 
 	go func() {
 		select {
 		case <-sub.Done():
 			s.log.Debugf("create subscription: subscription done")
 		case <-s.lc.ShuttingDown():
-			s.log.Debugf("create subscription: shut down, killing subscription")
-			sub.Close()
+			// the subscription shuts itself down using the publisher's error.
+			s.log.Debugf("create subscription: shut down, waiting for subscription")
 			<-sub.Done()
 		}
 		s.unsubscribech <- sub
@@ -170,8 +414,11 @@ func (s *publisher) createSubscription() Subscription {
 	return sub
 }
 
-func newFilterPublisher(log logutil.Log, subscription FilterSubscription) FilterController {
-	return &filterController{subscription, newPublisher(log, subscription)}
+func (s *publisher) unsubscribe(sub subscription) {
+	delete(s.subscriptions, sub)
+	delete(s.filters, sub)
+	s.interest.remove(sub)
+	s.metrics.SubscriberRemoved()
 }
 
 type filterController struct {
@@ -187,10 +434,22 @@ func (c *filterController) Ready() <-chan struct{} {
 	return c.parent.Ready()
 }
 
+func (c *filterController) AddIndex(name string, fn IndexFunc) error {
+	return c.parent.AddIndex(name, fn)
+}
+
 func (c *filterController) Subscribe() (Subscription, error) {
 	return c.parent.Subscribe()
 }
 
+func (c *filterController) SubscribeWithBuffer(size int, policy OverflowPolicy) (Subscription, error) {
+	return c.parent.SubscribeWithBuffer(size, policy)
+}
+
+func (c *filterController) SubscribeWithReplay() (Subscription, error) {
+	return c.parent.SubscribeWithReplay()
+}
+
 func (c *filterController) SubscribeWithFilter(f filter.Filter) (FilterSubscription, error) {
 	return c.parent.SubscribeWithFilter(f)
 }
@@ -199,6 +458,14 @@ func (c *filterController) SubscribeForFilter() (FilterSubscription, error) {
 	return c.parent.SubscribeForFilter()
 }
 
+func (c *filterController) SubscribeCoalesced(window time.Duration) (Subscription, error) {
+	return c.parent.SubscribeCoalesced(window)
+}
+
+func (c *filterController) SubscribeChanged(changed filter.ChangeFunc) (Subscription, error) {
+	return c.parent.SubscribeChanged(changed)
+}
+
 func (c *filterController) Clone() (Controller, error) {
 	return c.parent.Clone()
 }
@@ -219,6 +486,38 @@ func (c *filterController) Close() {
 	c.parent.Close()
 }
 
+func (c *filterController) WaitForSync(ctx context.Context) bool {
+	return c.parent.WaitForSync(ctx)
+}
+
+func (c *filterController) Stats() Stats {
+	return c.parent.Stats()
+}
+
+func (c *filterController) Export(w io.Writer) error {
+	return c.parent.Export(w)
+}
+
+func (c *filterController) ObjectCount() int {
+	return c.parent.ObjectCount()
+}
+
+func (c *filterController) SubscriberCount(f filter.Filter) int {
+	return c.parent.SubscriberCount(f)
+}
+
+func (c *filterController) subscribers() ([]subscriberInfo, error) {
+	return listSubscribers(c.parent)
+}
+
+func (c *filterController) timeSource() clock.Clock {
+	return clockOf(c.parent)
+}
+
+func (c *filterController) guard() panicGuard {
+	return guardOf(c.parent)
+}
+
 func (c *filterController) Error() error {
 	return c.parent.Error()
 }
@@ -226,3 +525,7 @@ func (c *filterController) Error() error {
 func (c *filterController) Refilter(filter filter.Filter) error {
 	return c.subscription.Refilter(filter)
 }
+
+func (c *filterController) RefilterWithRate(filter filter.Filter, limit float64) error {
+	return c.subscription.RefilterWithRate(filter, limit)
+}
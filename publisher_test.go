@@ -2,20 +2,26 @@ package kcache
 
 import (
 	"context"
+	"runtime"
+	"strconv"
+	"sync/atomic"
 	"testing"
 
 	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/clock"
 	"github.com/boz/kcache/filter"
 	"github.com/boz/kcache/nsname"
 	"github.com/boz/kcache/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
 )
 
 func TestPublisher_lifecycle(t *testing.T) {
 	log := logutil.Default()
 	parent, _, readych := testNewSubscription(t, log, filter.Null())
-	publisher := newPublisher(log, parent)
+	publisher := newPublisher(log, parent, clock.Real(), nullMetrics{})
 	defer parent.Close()
 
 	sub, err := publisher.Subscribe()
@@ -75,7 +81,7 @@ func TestPublisher_lifecycle(t *testing.T) {
 func TestPublisher_Subscribe(t *testing.T) {
 	log := logutil.Default()
 	parent, cache, readych := testNewSubscription(t, log, filter.Null())
-	publisher := newPublisher(log, parent)
+	publisher := newPublisher(log, parent, clock.Real(), nullMetrics{})
 	defer parent.Close()
 
 	doTestPublisherSubscribe(t, parent, cache, publisher, readych)
@@ -84,7 +90,7 @@ func TestPublisher_Subscribe(t *testing.T) {
 func TestFilterPublisher_Subscribe(t *testing.T) {
 	log := logutil.Default()
 	parent, cache, readych := testNewSubscription(t, log, filter.Null())
-	publisher := newPublisher(log, parent)
+	publisher := newPublisher(log, parent, clock.Real(), nullMetrics{})
 	defer parent.Close()
 
 	fpublisher, err := publisher.CloneWithFilter(filter.Null())
@@ -113,7 +119,7 @@ func doTestPublisherSubscribe(t *testing.T,
 func TestPublisher_SubscribeWithFilter(t *testing.T) {
 	log := logutil.Default()
 	parent, cache, readych := testNewSubscription(t, log, filter.Null())
-	publisher := newPublisher(log, parent)
+	publisher := newPublisher(log, parent, clock.Real(), nullMetrics{})
 	defer parent.Close()
 
 	doTestPublisherSubscribeWithFilter(t, parent, cache, publisher, readych)
@@ -122,7 +128,7 @@ func TestPublisher_SubscribeWithFilter(t *testing.T) {
 func TestFilterPublisher_SubscribeWithFilter(t *testing.T) {
 	log := logutil.Default()
 	parent, cache, readych := testNewSubscription(t, log, filter.Null())
-	publisher := newPublisher(log, parent)
+	publisher := newPublisher(log, parent, clock.Real(), nullMetrics{})
 	defer parent.Close()
 
 	fpublisher, err := publisher.CloneWithFilter(filter.Null())
@@ -148,10 +154,58 @@ func doTestPublisherSubscribeWithFilter(t *testing.T,
 	testutil.AssertDone(t, "publisher", publisher)
 }
 
+func TestPublisher_SubscribeWithFilter_beforeReady(t *testing.T) {
+	log := logutil.Default()
+	parent, cache, readych := testNewSubscription(t, log, filter.Null())
+	publisher := newPublisher(log, parent, clock.Real(), nullMetrics{})
+	defer parent.Close()
+
+	doTestPublisherSubscribeFilter(t, parent, cache, publisher, readych)
+}
+
+func TestFilterPublisher_SubscribeWithFilter_beforeReady(t *testing.T) {
+	log := logutil.Default()
+	parent, cache, readych := testNewSubscription(t, log, filter.Null())
+	publisher := newPublisher(log, parent, clock.Real(), nullMetrics{})
+	defer parent.Close()
+
+	fpublisher, err := publisher.CloneWithFilter(filter.Null())
+	require.NoError(t, err)
+
+	doTestPublisherSubscribeFilter(t, parent, cache, fpublisher, readych)
+}
+
+func doTestPublisherSubscribeFilter(t *testing.T,
+	parent subscription, cache cache, publisher Controller, readych chan struct{}) {
+
+	// populated before the subscription is ready; must never be visible.
+	_, err := cache.update(testGenEvent(EventTypeCreate, "a", "x", "1"))
+	require.NoError(t, err)
+
+	f := filter.NSName(nsname.New("a", "c"))
+	sub, err := publisher.SubscribeWithFilter(f)
+	require.NoError(t, err)
+
+	testutil.AssertNotReady(t, "sub", sub)
+
+	close(readych)
+
+	testutil.AssertReady(t, "sub", sub)
+
+	list, err := sub.Cache().List()
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+
+	testPublisherFilteredSubscriber(t, parent, cache, sub)
+
+	publisher.Close()
+	testutil.AssertDone(t, "publisher", publisher)
+}
+
 func TestPublisher_SubscribeForFilter(t *testing.T) {
 	log := logutil.Default()
 	parent, cache, readych := testNewSubscription(t, log, filter.Null())
-	publisher := newPublisher(log, parent)
+	publisher := newPublisher(log, parent, clock.Real(), nullMetrics{})
 	defer parent.Close()
 	doTestPublisherSubscribeForFilter(t, parent, cache, publisher, readych)
 }
@@ -159,7 +213,7 @@ func TestPublisher_SubscribeForFilter(t *testing.T) {
 func TestFilterPublisher_SubscribeForFilter(t *testing.T) {
 	log := logutil.Default()
 	parent, cache, readych := testNewSubscription(t, log, filter.Null())
-	publisher := newPublisher(log, parent)
+	publisher := newPublisher(log, parent, clock.Real(), nullMetrics{})
 	defer parent.Close()
 
 	fpublisher, err := publisher.CloneWithFilter(filter.Null())
@@ -192,7 +246,7 @@ func doTestPublisherSubscribeForFilter(t *testing.T,
 func TestPublisher_Clone(t *testing.T) {
 	log := logutil.Default()
 	parent, cache, readych := testNewSubscription(t, log, filter.Null())
-	publisher := newPublisher(log, parent)
+	publisher := newPublisher(log, parent, clock.Real(), nullMetrics{})
 	defer parent.Close()
 
 	doTestPublisherClone(t, parent, cache, publisher, readych)
@@ -201,7 +255,7 @@ func TestPublisher_Clone(t *testing.T) {
 func TestFilterPublisher_Clone(t *testing.T) {
 	log := logutil.Default()
 	parent, cache, readych := testNewSubscription(t, log, filter.Null())
-	publisher := newPublisher(log, parent)
+	publisher := newPublisher(log, parent, clock.Real(), nullMetrics{})
 	defer parent.Close()
 
 	fpublisher, err := publisher.CloneWithFilter(filter.Null())
@@ -231,7 +285,7 @@ func doTestPublisherClone(t *testing.T,
 func TestPublisher_CloneWithFilter(t *testing.T) {
 	log := logutil.Default()
 	parent, cache, readych := testNewSubscription(t, log, filter.Null())
-	publisher := newPublisher(log, parent)
+	publisher := newPublisher(log, parent, clock.Real(), nullMetrics{})
 	defer parent.Close()
 
 	doTestPublisherCloneWithFilter(t, parent, cache, publisher, readych)
@@ -240,7 +294,7 @@ func TestPublisher_CloneWithFilter(t *testing.T) {
 func TestFilterPublisher_CloneWithFilter(t *testing.T) {
 	log := logutil.Default()
 	parent, cache, readych := testNewSubscription(t, log, filter.Null())
-	publisher := newPublisher(log, parent)
+	publisher := newPublisher(log, parent, clock.Real(), nullMetrics{})
 	defer parent.Close()
 
 	fpublisher, err := publisher.CloneWithFilter(filter.Null())
@@ -270,7 +324,7 @@ func doTestPublisherCloneWithFilter(t *testing.T,
 func TestPublisher_CloneForFilter(t *testing.T) {
 	log := logutil.Default()
 	parent, cache, readych := testNewSubscription(t, log, filter.Null())
-	publisher := newPublisher(log, parent)
+	publisher := newPublisher(log, parent, clock.Real(), nullMetrics{})
 	defer parent.Close()
 
 	doTestPublisherCloneForFilter(t, parent, cache, publisher, readych)
@@ -279,7 +333,7 @@ func TestPublisher_CloneForFilter(t *testing.T) {
 func TestFilterPublisher_CloneForFilter(t *testing.T) {
 	log := logutil.Default()
 	parent, cache, readych := testNewSubscription(t, log, filter.Null())
-	publisher := newPublisher(log, parent)
+	publisher := newPublisher(log, parent, clock.Real(), nullMetrics{})
 	defer parent.Close()
 
 	fpublisher, err := publisher.CloneWithFilter(filter.Null())
@@ -375,3 +429,91 @@ func testPublisherFilteredSubscriber(t *testing.T, parent subscription, cache ca
 	assert.Equal(t, "a", list[0].GetNamespace())
 	assert.Equal(t, "c", list[0].GetName())
 }
+
+type countingFilter struct {
+	filter.Filter
+	accepts *int64
+}
+
+func (f countingFilter) Accept(obj metav1.Object) bool {
+	atomic.AddInt64(f.accepts, 1)
+	return f.Filter.Accept(obj)
+}
+
+// BenchmarkPublisher_sharedFilters compares 1000 filtered subscriptions
+// that use 5 distinct filters with 5 filtered clones of 200 subscriptions
+// each.  The accepts/event metric is the number of Accept() calls made
+// for each published event.
+func BenchmarkPublisher_sharedFilters(b *testing.B) {
+	const subscribers = 1000
+	const filters = 5
+
+	// perEvent is the number of Accept() calls each event requires.
+	run := func(b *testing.B, perEvent int64, subscribe func(Controller, []filter.Filter) error) {
+		var accepts int64
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		eventch := make(chan watch.Event)
+
+		controller := testNewController(b, testContext(ctx), testEvents(eventch))
+		defer controller.Close()
+
+		fs := make([]filter.Filter, 0, filters)
+		for i := 0; i < filters; i++ {
+			f := filter.NSName(nsname.New("ns-"+strconv.Itoa(i), ""))
+			fs = append(fs, countingFilter{f, &accepts})
+		}
+		if err := subscribe(controller, fs); err != nil {
+			b.Fatal(err)
+		}
+		<-controller.Ready()
+
+		b.ResetTimer()
+		start := atomic.LoadInt64(&accepts)
+		for i := 0; i < b.N; i++ {
+			ns := "ns-" + strconv.Itoa(i%filters)
+			eventch <- watch.Event{Type: watch.Added, Object: testGenPod(ns, strconv.Itoa(i), strconv.Itoa(i+2))}
+
+			// wait until every filter has seen the event.
+			for atomic.LoadInt64(&accepts) < start+perEvent*int64(i+1) {
+				runtime.Gosched()
+			}
+		}
+		b.StopTimer()
+
+		b.ReportMetric(float64(atomic.LoadInt64(&accepts)-start)/float64(b.N), "accepts/event")
+	}
+
+	b.Run("subscriptions", func(b *testing.B) {
+		run(b, subscribers, func(c Controller, fs []filter.Filter) error {
+			for i := 0; i < subscribers; i++ {
+				sub, err := c.SubscribeWithFilter(fs[i%filters])
+				if err != nil {
+					return err
+				}
+				<-sub.Ready()
+			}
+			return nil
+		})
+	})
+
+	b.Run("clones", func(b *testing.B) {
+		run(b, filters, func(c Controller, fs []filter.Filter) error {
+			for _, f := range fs {
+				clone, err := c.CloneWithFilter(f)
+				if err != nil {
+					return err
+				}
+				for i := 0; i < subscribers/filters; i++ {
+					if _, err := clone.Subscribe(); err != nil {
+						return err
+					}
+				}
+				<-clone.Ready()
+			}
+			return nil
+		})
+	})
+}
@@ -0,0 +1,119 @@
+package kcache
+
+import (
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/filter"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// panicGuard recovers panics in user-supplied functions so that they
+// don't take down the goroutine that calls them.  Each panic is logged
+// and passed to handler, if set.
+type panicGuard struct {
+	log     logutil.Log
+	handler func(error)
+}
+
+// recover() must be deferred directly by the function that calls the
+// user-supplied function named name.
+func (g panicGuard) recover(name string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	err := errors.Errorf("%v panicked: %v", name, r)
+	g.log.Errorf("%+v", err)
+	if g.handler != nil {
+		g.handler(err)
+	}
+}
+
+// transform() returns fn, except that an object for which fn panics is
+// used untransformed.
+func (g panicGuard) transform(fn TransformFunc) TransformFunc {
+	if fn == nil {
+		return nil
+	}
+	return func(obj metav1.Object) (result metav1.Object) {
+		result = obj
+		defer g.recover("transform")
+		return fn(obj)
+	}
+}
+
+func (g panicGuard) eventFunc(name string, fn func(Event)) func(Event) {
+	return func(evt Event) {
+		defer g.recover(name)
+		fn(evt)
+	}
+}
+
+func (g panicGuard) countFunc(name string, fn func(int)) func(int) {
+	if fn == nil {
+		return nil
+	}
+	return func(count int) {
+		defer g.recover(name)
+		fn(count)
+	}
+}
+
+// changeFunc() returns fn, except that an update for which fn panics is
+// reported as a change.
+func (g panicGuard) changeFunc(fn filter.ChangeFunc) filter.ChangeFunc {
+	if fn == nil {
+		return nil
+	}
+	return func(prev, next metav1.Object) (changed bool) {
+		changed = true
+		defer g.recover("SubscribeChanged() change function")
+		return fn(prev, next)
+	}
+}
+
+// monitorHandler() returns h, except that panics in its callbacks are
+// recovered.
+func (g panicGuard) monitorHandler(h Handler) Handler {
+	return guardedHandler{h, g}
+}
+
+type guardedHandler struct {
+	handler Handler
+	panics  panicGuard
+}
+
+func (h guardedHandler) OnInitialize(objs []metav1.Object) {
+	defer h.panics.recover("Monitor OnInitialize()")
+	h.handler.OnInitialize(objs)
+}
+
+func (h guardedHandler) OnCreate(obj metav1.Object) {
+	defer h.panics.recover("Monitor OnCreate()")
+	h.handler.OnCreate(obj)
+}
+
+func (h guardedHandler) OnUpdate(obj metav1.Object) {
+	defer h.panics.recover("Monitor OnUpdate()")
+	h.handler.OnUpdate(obj)
+}
+
+func (h guardedHandler) OnDelete(obj metav1.Object) {
+	defer h.panics.recover("Monitor OnDelete()")
+	h.handler.OnDelete(obj)
+}
+
+// guarded is implemented by controllers to provide the panicGuard
+// configured by Builder.PanicHandler().
+type guarded interface {
+	guard() panicGuard
+}
+
+// guardOf() returns the panicGuard of the controller that p belongs to,
+// or one that only logs if it has none.
+func guardOf(p Publisher) panicGuard {
+	if g, ok := p.(guarded); ok {
+		return g.guard()
+	}
+	return panicGuard{log: logutil.Default()}
+}
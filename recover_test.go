@@ -0,0 +1,197 @@
+package kcache
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestController_panics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventch := make(chan watch.Event)
+	panics := make(chan error, 10)
+
+	controller := testNewController(t, testContext(ctx), testEvents(eventch), testBuild(func(b Builder) {
+		b.Transform(func(obj metav1.Object) metav1.Object {
+			if obj.GetName() == "bad-transform" {
+				panic("transform")
+			}
+			return obj
+		})
+		b.PanicHandler(func(err error) { panics <- err })
+	}))
+	defer controller.Close()
+	testutil.AssertReady(t, "controller", controller)
+
+	stop, err := controller.OnEvent(func(evt Event) {
+		if evt.Resource().GetName() == "bad-callback" {
+			panic("callback")
+		}
+	})
+	require.NoError(t, err)
+	defer stop()
+
+	clone, err := controller.Clone()
+	require.NoError(t, err)
+	defer clone.Close()
+	cstop, err := clone.OnEvent(func(Event) { panic("clone callback") })
+	require.NoError(t, err)
+	defer cstop()
+
+	sub, err := controller.Subscribe()
+	require.NoError(t, err)
+	csub, err := clone.Subscribe()
+	require.NoError(t, err)
+
+	send := func(name, vsn string) {
+		select {
+		case eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", name, vsn)}:
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "event not received")
+		}
+	}
+
+	read := func(name string, sub Subscription, expected string) {
+		select {
+		case evt, ok := <-sub.Events():
+			require.True(t, ok, "%v: closed", name)
+			assert.Equal(t, expected, evt.Resource().GetName(), name)
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "no event", "%v: %v", name, expected)
+		}
+	}
+
+	recovered := func(count int) {
+		for i := 0; i < count; i++ {
+			select {
+			case err := <-panics:
+				assert.Error(t, err)
+			case <-testutil.Timerch(ctx, time.Second):
+				require.Fail(t, "panic not reported", "%v/%v", i, count)
+			}
+		}
+	}
+
+	for i, name := range []string{"bad-callback", "bad-transform", "good"} {
+		send(name, strconv.Itoa(i+2))
+		read("sub", sub, name)
+		read("csub", csub, name)
+	}
+
+	// bad-callback panics in both callbacks; each other event panics in
+	// the clone's callback, and bad-transform in the transform.
+	recovered(5)
+
+	obj, err := controller.Cache().Get("ns", "bad-transform")
+	require.NoError(t, err)
+	assert.NotNil(t, obj)
+
+	testutil.AssertNotDone(t, "controller", controller)
+	testutil.AssertNotDone(t, "clone", clone)
+}
+
+func TestController_panicsInSubscribers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventch := make(chan watch.Event)
+	panics := make(chan error, 10)
+
+	controller := testNewController(t, testContext(ctx), testEvents(eventch),
+		testBuild(func(b Builder) { b.PanicHandler(func(err error) { panics <- err }) }))
+	defer controller.Close()
+	testutil.AssertReady(t, "controller", controller)
+
+	sub, err := controller.Subscribe()
+	require.NoError(t, err)
+
+	fsub, err := controller.SubscribeWithFilter(filter.Func(func(obj metav1.Object) bool {
+		if obj.GetName() == "bad-filter" {
+			panic("filter")
+		}
+		return true
+	}))
+	require.NoError(t, err)
+	testutil.AssertReady(t, "fsub", fsub)
+
+	chsub, err := controller.SubscribeChanged(func(prev, next metav1.Object) bool {
+		panic("change function")
+	})
+	require.NoError(t, err)
+
+	created := make(chan string, 10)
+	monitor, err := NewMonitor(controller, BuildHandler().
+		OnCreate(func(obj metav1.Object) {
+			if obj.GetName() == "bad-monitor" {
+				panic("monitor")
+			}
+			created <- obj.GetName()
+		}).Create())
+	require.NoError(t, err)
+	defer monitor.Close()
+
+	send := func(et watch.EventType, name, vsn string) {
+		select {
+		case eventch <- watch.Event{Type: et, Object: testGenPod("ns", name, vsn)}:
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "event not received")
+		}
+	}
+
+	read := func(name string, sub Subscription, expected ...string) {
+		for _, exp := range expected {
+			select {
+			case evt, ok := <-sub.Events():
+				require.True(t, ok, "%v: closed", name)
+				assert.Equal(t, exp, evt.Resource().GetName(), name)
+			case <-testutil.Timerch(ctx, time.Second):
+				require.Fail(t, "no event", "%v: %v", name, exp)
+			}
+		}
+	}
+
+	send(watch.Added, "bad-filter", "2")
+	send(watch.Added, "bad-monitor", "3")
+	send(watch.Added, "good", "4")
+	send(watch.Modified, "good", "5")
+
+	read("sub", sub, "bad-filter", "bad-monitor", "good", "good")
+
+	// bad-filter is rejected by the filter that panics for it.
+	read("fsub", fsub, "bad-monitor", "good", "good")
+
+	// an update for which the change function panics is delivered.
+	read("chsub", chsub, "bad-filter", "bad-monitor", "good", "good")
+
+	for _, expected := range []string{"bad-filter", "good"} {
+		select {
+		case name := <-created:
+			assert.Equal(t, expected, name)
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "no create", "monitor: %v", expected)
+		}
+	}
+
+	// the filter, change function, and monitor each panic once.
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-panics:
+			assert.Error(t, err)
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "panic not reported", "%v/3", i)
+		}
+	}
+
+	testutil.AssertNotDone(t, "controller", controller)
+	testutil.AssertNotDone(t, "monitor", monitor)
+}
@@ -0,0 +1,55 @@
+package kcache
+
+// replayedEvent is an event that was published before the subscription
+// that receives it was created.
+type replayedEvent struct {
+	Event
+}
+
+// Replayed() returns true if evt was published before the subscription
+// that delivered it was created.  See Publisher.SubscribeWithReplay().
+func Replayed(evt Event) bool {
+	if merged, ok := evt.(mergedEvent); ok {
+		evt = merged.Event
+	}
+	_, ok := evt.(replayedEvent)
+	return ok
+}
+
+// eventRing retains the most recently added events.
+type eventRing struct {
+	events []Event
+	next   int
+	full   bool
+}
+
+// newEventRing() returns a ring that retains size events.  It returns nil,
+// which retains nothing, if size is not positive.
+func newEventRing(size int) *eventRing {
+	if size <= 0 {
+		return nil
+	}
+	return &eventRing{events: make([]Event, size)}
+}
+
+func (r *eventRing) add(evt Event) {
+	if r == nil {
+		return
+	}
+	r.events[r.next] = evt
+	r.next = (r.next + 1) % len(r.events)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// list() returns the retained events, oldest first.
+func (r *eventRing) list() []Event {
+	if r == nil {
+		return nil
+	}
+	if !r.full {
+		return append([]Event(nil), r.events[:r.next]...)
+	}
+	return append(append([]Event(nil), r.events[r.next:]...), r.events[:r.next]...)
+}
@@ -0,0 +1,137 @@
+package kcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/boz/kcache/client/mocks"
+	"github.com/boz/kcache/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestEventRing(t *testing.T) {
+	names := func(events []Event) []string {
+		var names []string
+		for _, evt := range events {
+			names = append(names, evt.Resource().GetName())
+		}
+		return names
+	}
+
+	var empty *eventRing
+	empty.add(testGenEvent(EventTypeCreate, "ns", "a", "1"))
+	assert.Empty(t, empty.list())
+	assert.Nil(t, newEventRing(0))
+
+	ring := newEventRing(3)
+	assert.Empty(t, ring.list())
+
+	ring.add(testGenEvent(EventTypeCreate, "ns", "a", "1"))
+	ring.add(testGenEvent(EventTypeCreate, "ns", "b", "2"))
+	assert.Equal(t, []string{"a", "b"}, names(ring.list()))
+
+	ring.add(testGenEvent(EventTypeCreate, "ns", "c", "3"))
+	assert.Equal(t, []string{"a", "b", "c"}, names(ring.list()))
+
+	ring.add(testGenEvent(EventTypeCreate, "ns", "d", "4"))
+	ring.add(testGenEvent(EventTypeCreate, "ns", "e", "5"))
+	assert.Equal(t, []string{"c", "d", "e"}, names(ring.list()))
+}
+
+func TestController_SubscribeWithReplay(t *testing.T) {
+
+	newController := func(t *testing.T, ctx context.Context, eventch chan watch.Event, size int) Controller {
+		controller := testNewController(t, testContext(ctx), testEvents(eventch), testBuild(func(b Builder) { b.ReplayBuffer(size) }))
+		testutil.AssertReady(t, "controller", controller)
+		return controller
+	}
+
+	type result struct {
+		name     string
+		replayed bool
+	}
+
+	read := func(t *testing.T, name string, sub Subscription, count int) []result {
+		var results []result
+		for len(results) < count {
+			select {
+			case evt := <-sub.Events():
+				results = append(results, result{evt.Resource().GetName(), Replayed(evt)})
+			case <-testutil.AsyncWaitch(context.Background()):
+				require.Fail(t, "no event", "%v: %v/%v events", name, len(results), count)
+			}
+		}
+		return results
+	}
+
+	t.Run("replay", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		eventch := make(chan watch.Event, 3)
+		controller := newController(t, ctx, eventch, 2)
+		defer controller.Close()
+
+		sub, err := controller.Subscribe()
+		require.NoError(t, err)
+
+		for _, name := range []string{"a", "b", "c"} {
+			eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", name, "2")}
+		}
+		assert.Equal(t, []result{{"a", false}, {"b", false}, {"c", false}}, read(t, "sub", sub, 3))
+
+		rsub, err := controller.SubscribeWithReplay()
+		require.NoError(t, err)
+
+		eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", "d", "3")}
+
+		assert.Equal(t, []result{{"b", true}, {"c", true}, {"d", false}}, read(t, "rsub", rsub, 3))
+		assert.Equal(t, []result{{"d", false}}, read(t, "sub", sub, 1))
+
+		// not replayed to clones.
+		clone, err := controller.Clone()
+		require.NoError(t, err)
+		csub, err := clone.SubscribeWithReplay()
+		require.NoError(t, err)
+
+		eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", "e", "4")}
+		assert.Equal(t, []result{{"e", false}}, read(t, "csub", csub, 1))
+
+		select {
+		case evt := <-rsub.Events():
+			assert.Equal(t, "e", evt.Resource().GetName())
+			assert.False(t, Replayed(evt))
+		case <-time.After(time.Second):
+			require.Fail(t, "no event")
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		eventch := make(chan watch.Event, 2)
+		controller := newController(t, ctx, eventch, 0)
+		defer controller.Close()
+
+		sub, err := controller.Subscribe()
+		require.NoError(t, err)
+
+		eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", "a", "2")}
+		read(t, "sub", sub, 1)
+
+		rsub, err := controller.SubscribeWithReplay()
+		require.NoError(t, err)
+
+		eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", "b", "3")}
+		assert.Equal(t, []result{{"b", false}}, read(t, "rsub", rsub, 1))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := NewBuilder().Client(&mocks.Client{}).ReplayBuffer(-1).Create()
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,135 @@
+package kcache
+
+import (
+	"time"
+
+	lifecycle "github.com/boz/go-lifecycle"
+	"github.com/boz/kcache/clock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Settler delivers the contents of a publisher's cache once events have
+// stopped arriving.  It is useful for rebuilding derived state from the
+// complete set of objects without doing so for every change in a burst.
+type Settler interface {
+	// Settled() receives the contents of the cache once the cache is
+	// ready and again each time no events have occurred for the quiet
+	// period following an event.  Only the latest contents are kept if
+	// the receiver falls behind.  Settled() is closed when the settler
+	// shuts down.
+	Settled() <-chan []metav1.Object
+
+	Close()
+	Done() <-chan struct{}
+	Error() error
+}
+
+// NewSettler() returns a settler that subscribes to publisher and waits
+// for quiet periods of at least quiet.  Quiet periods are timed by the
+// clock of the controller that publisher belongs to; see Builder.Clock().
+func NewSettler(publisher Publisher, quiet time.Duration) (Settler, error) {
+	sub, err := publisher.Subscribe()
+	if err != nil {
+		return nil, err
+	}
+	s := &settler{
+		sub:       sub,
+		quiet:     quiet,
+		clock:     clockOf(publisher),
+		settledch: make(chan []metav1.Object, 1),
+		lc:        lifecycle.New(),
+	}
+	go s.run()
+	return s, nil
+}
+
+type settler struct {
+	sub       Subscription
+	quiet     time.Duration
+	clock     clock.Clock
+	settledch chan []metav1.Object
+	lc        lifecycle.Lifecycle
+}
+
+func (s *settler) Settled() <-chan []metav1.Object {
+	return s.settledch
+}
+
+func (s *settler) Close() {
+	s.sub.Close()
+}
+
+func (s *settler) Done() <-chan struct{} {
+	return s.lc.Done()
+}
+
+func (s *settler) Error() error {
+	if err := s.lc.Error(); err != nil {
+		return err
+	}
+	return s.sub.Error()
+}
+
+func (s *settler) run() {
+	defer s.lc.ShutdownCompleted()
+	defer close(s.settledch)
+
+	select {
+	case <-s.sub.Done():
+		s.lc.ShutdownInitiated(nil)
+		return
+	case <-s.sub.Ready():
+		if !s.settle() {
+			return
+		}
+	}
+
+	timer := s.clock.NewTimer(s.quiet)
+	timer.Stop()
+	defer timer.Stop()
+
+	var timerch <-chan time.Time
+
+	for {
+		select {
+		case _, ok := <-s.sub.Events():
+			if !ok {
+				s.lc.ShutdownInitiated(nil)
+				<-s.sub.Done()
+				return
+			}
+
+			if timerch != nil && !timer.Stop() {
+				<-timer.C()
+			}
+			timer.Reset(s.quiet)
+			timerch = timer.C()
+
+		case <-timerch:
+			timerch = nil
+			if !s.settle() {
+				return
+			}
+		}
+	}
+}
+
+// settle() delivers the contents of the cache.
+// settle() returns false if the settler is shutting down.
+func (s *settler) settle() bool {
+	objs, err := s.sub.Cache().List()
+	if err != nil {
+		s.lc.ShutdownInitiated(err)
+		s.sub.Close()
+		<-s.sub.Done()
+		return false
+	}
+
+	// replace an undelivered result.
+	select {
+	case <-s.settledch:
+	default:
+	}
+	s.settledch <- objs
+	return true
+}
@@ -0,0 +1,114 @@
+package kcache
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/clock"
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSettler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log := logutil.Default()
+	parent, cache, readych := testNewSubscription(t, log, filter.Null())
+	publisher := newPublisher(log, parent, clock.Real(), nullMetrics{})
+	defer parent.Close()
+
+	cache.sync([]metav1.Object{testGenPod("a", "0", "1")})
+
+	settler, err := NewSettler(publisher, 50*time.Millisecond)
+	require.NoError(t, err)
+
+	settled := func(name string) []metav1.Object {
+		select {
+		case objs, ok := <-settler.Settled():
+			require.True(t, ok, name)
+			return objs
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "not settled", name)
+			return nil
+		}
+	}
+
+	close(readych)
+	assert.Len(t, settled("ready"), 1)
+
+	// a burst of events.
+	for i := 1; i <= 5; i++ {
+		evt := testGenEvent(EventTypeCreate, "a", strconv.Itoa(i), strconv.Itoa(i+1))
+		_, err := cache.update(evt)
+		require.NoError(t, err)
+		require.NoError(t, parent.send(evt))
+	}
+
+	assert.Len(t, settled("burst"), 6)
+
+	select {
+	case <-settler.Settled():
+		assert.Fail(t, "settled without events")
+	case <-testutil.Timerch(ctx, 200*time.Millisecond):
+	}
+
+	settler.Close()
+	testutil.AssertDone(t, "settler", settler)
+
+	_, ok := <-settler.Settled()
+	assert.False(t, ok)
+	assert.NoError(t, settler.Error())
+}
+
+func TestSettler_clock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := clock.NewFake(time.Now())
+
+	log := logutil.Default()
+	parent, cache, readych := testNewSubscription(t, log, filter.Null())
+	publisher := newPublisher(log, parent, c, nullMetrics{})
+	defer parent.Close()
+
+	settler, err := NewSettler(publisher, time.Minute)
+	require.NoError(t, err)
+
+	close(readych)
+	select {
+	case <-settler.Settled():
+	case <-testutil.Timerch(ctx, time.Second):
+		require.Fail(t, "not settled when ready")
+	}
+
+	evt := testGenEvent(EventTypeCreate, "a", "1", "2")
+	_, err = cache.update(evt)
+	require.NoError(t, err)
+	require.NoError(t, parent.send(evt))
+	testWaitForClock(t, c, 1)
+
+	c.Advance(time.Minute - time.Nanosecond)
+	select {
+	case <-settler.Settled():
+		assert.Fail(t, "settled before quiet period")
+	case <-testutil.AsyncWaitch(ctx):
+	}
+
+	c.Advance(time.Nanosecond)
+	select {
+	case objs := <-settler.Settled():
+		assert.Len(t, objs, 1)
+	case <-testutil.Timerch(ctx, time.Second):
+		require.Fail(t, "not settled after quiet period")
+	}
+
+	settler.Close()
+	testutil.AssertDone(t, "settler", settler)
+}
@@ -0,0 +1,184 @@
+package kcache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/boz/kcache/clock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the interval at which a subscription holding a flush marker checks
+// whether the events ahead of it have been read.
+const flushPollPeriod = 10 * time.Millisecond
+
+func (c *controller) Shutdown(ctx context.Context) error {
+	m := newFlushMarker(c.clock)
+
+	select {
+	case c.drainch <- m:
+	case <-c.lc.ShuttingDown():
+		<-c.Done()
+		return c.Error()
+	case <-ctx.Done():
+		c.Close()
+		return ctx.Err()
+	}
+
+	err := m.wait(ctx, c.lc.ShuttingDown())
+
+	c.Close()
+
+	if err != nil {
+		return err
+	}
+	return c.Error()
+}
+
+func (s *publisher) Shutdown(ctx context.Context) error {
+	m := newFlushMarker(s.clock)
+
+	// the publisher stops distributing events once m arrives from its
+	// parent.
+	if parent, ok := s.parent.(flushMarkable); !ok || parent.markFlush(m) != nil {
+		m.done()
+	}
+
+	err := m.wait(ctx, s.lc.ShuttingDown())
+
+	s.Close()
+	<-s.Done()
+
+	if err != nil {
+		return err
+	}
+	return s.Error()
+}
+
+func (c *filterController) Shutdown(ctx context.Context) error {
+	return c.parent.Shutdown(ctx)
+}
+
+// flushMarker is sent through the event stream by Shutdown(), after the
+// last event that is to be delivered.  It follows the path of events to
+// every subscription.
+//
+// Each subscription that receives the marker holds a claim on it.  A
+// subscription whose events are read by another subscription or
+// publisher passes the marker, and its claim, on with its events; one
+// whose events are read by the user releases its claim once the events
+// ahead of the marker have been read.  The marker is complete once every
+// claim is released.
+type flushMarker struct {
+	// outstanding claims; accessed atomically.  first for 64-bit alignment.
+	pending int64
+
+	donech chan struct{}
+	clock  clock.Clock
+}
+
+// flushMarkable is implemented by subscriptions that can place a flush
+// marker in their event stream.
+type flushMarkable interface {
+	markFlush(*flushMarker) error
+}
+
+// newFlushMarker() returns a marker with a single claim, held by the
+// caller.
+func newFlushMarker(clock clock.Clock) *flushMarker {
+	return &flushMarker{pending: 1, donech: make(chan struct{}), clock: clock}
+}
+
+func (m *flushMarker) Type() EventType {
+	return ""
+}
+
+func (m *flushMarker) Resource() metav1.Object {
+	return nil
+}
+
+func (m *flushMarker) DeepCopyResource() metav1.Object {
+	return nil
+}
+
+func (m *flushMarker) ObservedAt() time.Time {
+	return time.Time{}
+}
+
+func (m *flushMarker) String() string {
+	return "Event{flush}"
+}
+
+// fork() replaces the caller's claim with one for each of n
+// subscriptions that the marker is passed to.
+func (m *flushMarker) fork(n int) {
+	m.add(n - 1)
+}
+
+// done() releases the caller's claim.
+func (m *flushMarker) done() {
+	m.add(-1)
+}
+
+func (m *flushMarker) add(delta int) {
+	if atomic.AddInt64(&m.pending, int64(delta)) == 0 {
+		close(m.donech)
+	}
+}
+
+// awaitRead() releases the caller's claim once the events buffered in ch
+// have been read or stopch is closed.
+func (m *flushMarker) awaitRead(ch chan Event, stopch <-chan struct{}) {
+	go func() {
+		defer m.done()
+
+		if len(ch) == 0 {
+			return
+		}
+
+		ticker := m.clock.NewTicker(flushPollPeriod)
+		defer ticker.Stop()
+
+		for len(ch) > 0 {
+			select {
+			case <-ticker.C():
+			case <-stopch:
+				return
+			}
+		}
+	}()
+}
+
+// wait() returns nil once every claim on the marker has been released or
+// stopch is closed.  wait() returns ctx.Err() if ctx is done first.
+func (m *flushMarker) wait(ctx context.Context, stopch <-chan struct{}) error {
+	select {
+	case <-m.donech:
+		return nil
+	case <-stopch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseFlushes() releases the claims of the flush markers among the
+// events remaining in ch, which must be closed.
+func releaseFlushes(ch <-chan Event) {
+	for evt := range ch {
+		if m, ok := evt.(*flushMarker); ok {
+			m.done()
+		}
+	}
+}
+
+// isMarker() returns true if evt is a snapshot or flush marker, which
+// subscriptions pass on rather than discard.
+func isMarker(evt Event) bool {
+	switch evt.(type) {
+	case *snapshotMarker, *flushMarker:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,233 @@
+package kcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestController_Shutdown(t *testing.T) {
+
+	// returns a ready controller whose watch delivers eventch.
+	newController := func(t *testing.T, ctx context.Context, eventch chan watch.Event) Controller {
+		controller := testNewController(t, testContext(ctx), testEvents(eventch))
+		testutil.AssertReady(t, "controller", controller)
+		return controller
+	}
+
+	// waits until sub has count events buffered.
+	waitBuffered := func(t *testing.T, ctx context.Context, name string, sub Subscription, count int) {
+		timeout := testutil.Timerch(ctx, time.Second)
+		for len(sub.Events()) < count {
+			select {
+			case <-time.After(time.Millisecond):
+			case <-timeout:
+				require.Fail(t, "timed out", "%v: %v/%v events buffered", name, len(sub.Events()), count)
+			}
+		}
+	}
+
+	// reads count events from sub.
+	read := func(t *testing.T, ctx context.Context, name string, sub Subscription, count int) []string {
+		var names []string
+		timeout := testutil.Timerch(ctx, time.Second)
+		for len(names) < count {
+			select {
+			case evt, ok := <-sub.Events():
+				require.True(t, ok, "%v: closed after %v/%v events", name, len(names), count)
+				names = append(names, evt.Resource().GetName())
+			case <-timeout:
+				require.Fail(t, "timed out", "%v: %v/%v events", name, len(names), count)
+			}
+		}
+		return names
+	}
+
+	// asserts that sub is done and has no more events.
+	assertClosed := func(t *testing.T, name string, sub Subscription) {
+		testutil.AssertDone(t, name, sub)
+		_, ok := <-sub.Events()
+		assert.False(t, ok, "%v: event after close", name)
+	}
+
+	shutdown := func(ctx context.Context, c Controller) <-chan error {
+		errch := make(chan error, 1)
+		go func() { errch <- c.Shutdown(ctx) }()
+		return errch
+	}
+
+	t.Run("flush", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		eventch := make(chan watch.Event, 4)
+		controller := newController(t, ctx, eventch)
+		defer controller.Close()
+
+		sub, err := controller.Subscribe()
+		require.NoError(t, err)
+		fsub, err := controller.SubscribeWithFilter(filter.Null())
+		require.NoError(t, err)
+		testutil.AssertReady(t, "fsub", fsub)
+
+		for _, name := range []string{"a", "b", "c"} {
+			eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", name, "2")}
+		}
+		waitBuffered(t, ctx, "sub", sub, 3)
+		waitBuffered(t, ctx, "fsub", fsub, 3)
+
+		errch := shutdown(ctx, controller)
+
+		select {
+		case err := <-errch:
+			require.Fail(t, "shutdown before events read", "%v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+		testutil.AssertNotDone(t, "controller", controller)
+
+		// not accepted once shutdown has begun.
+		eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", "d", "3")}
+
+		assert.Equal(t, []string{"a", "b", "c"}, read(t, ctx, "sub", sub, 3))
+		assert.Equal(t, []string{"a", "b", "c"}, read(t, ctx, "fsub", fsub, 3))
+
+		select {
+		case err := <-errch:
+			assert.NoError(t, err)
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "shutdown not complete")
+		}
+		testutil.AssertDone(t, "controller", controller)
+		assertClosed(t, "sub", sub)
+		assertClosed(t, "fsub", fsub)
+	})
+
+	t.Run("held", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		eventch := make(chan watch.Event, 3)
+		controller := newController(t, ctx, eventch)
+		defer controller.Close()
+
+		// events are held by the coalesced subscription for the window,
+		// and pass through the subscriptions of the filtered clone.
+		csub, err := controller.SubscribeCoalesced(time.Hour)
+		require.NoError(t, err)
+		clone, err := controller.CloneWithFilter(filter.Null())
+		require.NoError(t, err)
+		fsub, err := clone.SubscribeWithFilter(filter.Null())
+		require.NoError(t, err)
+		testutil.AssertReady(t, "fsub", fsub)
+
+		for _, name := range []string{"a", "b", "c"} {
+			eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", name, "2")}
+		}
+
+		// accepted by the controller once they are in its cache.
+		timeout := testutil.Timerch(ctx, time.Second)
+		for controller.ObjectCount() < 3 {
+			select {
+			case <-time.After(time.Millisecond):
+			case <-timeout:
+				require.Fail(t, "timed out", "%v/3 objects", controller.ObjectCount())
+			}
+		}
+
+		errch := shutdown(ctx, controller)
+
+		assert.Equal(t, []string{"a", "b", "c"}, read(t, ctx, "csub", csub, 3))
+
+		select {
+		case err := <-errch:
+			require.Fail(t, "shutdown before events read", "%v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		assert.Equal(t, []string{"a", "b", "c"}, read(t, ctx, "fsub", fsub, 3))
+
+		select {
+		case err := <-errch:
+			assert.NoError(t, err)
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "shutdown not complete")
+		}
+		testutil.AssertDone(t, "controller", controller)
+		assertClosed(t, "csub", csub)
+		assertClosed(t, "fsub", fsub)
+	})
+
+	t.Run("context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		eventch := make(chan watch.Event, 1)
+		controller := newController(t, ctx, eventch)
+		defer controller.Close()
+
+		sub, err := controller.Subscribe()
+		require.NoError(t, err)
+
+		eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", "a", "2")}
+		waitBuffered(t, ctx, "sub", sub, 1)
+
+		sctx, scancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer scancel()
+
+		select {
+		case err := <-shutdown(sctx, controller):
+			assert.Equal(t, context.DeadlineExceeded, err)
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "shutdown not complete")
+		}
+		testutil.AssertDone(t, "controller", controller)
+
+		// still readable after shutdown.
+		assert.Equal(t, []string{"a"}, read(t, ctx, "sub", sub, 1))
+		assertClosed(t, "sub", sub)
+	})
+
+	t.Run("clone", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		eventch := make(chan watch.Event, 1)
+		controller := newController(t, ctx, eventch)
+		defer controller.Close()
+
+		clone, err := controller.Clone()
+		require.NoError(t, err)
+		csub, err := clone.Subscribe()
+		require.NoError(t, err)
+		testutil.AssertReady(t, "csub", csub)
+
+		eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", "a", "2")}
+		waitBuffered(t, ctx, "csub", csub, 1)
+
+		errch := shutdown(ctx, clone)
+
+		select {
+		case err := <-errch:
+			require.Fail(t, "shutdown before events read", "%v", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		assert.Equal(t, []string{"a"}, read(t, ctx, "csub", csub, 1))
+
+		select {
+		case err := <-errch:
+			assert.NoError(t, err)
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "shutdown not complete")
+		}
+		testutil.AssertDone(t, "clone", clone)
+		assertClosed(t, "csub", csub)
+		testutil.AssertNotDone(t, "controller", controller)
+	})
+}
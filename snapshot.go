@@ -0,0 +1,129 @@
+package kcache
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type snapshotResult struct {
+	list []metav1.Object
+	err  error
+}
+
+// snapshotMarker is sent through the event stream to mark the position
+// at which a snapshot of the cache was taken.
+//
+// A subscription requests a snapshot by passing a marker up to the loop
+// that owns its cache.  That loop lists the cache and sends the marker down
+// the same path as its events, so that every event before the marker is
+// reflected in the list and every event after it is not.
+type snapshotMarker struct {
+	list []metav1.Object
+	err  error
+
+	// the subscription that requested the snapshot.
+	origin subscription
+
+	// subscriptions that the marker must be delivered through; last first.
+	route []subscription
+
+	resultch chan snapshotResult
+}
+
+// snapshotMarkable is implemented by subscriptions that can place a
+// snapshot marker in their event stream.
+type snapshotMarkable interface {
+	markSnapshot(*snapshotMarker) error
+}
+
+func newSnapshotMarker(origin subscription) *snapshotMarker {
+	return &snapshotMarker{origin: origin, resultch: make(chan snapshotResult, 1)}
+}
+
+func (m *snapshotMarker) Type() EventType {
+	return ""
+}
+
+func (m *snapshotMarker) Resource() metav1.Object {
+	return nil
+}
+
+func (m *snapshotMarker) DeepCopyResource() metav1.Object {
+	return nil
+}
+
+func (m *snapshotMarker) ObservedAt() time.Time {
+	return time.Time{}
+}
+
+func (m *snapshotMarker) String() string {
+	return "Event{snapshot}"
+}
+
+// pop() removes and returns the next subscription on the marker's route.
+func (m *snapshotMarker) pop() subscription {
+	if len(m.route) == 0 {
+		return nil
+	}
+	next := m.route[len(m.route)-1]
+	m.route = m.route[:len(m.route)-1]
+	return next
+}
+
+func (m *snapshotMarker) respond(list []metav1.Object, err error) {
+	select {
+	case m.resultch <- snapshotResult{list, err}:
+	default:
+	}
+}
+
+// fail() returns m directly to its origin with the given error.
+func (m *snapshotMarker) fail(err error) {
+	m.list, m.err, m.route = nil, err, nil
+	go m.origin.send(m)
+}
+
+// sendSnapshotFn() returns a function that delivers markers to ch until stopch is closed.
+func sendSnapshotFn(ch chan<- *snapshotMarker, stopch <-chan struct{}) func(*snapshotMarker) error {
+	return func(m *snapshotMarker) error {
+		select {
+		case ch <- m:
+			return nil
+		case <-stopch:
+			return errors.WithStack(ErrNotRunning)
+		}
+	}
+}
+
+// routeSnapshot() delivers m to the next subscription on its route, which
+// must be one of subscriptions.
+func routeSnapshot(m *snapshotMarker, subscriptions map[subscription]struct{}) {
+	next := m.pop()
+	if _, ok := subscriptions[next]; !ok {
+		m.fail(errors.WithStack(ErrNotRunning))
+		return
+	}
+	if err := next.send(m); err != nil {
+		m.fail(err)
+	}
+}
+
+func requestSnapshot(readych <-chan struct{}, snapshotch chan<- chan<- snapshotResult, stopch <-chan struct{}) ([]metav1.Object, error) {
+	select {
+	case <-readych:
+	case <-stopch:
+		return nil, errors.WithStack(ErrNotRunning)
+	}
+
+	resultch := make(chan snapshotResult, 1)
+
+	select {
+	case snapshotch <- resultch:
+		result := <-resultch
+		return result.list, result.err
+	case <-stopch:
+		return nil, errors.WithStack(ErrNotRunning)
+	}
+}
@@ -0,0 +1,138 @@
+package kcache
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a summary of a controller's state.
+type Stats struct {
+	// Objects is the number of objects in the controller's cache.
+	Objects int
+
+	// Subscribers is the number of active subscriptions to the controller
+	// and its clones.  Each clone is itself a subscription to the
+	// controller it was cloned from.
+	Subscribers int
+
+	// EventsPublished is the number of events published by the
+	// controller, by type.
+	EventsPublished map[EventType]uint64
+
+	// LastSync is the time that the controller last loaded a list into
+	// its cache, or zero if it has not.
+	LastSync time.Time
+
+	// QueueDepths is the number of events waiting to be read from the
+	// buffer of each active subscription of the controller and its
+	// clones, in decreasing order.  It includes the subscriptions that
+	// clones and filtered subscriptions read internally.  Use
+	// DebugHandler() to tell which subscription each depth belongs to.
+	QueueDepths []int
+}
+
+// statsRecorder maintains Stats for a controller and forwards
+// callbacks to the user's Metrics.
+type statsRecorder struct {
+	// accessed atomically; first for 64-bit alignment.
+	subscribers int64
+	created     uint64
+	updated     uint64
+	deleted     uint64
+	lastSync    int64
+
+	Metrics
+	cache cache
+
+	// event buffers of subscriptions sharing this recorder.
+	buffers map[*eventBuffer]struct{}
+	mtx     sync.Mutex
+}
+
+func newStatsRecorder(metrics Metrics, cache cache) *statsRecorder {
+	return &statsRecorder{
+		Metrics: metrics,
+		cache:   cache,
+		buffers: make(map[*eventBuffer]struct{}),
+	}
+}
+
+func (r *statsRecorder) EventPublished(et EventType) {
+	switch et {
+	case EventTypeCreate:
+		atomic.AddUint64(&r.created, 1)
+	case EventTypeUpdate:
+		atomic.AddUint64(&r.updated, 1)
+	case EventTypeDelete:
+		atomic.AddUint64(&r.deleted, 1)
+	}
+	r.Metrics.EventPublished(et)
+}
+
+func (r *statsRecorder) SubscriberAdded() {
+	atomic.AddInt64(&r.subscribers, 1)
+	r.Metrics.SubscriberAdded()
+}
+
+func (r *statsRecorder) SubscriberRemoved() {
+	atomic.AddInt64(&r.subscribers, -1)
+	r.Metrics.SubscriberRemoved()
+}
+
+// synced() records that a list was loaded into the cache at t.
+func (r *statsRecorder) synced(t time.Time) {
+	atomic.StoreInt64(&r.lastSync, t.UnixNano())
+}
+
+func (r *statsRecorder) stats() Stats {
+	stats := Stats{
+		Objects:     r.cache.count(),
+		Subscribers: int(atomic.LoadInt64(&r.subscribers)),
+		EventsPublished: map[EventType]uint64{
+			EventTypeCreate: atomic.LoadUint64(&r.created),
+			EventTypeUpdate: atomic.LoadUint64(&r.updated),
+			EventTypeDelete: atomic.LoadUint64(&r.deleted),
+		},
+	}
+	if nsec := atomic.LoadInt64(&r.lastSync); nsec != 0 {
+		stats.LastSync = time.Unix(0, nsec)
+	}
+	stats.QueueDepths = r.queueDepths()
+	return stats
+}
+
+// queueDepths() returns the number of events in each tracked buffer, in
+// decreasing order.
+func (r *statsRecorder) queueDepths() []int {
+	r.mtx.Lock()
+	depths := make([]int, 0, len(r.buffers))
+	for b := range r.buffers {
+		depths = append(depths, len(b.ch))
+	}
+	r.mtx.Unlock()
+
+	sort.Sort(sort.Reverse(sort.IntSlice(depths)))
+	return depths
+}
+
+func (r *statsRecorder) addBuffer(b *eventBuffer) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.buffers[b] = struct{}{}
+}
+
+func (r *statsRecorder) removeBuffer(b *eventBuffer) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	delete(r.buffers, b)
+}
+
+// statsOf() returns the stats recorded by metrics, if it records any.
+func statsOf(metrics Metrics) Stats {
+	if r, ok := metrics.(*statsRecorder); ok {
+		return r.stats()
+	}
+	return Stats{EventsPublished: make(map[EventType]uint64)}
+}
@@ -0,0 +1,89 @@
+package kcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/boz/kcache/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestController_Stats(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eventch := make(chan watch.Event, 3)
+	listch := make(chan time.Time, 1)
+
+	list := &v1.PodList{
+		ListMeta: metav1.ListMeta{ResourceVersion: "2"},
+		Items:    []v1.Pod{*testGenPod("ns", "a", "1"), *testGenPod("ns", "b", "2")},
+	}
+
+	controller := testNewController(t, testContext(ctx), testEvents(eventch), testList(list), testListWait(listch))
+	defer controller.Close()
+
+	// waits for cond to be true of the controller's stats.
+	waitFor := func(name string, cond func(Stats) bool) Stats {
+		timeout := testutil.Timerch(ctx, time.Second)
+		for {
+			stats := controller.Stats()
+			if cond(stats) {
+				return stats
+			}
+			select {
+			case <-time.After(time.Millisecond):
+			case <-timeout:
+				require.Fail(t, "timed out", "%v: %+v", name, stats)
+			}
+		}
+	}
+
+	stats := controller.Stats()
+	assert.Equal(t, 0, stats.Objects)
+	assert.Equal(t, 0, stats.Subscribers)
+	assert.True(t, stats.LastSync.IsZero())
+
+	sub, err := controller.Subscribe()
+	require.NoError(t, err)
+	clone, err := controller.Clone()
+	require.NoError(t, err)
+
+	before := time.Now()
+	listch <- time.Now()
+	testutil.AssertReady(t, "controller", controller)
+
+	stats = waitFor("synced", func(s Stats) bool { return s.Objects == 2 })
+	assert.False(t, stats.LastSync.Before(before))
+	assert.Equal(t, 2, stats.Subscribers)
+
+	eventch <- watch.Event{Type: watch.Added, Object: testGenPod("ns", "c", "3")}
+	eventch <- watch.Event{Type: watch.Modified, Object: testGenPod("ns", "a", "4")}
+	eventch <- watch.Event{Type: watch.Deleted, Object: testGenPod("ns", "b", "5")}
+
+	stats = waitFor("events", func(s Stats) bool { return s.EventsPublished[EventTypeDelete] == 1 })
+	assert.Equal(t, map[EventType]uint64{
+		EventTypeCreate: 1,
+		EventTypeUpdate: 1,
+		EventTypeDelete: 1,
+	}, stats.EventsPublished)
+	assert.Equal(t, 2, stats.Objects)
+
+	// the events wait in sub's buffer; the controller's and the clone's
+	// are read.
+	stats = waitFor("queued", func(s Stats) bool {
+		return len(s.QueueDepths) == 3 && s.QueueDepths[0] == 3 && s.QueueDepths[1] == 0
+	})
+	assert.Equal(t, []int{3, 0, 0}, stats.QueueDepths)
+
+	assert.Equal(t, stats, clone.Stats())
+
+	sub.Close()
+	testutil.AssertDone(t, "sub", sub)
+	waitFor("unsubscribed", func(s Stats) bool { return s.Subscribers == 1 })
+}
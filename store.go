@@ -0,0 +1,94 @@
+package kcache
+
+import (
+	builtin_errors "errors"
+
+	"github.com/pkg/errors"
+	toolscache "k8s.io/client-go/tools/cache"
+)
+
+var (
+	ErrReadOnly = builtin_errors.New("Read only")
+)
+
+// AsStore() returns a client-go store backed by the cache of c, which may
+// be a controller or a subscription, for libraries that consume a
+// toolscache.Store.  Keys are those of toolscache.MetaNamespaceKeyFunc.
+//
+// The store is read-only: Add(), Update(), Delete() and Replace() return
+// ErrReadOnly and Resync() does nothing.  Each call reads the cache as it
+// is at that moment, so a List() followed by Get() may observe different
+// versions of an object, and the store is empty until c is ready.
+// List() and ListKeys() return nothing once c has shut down.
+func AsStore(c CacheController) toolscache.Store {
+	return &store{c}
+}
+
+type store struct {
+	controller CacheController
+}
+
+func (s *store) Add(interface{}) error {
+	return errors.WithStack(ErrReadOnly)
+}
+
+func (s *store) Update(interface{}) error {
+	return errors.WithStack(ErrReadOnly)
+}
+
+func (s *store) Delete(interface{}) error {
+	return errors.WithStack(ErrReadOnly)
+}
+
+func (s *store) Replace([]interface{}, string) error {
+	return errors.WithStack(ErrReadOnly)
+}
+
+func (s *store) Resync() error {
+	return nil
+}
+
+func (s *store) List() []interface{} {
+	objs, err := s.controller.Cache().List()
+	if err != nil {
+		return nil
+	}
+	items := make([]interface{}, 0, len(objs))
+	for _, obj := range objs {
+		items = append(items, obj)
+	}
+	return items
+}
+
+func (s *store) ListKeys() []string {
+	objs, err := s.controller.Cache().List()
+	if err != nil {
+		return nil
+	}
+	keys := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		if key, err := toolscache.MetaNamespaceKeyFunc(obj); err == nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (s *store) Get(obj interface{}) (interface{}, bool, error) {
+	key, err := toolscache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return nil, false, err
+	}
+	return s.GetByKey(key)
+}
+
+func (s *store) GetByKey(key string) (interface{}, bool, error) {
+	obj, err := s.controller.Cache().GetByKey(key)
+	if IsNotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return obj, true, nil
+}
@@ -0,0 +1,68 @@
+package kcache
+
+import (
+	"sort"
+	"testing"
+
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/testutil"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+)
+
+func TestAsStore(t *testing.T) {
+	sub, cache, readych := testNewSubscription(t, logutil.Default(), filter.Null())
+
+	cache.update(testGenEvent(EventTypeCreate, "a", "x", "1"))
+	cache.update(testGenEvent(EventTypeCreate, "b", "y", "2"))
+	close(readych)
+	testutil.AssertReady(t, "sub", sub)
+
+	store := AsStore(sub)
+
+	assert.Len(t, store.List(), 2)
+
+	keys := store.ListKeys()
+	sort.Strings(keys)
+	assert.Equal(t, []string{"a/x", "b/y"}, keys)
+
+	item, exists, err := store.GetByKey("a/x")
+	require.NoError(t, err)
+	require.True(t, exists)
+	assert.Equal(t, "x", item.(metav1.Object).GetName())
+
+	item, exists, err = store.Get(testGenPod("b", "y", "3"))
+	require.NoError(t, err)
+	require.True(t, exists)
+	assert.Equal(t, "2", item.(metav1.Object).GetResourceVersion())
+
+	item, exists, err = store.Get(toolscache.DeletedFinalStateUnknown{Key: "b/y"})
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	item, exists, err = store.GetByKey("a/missing")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	assert.Nil(t, item)
+
+	obj := testGenPod("c", "z", "4")
+	assert.Equal(t, ErrReadOnly, errors.Cause(store.Add(obj)))
+	assert.Equal(t, ErrReadOnly, errors.Cause(store.Update(obj)))
+	assert.Equal(t, ErrReadOnly, errors.Cause(store.Delete(obj)))
+	assert.Equal(t, ErrReadOnly, errors.Cause(store.Replace([]interface{}{obj}, "4")))
+	assert.NoError(t, store.Resync())
+	assert.Len(t, store.List(), 2)
+
+	sub.Close()
+	testutil.AssertDone(t, "sub", sub)
+	testutil.AssertDone(t, "cache", cache)
+	assert.Empty(t, store.List())
+	assert.Empty(t, store.ListKeys())
+	_, exists, err = store.GetByKey("a/x")
+	assert.Error(t, err)
+	assert.False(t, exists)
+}
@@ -1,9 +1,15 @@
 package kcache
 
 import (
+	"context"
+	"time"
+
 	lifecycle "github.com/boz/go-lifecycle"
 	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/clock"
+	"github.com/boz/kcache/filter"
 	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
@@ -12,9 +18,76 @@ const (
 
 type Subscription interface {
 	CacheController
+
+	// Events() returns the subscription's events.
+	//
+	// Events for the same object are delivered in the order that the
+	// controller observed them.  Events may be missing from the sequence
+	// if they were dropped (see Dropped()), discarded by Snapshot(), or
+	// merged by a coalesced subscription.  There is no ordering guarantee
+	// between events for different objects.
+	//
+	// Events() is closed when the subscription shuts down, before Done()
+	// is closed.  Events that were buffered when the subscription shut
+	// down can still be read from Events() before it reports closed; no
+	// events are added once shutdown begins.
 	Events() <-chan Event
+
+	// EventsContext() returns Events() and closes the subscription
+	// when ctx is done.
+	EventsContext(ctx context.Context) <-chan Event
+
+	// EventBatches() reads Events() on behalf of the caller and delivers
+	// them, in order, in batches of at most maxCount events.  A batch is
+	// delivered once it is full or maxDelay after its first event was
+	// read, whichever comes first.  A maxCount that is not positive
+	// places no limit on the size of a batch; a maxDelay that is not
+	// positive delivers the events that are already buffered.
+	//
+	// Events() must not be read once EventBatches() has been called, and
+	// EventBatches() must be called at most once.  Events held in a batch
+	// that has not been delivered are not discarded by Snapshot().  The
+	// returned channel is closed after the final batch once Events() is
+	// closed.  Once Done() is closed a batch is dropped unless the caller
+	// is already waiting to receive it, so the final batches of a closed
+	// subscription may not be delivered.
+	EventBatches(maxCount int, maxDelay time.Duration) <-chan []Event
+
+	// Snapshot() returns the current contents of the subscription's cache.
+	//
+	// Snapshot() blocks until the subscription is ready.  Events that are
+	// reflected in the returned list are discarded, including those that
+	// were buffered but not yet read from Events(), so that events read
+	// after Snapshot() returns describe changes made after the snapshot
+	// was taken.
+	Snapshot() ([]metav1.Object, error)
+
+	// ResourceVersion() returns the resource version that the controller
+	// has processed up to.  It is the version of the list or watch, not of
+	// any particular object, and is updated before the corresponding
+	// events are delivered.
+	ResourceVersion() string
+
+	// Dropped() returns the number of events that were discarded
+	// because the subscription's buffer was full.
+	Dropped() uint64
+
+	// Filter() returns the filter that the subscription's cache and
+	// events are restricted to, including the filters of the controller
+	// or clone that created it.  It reflects the filter of the most
+	// recent Refilter() to return.  Filter() returns filter.Null() if no
+	// filter has been applied.
+	Filter() filter.Filter
+
+	// Close() initiates shutdown of the subscription; Done() is closed
+	// when it completes.  Close() may be called any number of times,
+	// from any goroutine.
 	Close()
 	Done() <-chan struct{}
+
+	// Error() returns the error that caused the subscription to
+	// shut down, or nil if it was closed cleanly.  It is set before
+	// Done() is closed.
 	Error() error
 }
 
@@ -24,48 +97,126 @@ type subscription interface {
 }
 
 type _subscription struct {
-	outch chan Event
-	inch  chan Event
+	buffer *eventBuffer
+	inch   chan Event
+
+	snapshotfn func(*snapshotMarker) error
+	snapshotch chan *snapshotMarker
+
+	versionfn func() string
+	filterfn  func() filter.Filter
 
 	readych <-chan struct{}
 
+	// true if the subscription's events are read by another
+	// subscription or publisher rather than by the user.
+	relay bool
+
+	clock clock.Clock
 	cache CacheReader
 
 	log logutil.Log
 	lc  lifecycle.Lifecycle
 }
 
-func newSubscription(log logutil.Log, stopch <-chan struct{}, readych <-chan struct{}, cache CacheReader) subscription {
+// newSubscription() returns a subscription that shuts down when stopch
+// is closed.  If errfn is not nil, its result is used as the subscription's
+// error when shutting down due to stopch.
+//
+// snapshotfn passes snapshot markers to the owner of the cache, which
+// must send them back through send() after listing the cache.  If snapshotfn
+// is nil the subscription lists the cache itself.
+//
+// versionfn returns the resource version of the owner of the cache, and
+// filterfn the filter of its contents.  Either may be nil.
+func newSubscription(log logutil.Log, stopch <-chan struct{}, errfn func() error, snapshotfn func(*snapshotMarker) error, readych <-chan struct{}, cache CacheReader) subscription {
+	return newBufferedSubscription(log, stopch, errfn, snapshotfn, nil, nil, readych, cache, EventBufsiz, OverflowDropNewest, false, clock.Real(), nullMetrics{})
+}
+
+// newBufferedSubscription() is like newSubscription() but buffers size
+// events according to policy.  If relay is true, flush markers are
+// buffered for the reader of the subscription's events; otherwise the
+// subscription waits for the events ahead of a marker to be read.
+// EventBatches() is timed by clock.
+func newBufferedSubscription(log logutil.Log, stopch <-chan struct{}, errfn func() error, snapshotfn func(*snapshotMarker) error, versionfn func() string, filterfn func() filter.Filter, readych <-chan struct{}, cache CacheReader, size int, policy OverflowPolicy, relay bool, clock clock.Clock, metrics Metrics) subscription {
 	log = log.WithComponent("subscription")
 
 	lc := lifecycle.New()
 	s := &_subscription{
-		readych: readych,
-		inch:    make(chan Event),
-		outch:   make(chan Event, EventBufsiz),
-		cache:   cache,
-		log:     log,
-		lc:      lc,
+		readych:    readych,
+		inch:       make(chan Event),
+		buffer:     newEventBuffer(log, size, policy, metrics),
+		snapshotfn: snapshotfn,
+		snapshotch: make(chan *snapshotMarker),
+		versionfn:  versionfn,
+		filterfn:   filterfn,
+		relay:      relay,
+		clock:      clock,
+		cache:      cache,
+		log:        log,
+		lc:         lc,
 	}
 
-	go s.lc.WatchChannel(stopch)
+	go s.watchParent(stopch, errfn)
 
 	go s.run()
 	return s
 }
 
+func (s *_subscription) watchParent(stopch <-chan struct{}, errfn func() error) {
+	select {
+	case <-stopch:
+		var err error
+		if errfn != nil {
+			err = errfn()
+		}
+		s.lc.ShutdownAsync(err)
+	case <-s.lc.ShuttingDown():
+	}
+}
+
 func (s *_subscription) Ready() <-chan struct{} {
 	return s.readych
 }
 
 func (s *_subscription) Events() <-chan Event {
-	return s.outch
+	return s.buffer.ch
+}
+
+func (s *_subscription) EventsContext(ctx context.Context) <-chan Event {
+	return eventsContext(ctx, s)
+}
+
+func (s *_subscription) EventBatches(maxCount int, maxDelay time.Duration) <-chan []Event {
+	return eventBatches(s, maxCount, maxDelay, s.clock)
+}
+
+func (s *_subscription) ResourceVersion() string {
+	if s.versionfn == nil {
+		return ""
+	}
+	return s.versionfn()
+}
+
+func (s *_subscription) Filter() filter.Filter {
+	if s.filterfn == nil {
+		return filter.Null()
+	}
+	return s.filterfn()
+}
+
+func (s *_subscription) Dropped() uint64 {
+	return s.buffer.Dropped()
 }
 
 func (s *_subscription) Cache() CacheReader {
 	return s.cache
 }
 
+func (s *_subscription) AddIndex(name string, fn IndexFunc) error {
+	return addCacheIndex(s.cache, name, fn)
+}
+
 func (s *_subscription) Close() {
 	s.lc.ShutdownAsync(nil)
 }
@@ -78,6 +229,42 @@ func (s *_subscription) Error() error {
 	return s.lc.Error()
 }
 
+func (s *_subscription) Snapshot() ([]metav1.Object, error) {
+	select {
+	case <-s.readych:
+	case <-s.lc.ShuttingDown():
+		return nil, errors.WithStack(ErrNotRunning)
+	}
+
+	m := newSnapshotMarker(s)
+
+	select {
+	case s.snapshotch <- m:
+	case <-s.lc.ShuttingDown():
+		return nil, errors.WithStack(ErrNotRunning)
+	}
+
+	select {
+	case result := <-m.resultch:
+		return result.list, result.err
+	case <-s.lc.ShuttingDown():
+		return nil, errors.WithStack(ErrNotRunning)
+	}
+}
+
+func (s *_subscription) markSnapshot(m *snapshotMarker) error {
+	if s.snapshotfn == nil {
+		m.list, m.err = s.cache.List()
+		return s.send(m)
+	}
+	m.route = append(m.route, s)
+	return s.snapshotfn(m)
+}
+
+func (s *_subscription) markFlush(m *flushMarker) error {
+	return s.send(m)
+}
+
 func (s *_subscription) send(ev Event) error {
 	select {
 	case s.inch <- ev:
@@ -89,7 +276,10 @@ func (s *_subscription) send(ev Event) error {
 
 func (s *_subscription) run() {
 	defer s.lc.ShutdownCompleted()
-	defer close(s.outch)
+	defer s.buffer.close()
+
+	// number of snapshots whose markers have not yet arrived.
+	pending := 0
 
 	for {
 		select {
@@ -97,12 +287,106 @@ func (s *_subscription) run() {
 			s.log.Debugf("shutdown requested: %v", err)
 			s.lc.ShutdownInitiated(err)
 			return
+
+		case m := <-s.snapshotch:
+			pending++
+			s.startSnapshot(m)
+
 		case evt := <-s.inch:
-			select {
-			case s.outch <- evt:
-			default:
-				s.log.Warnf("event buffer overrun")
+			if m, ok := evt.(*snapshotMarker); ok {
+				if len(m.route) == 0 {
+					// the marker for one of our snapshots.
+					pending--
+					m.respond(m.list, m.err)
+					continue
+				}
+				// markers for downstream subscriptions are never dropped.
+				if !s.deliver(m, &pending) {
+					return
+				}
+				continue
+			}
+
+			if m, ok := evt.(*flushMarker); ok {
+				if !s.flush(m, &pending) {
+					return
+				}
+				continue
+			}
+
+			if pending > 0 {
+				// reflected in a pending snapshot.
+				continue
+			}
+
+			if s.buffer.offer(evt) {
+				continue
+			}
+
+			if !s.deliver(evt, &pending) {
+				return
 			}
 		}
 	}
 }
+
+// deliver() blocks until evt is buffered or the subscription is shut down.
+// deliver() returns false if the subscription is shutting down.
+func (s *_subscription) deliver(evt Event, pending *int) bool {
+	marker := isMarker(evt)
+	for {
+		select {
+		case s.buffer.ch <- evt:
+			return true
+		case m := <-s.snapshotch:
+			*pending = *pending + 1
+			s.startSnapshot(m)
+			if !marker {
+				// evt is reflected in the snapshot.
+				return true
+			}
+		case err := <-s.lc.ShutdownRequest():
+			s.log.Debugf("shutdown requested: %v", err)
+			s.lc.ShutdownInitiated(err)
+			return false
+		}
+	}
+}
+
+// flush() passes m to the reader of the subscription's events, or
+// releases its claim once the reader has read the events ahead of it.
+// flush() returns false if the subscription is shutting down.
+func (s *_subscription) flush(m *flushMarker, pending *int) bool {
+	if !s.relay {
+		m.awaitRead(s.buffer.ch, s.lc.ShuttingDown())
+		return true
+	}
+	if !s.deliver(m, pending) {
+		m.done()
+		return false
+	}
+	return true
+}
+
+// startSnapshot() discards buffered events and requests a marker for
+// the snapshot.  Events are discarded until the marker arrives.
+func (s *_subscription) startSnapshot(m *snapshotMarker) {
+	s.log.Debugf("snapshot: discarded %v events", s.buffer.drain())
+	go func() {
+		if err := s.markSnapshot(m); err != nil {
+			m.fail(err)
+		}
+	}()
+}
+
+// eventsContext() closes sub when ctx is done and returns its events.
+func eventsContext(ctx context.Context, sub Subscription) <-chan Event {
+	go func() {
+		select {
+		case <-ctx.Done():
+			sub.Close()
+		case <-sub.Done():
+		}
+	}()
+	return sub.Events()
+}
@@ -0,0 +1,102 @@
+package kcache
+
+import (
+	"time"
+
+	"github.com/boz/kcache/clock"
+)
+
+// eventBatches() reads the events of sub and delivers them in batches of
+// at most maxCount events, each sent no later than maxDelay, as timed by
+// clock, after its first event was read.  The returned channel is closed
+// after the events of sub are closed and the final batch is delivered.
+//
+// Once sub is done, a batch is delivered only if it is already being
+// received; otherwise it is dropped, so that a reader that abandons the
+// channel after Close() doesn't leave its goroutine blocked.
+func eventBatches(sub Subscription, maxCount int, maxDelay time.Duration, clock clock.Clock) <-chan []Event {
+	outch := make(chan []Event)
+
+	go func() {
+		defer close(outch)
+
+		inch := sub.Events()
+
+		for {
+			evt, ok := <-inch
+			if !ok {
+				return
+			}
+
+			batch := []Event{evt}
+			open := fillBatch(inch, &batch, maxCount, maxDelay, clock)
+
+			sendBatch(outch, batch, sub.Done())
+
+			if !open {
+				return
+			}
+		}
+	}()
+
+	return outch
+}
+
+// sendBatch() sends batch on outch.  If donech is closed first, batch is
+// sent only if a receiver is waiting.  sendBatch() returns false if batch
+// was dropped.
+func sendBatch(outch chan<- []Event, batch []Event, donech <-chan struct{}) bool {
+	select {
+	case outch <- batch:
+		return true
+	case <-donech:
+	}
+
+	select {
+	case outch <- batch:
+		return true
+	default:
+		return false
+	}
+}
+
+// fillBatch() appends events from inch to batch until it holds maxCount
+// events or maxDelay elapses.  If maxDelay is not positive only events
+// that are already buffered are appended.  fillBatch() returns false if
+// inch is closed.
+func fillBatch(inch <-chan Event, batch *[]Event, maxCount int, maxDelay time.Duration, clock clock.Clock) bool {
+	full := func() bool {
+		return maxCount > 0 && len(*batch) >= maxCount
+	}
+
+	if maxDelay <= 0 {
+		for !full() {
+			select {
+			case evt, ok := <-inch:
+				if !ok {
+					return false
+				}
+				*batch = append(*batch, evt)
+			default:
+				return true
+			}
+		}
+		return true
+	}
+
+	timer := clock.NewTimer(maxDelay)
+	defer timer.Stop()
+
+	for !full() {
+		select {
+		case evt, ok := <-inch:
+			if !ok {
+				return false
+			}
+			*batch = append(*batch, evt)
+		case <-timer.C():
+			return true
+		}
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+package kcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscription_EventBatches(t *testing.T) {
+
+	names := func(batch []Event) []string {
+		var names []string
+		for _, evt := range batch {
+			names = append(names, evt.Resource().GetName())
+		}
+		return names
+	}
+
+	read := func(t *testing.T, ch <-chan []Event, timeout time.Duration) []string {
+		select {
+		case batch, ok := <-ch:
+			require.True(t, ok, "batches closed")
+			return names(batch)
+		case <-testutil.Timerch(context.Background(), timeout):
+			require.Fail(t, "no batch")
+			return nil
+		}
+	}
+
+	assertNone := func(t *testing.T, ch <-chan []Event) {
+		select {
+		case batch := <-ch:
+			assert.Fail(t, "unexpected batch", "%v", names(batch))
+		case <-testutil.AsyncWaitch(context.Background()):
+		}
+	}
+
+	send := func(sub subscription, names ...string) {
+		for _, name := range names {
+			sub.send(testGenEvent(EventTypeCreate, "ns", name, "1"))
+		}
+	}
+
+	t.Run("count", func(t *testing.T) {
+		sub, _, _ := testNewSubscription(t, logutil.Default(), filter.Null())
+		defer sub.Close()
+
+		batches := sub.EventBatches(2, time.Minute)
+
+		send(sub, "a", "b", "c")
+		assert.Equal(t, []string{"a", "b"}, read(t, batches, time.Second))
+		assertNone(t, batches)
+
+		send(sub, "d")
+		assert.Equal(t, []string{"c", "d"}, read(t, batches, time.Second))
+	})
+
+	t.Run("delay", func(t *testing.T) {
+		sub, _, _ := testNewSubscription(t, logutil.Default(), filter.Null())
+		defer sub.Close()
+
+		batches := sub.EventBatches(10, 50*time.Millisecond)
+
+		send(sub, "a", "b")
+		assert.Equal(t, []string{"a", "b"}, read(t, batches, time.Second))
+
+		send(sub, "c")
+		assert.Equal(t, []string{"c"}, read(t, batches, time.Second))
+	})
+
+	t.Run("buffered", func(t *testing.T) {
+		sub, _, _ := testNewSubscription(t, logutil.Default(), filter.Null())
+		defer sub.Close()
+
+		send(sub, "a", "b", "c")
+
+		// wait until every event is buffered.
+		timeout := testutil.Timerch(context.Background(), time.Second)
+		for len(sub.Events()) < 3 {
+			select {
+			case <-time.After(time.Millisecond):
+			case <-timeout:
+				require.Fail(t, "events not buffered")
+			}
+		}
+
+		batches := sub.EventBatches(0, 0)
+		assert.Equal(t, []string{"a", "b", "c"}, read(t, batches, time.Second))
+	})
+
+	t.Run("close", func(t *testing.T) {
+		sub, _, _ := testNewSubscription(t, logutil.Default(), filter.Null())
+
+		batches := sub.EventBatches(10, time.Minute)
+
+		send(sub, "a")
+
+		// closed while the batch is being received.
+		go func() {
+			<-testutil.AsyncWaitch(context.Background())
+			sub.Close()
+		}()
+
+		assert.Equal(t, []string{"a"}, read(t, batches, time.Second))
+
+		select {
+		case _, ok := <-batches:
+			assert.False(t, ok)
+		case <-testutil.Timerch(context.Background(), time.Second):
+			require.Fail(t, "batches not closed")
+		}
+	})
+
+	t.Run("abandoned", func(t *testing.T) {
+		sub, _, _ := testNewSubscription(t, logutil.Default(), filter.Null())
+
+		batches := sub.EventBatches(1, time.Minute)
+
+		send(sub, "a", "b", "c")
+		sub.Close()
+		testutil.AssertDone(t, "sub", sub)
+
+		// batches not being received once the subscription is done are
+		// dropped rather than blocking delivery.
+		<-testutil.AsyncWaitch(context.Background())
+
+		select {
+		case batch, ok := <-batches:
+			assert.False(t, ok, "unexpected batch: %v", names(batch))
+		case <-testutil.Timerch(context.Background(), time.Second):
+			require.Fail(t, "batches not closed")
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package kcache
+
+import (
+	"sync/atomic"
+
+	logutil "github.com/boz/go-logutil"
+)
+
+// OverflowPolicy determines what a subscription does with
+// an event when its buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDropNewest discards the incoming event.
+	OverflowDropNewest OverflowPolicy = iota
+
+	// OverflowDropOldest discards the oldest buffered event
+	// to make room for the incoming event.
+	OverflowDropOldest
+
+	// OverflowBlock waits until there is room in the buffer.
+	// A slow subscriber will delay event delivery for all other
+	// subscribers of the same publisher.
+	OverflowBlock
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowDropNewest:
+		return "drop-newest"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowBlock:
+		return "block"
+	default:
+		return "unknown"
+	}
+}
+
+// bufferTracker is implemented by metrics that keep track of the
+// event buffers created with them.
+type bufferTracker interface {
+	addBuffer(*eventBuffer)
+	removeBuffer(*eventBuffer)
+}
+
+type eventBuffer struct {
+	// accessed atomically; first for 64-bit alignment.
+	dropped uint64
+
+	ch      chan Event
+	policy  OverflowPolicy
+	metrics Metrics
+	log     logutil.Log
+}
+
+func newEventBuffer(log logutil.Log, size int, policy OverflowPolicy, metrics Metrics) *eventBuffer {
+	b := &eventBuffer{
+		ch:      make(chan Event, size),
+		policy:  policy,
+		metrics: metrics,
+		log:     log,
+	}
+	if t, ok := metrics.(bufferTracker); ok {
+		t.addBuffer(b)
+	}
+	return b
+}
+
+// close() closes ch.  Events buffered in ch can still be read.
+func (b *eventBuffer) close() {
+	if t, ok := b.metrics.(bufferTracker); ok {
+		t.removeBuffer(b)
+	}
+	close(b.ch)
+}
+
+// offer() buffers evt according to the overflow policy.
+//
+// offer() returns false if the buffer is full and the policy is
+// OverflowBlock; the caller is responsible for waiting to send on ch.
+func (b *eventBuffer) offer(evt Event) bool {
+	select {
+	case b.ch <- evt:
+		return true
+	default:
+	}
+
+	switch b.policy {
+	case OverflowBlock:
+		return false
+
+	case OverflowDropOldest:
+		select {
+		case <-b.ch:
+			atomic.AddUint64(&b.dropped, 1)
+			b.metrics.EventDropped()
+			b.log.Warnf("event buffer overrun: dropped oldest event")
+		default:
+		}
+		select {
+		case b.ch <- evt:
+			return true
+		default:
+		}
+	}
+
+	atomic.AddUint64(&b.dropped, 1)
+	b.metrics.EventDropped()
+	b.log.Warnf("event buffer overrun")
+	return true
+}
+
+// drain() discards buffered events and returns the number discarded.
+// Markers are kept, in order.  drain() must be called by the goroutine
+// that sends on ch.
+func (b *eventBuffer) drain() int {
+	count := 0
+	var markers []Event
+	for {
+		select {
+		case evt := <-b.ch:
+			if isMarker(evt) {
+				markers = append(markers, evt)
+				continue
+			}
+			count++
+		default:
+			for _, m := range markers {
+				b.ch <- m
+			}
+			return count
+		}
+	}
+}
+
+func (b *eventBuffer) Dropped() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
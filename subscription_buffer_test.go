@@ -0,0 +1,188 @@
+package kcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/clock"
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventBuffer(t *testing.T) {
+	log := logutil.Default()
+
+	e1 := testGenEvent(EventTypeCreate, "a", "1", "1")
+	e2 := testGenEvent(EventTypeCreate, "a", "2", "2")
+	e3 := testGenEvent(EventTypeCreate, "a", "3", "3")
+
+	{
+		b := newEventBuffer(log, 2, OverflowDropNewest, nullMetrics{})
+		assert.True(t, b.offer(e1))
+		assert.True(t, b.offer(e2))
+		assert.True(t, b.offer(e3))
+		assert.Equal(t, uint64(1), b.Dropped())
+		assert.Equal(t, e1, <-b.ch)
+		assert.Equal(t, e2, <-b.ch)
+	}
+
+	{
+		b := newEventBuffer(log, 2, OverflowDropOldest, nullMetrics{})
+		assert.True(t, b.offer(e1))
+		assert.True(t, b.offer(e2))
+		assert.True(t, b.offer(e3))
+		assert.Equal(t, uint64(1), b.Dropped())
+		assert.Equal(t, e2, <-b.ch)
+		assert.Equal(t, e3, <-b.ch)
+	}
+
+	{
+		b := newEventBuffer(log, 2, OverflowBlock, nullMetrics{})
+		assert.True(t, b.offer(e1))
+		assert.True(t, b.offer(e2))
+		assert.False(t, b.offer(e3))
+		assert.Equal(t, uint64(0), b.Dropped())
+		assert.Equal(t, 2, b.drain())
+		assert.Equal(t, uint64(0), b.Dropped())
+	}
+}
+
+func TestSubscription_block(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log := logutil.Default()
+	cache := newCache(ctx, log, nil, filter.Null())
+	sub := newBufferedSubscription(log, nil, nil, nil, nil, nil, nil, cache, 1, OverflowBlock, false, clock.Real(), nullMetrics{})
+	defer sub.Close()
+
+	events := []Event{
+		testGenEvent(EventTypeCreate, "a", "1", "1"),
+		testGenEvent(EventTypeCreate, "a", "2", "2"),
+		testGenEvent(EventTypeCreate, "a", "3", "3"),
+	}
+
+	sentch := make(chan struct{})
+	go func() {
+		defer close(sentch)
+		for _, evt := range events {
+			sub.send(evt)
+		}
+	}()
+
+	select {
+	case <-sentch:
+		assert.Fail(t, "send did not block")
+	case <-testutil.AsyncWaitch(ctx):
+	}
+
+	for _, evt := range events {
+		select {
+		case ev := <-sub.Events():
+			assert.Equal(t, evt, ev)
+		case <-testutil.AsyncWaitch(ctx):
+			assert.Fail(t, "missing event")
+		}
+	}
+
+	<-sentch
+	assert.Equal(t, uint64(0), sub.Dropped())
+
+	// snapshot while blocked
+	readych := make(chan struct{})
+	close(readych)
+	bsub := newBufferedSubscription(log, nil, nil, nil, nil, nil, readych, cache, 1, OverflowBlock, false, clock.Real(), nullMetrics{})
+	defer bsub.Close()
+
+	bsub.send(testGenEvent(EventTypeCreate, "a", "1", "1"))
+	bsub.send(testGenEvent(EventTypeCreate, "a", "2", "2"))
+
+	snapshotch := make(chan struct{})
+	go func() {
+		defer close(snapshotch)
+		_, err := bsub.Snapshot()
+		assert.NoError(t, err)
+	}()
+
+	select {
+	case <-snapshotch:
+	case <-testutil.Timerch(ctx, time.Second):
+		assert.Fail(t, "snapshot blocked")
+	}
+
+	select {
+	case ev := <-bsub.Events():
+		assert.Fail(t, "event reflected in snapshot was delivered", "%v", ev)
+	case <-testutil.AsyncWaitch(ctx):
+	}
+
+	// shutdown while blocked
+	sub.send(testGenEvent(EventTypeCreate, "a", "4", "4"))
+	sub.send(testGenEvent(EventTypeCreate, "a", "5", "5"))
+	sub.Close()
+	testutil.AssertDone(t, "sub", sub)
+}
+
+func TestPublisher_SubscribeWithBuffer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log := logutil.Default()
+	parent, _, readych := testNewSubscription(t, log, filter.Null())
+	publisher := newPublisher(log, parent, clock.Real(), nullMetrics{})
+	defer parent.Close()
+
+	close(readych)
+
+	_, err := publisher.SubscribeWithBuffer(0, OverflowDropOldest)
+	assert.Error(t, err)
+
+	sub, err := publisher.SubscribeWithBuffer(2, OverflowDropOldest)
+	require.NoError(t, err)
+
+	other, err := publisher.Subscribe()
+	require.NoError(t, err)
+
+	for _, name := range []string{"1", "2", "3", "4"} {
+		parent.send(testGenEvent(EventTypeCreate, "a", name, name))
+	}
+
+	for _, name := range []string{"1", "2", "3", "4"} {
+		select {
+		case ev := <-other.Events():
+			assert.Equal(t, name, ev.Resource().GetName())
+		case <-testutil.AsyncWaitch(ctx):
+			assert.Fail(t, "missing event")
+		}
+	}
+
+	// delivery order across subscribers is unspecified; wait for sub
+	// to have seen all four events before inspecting its buffer.
+	waitch := testutil.Timerch(ctx, time.Second)
+	for sub.Dropped() < 2 {
+		select {
+		case <-waitch:
+			require.Fail(t, "events not dropped", "dropped: %v", sub.Dropped())
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	for _, name := range []string{"3", "4"} {
+		select {
+		case ev := <-sub.Events():
+			assert.Equal(t, name, ev.Resource().GetName())
+		case <-testutil.AsyncWaitch(ctx):
+			assert.Fail(t, "missing event")
+		}
+	}
+
+	assert.Equal(t, uint64(2), sub.Dropped())
+	assert.Equal(t, uint64(0), other.Dropped())
+
+	publisher.Close()
+	testutil.AssertDone(t, "publisher", publisher)
+}
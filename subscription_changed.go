@@ -0,0 +1,174 @@
+package kcache
+
+import (
+	"context"
+	"time"
+
+	lifecycle "github.com/boz/go-lifecycle"
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/clock"
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/nsname"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type changedSubscription struct {
+	parent  Subscription
+	changed filter.ChangeFunc
+	clock   clock.Clock
+
+	snapshotch chan chan<- snapshotResult
+	buffer     *eventBuffer
+
+	// the last object delivered for each key.
+	delivered map[nsname.NSName]metav1.Object
+
+	lc  lifecycle.Lifecycle
+	log logutil.Log
+}
+
+// newChangedSubscription() returns a subscription that delivers
+// update events from parent only if changed() reports a difference
+// from the last object delivered for the same key.
+func newChangedSubscription(log logutil.Log, parent Subscription, changed filter.ChangeFunc, clock clock.Clock, metrics Metrics) Subscription {
+	log = log.WithComponent("subscription-changed")
+	s := &changedSubscription{
+		parent:     parent,
+		changed:    changed,
+		clock:      clock,
+		snapshotch: make(chan chan<- snapshotResult),
+		buffer:     newEventBuffer(log, EventBufsiz, OverflowDropNewest, metrics),
+		delivered:  make(map[nsname.NSName]metav1.Object),
+		lc:         lifecycle.New(),
+		log:        log,
+	}
+
+	go s.run()
+
+	return s
+}
+
+func (s *changedSubscription) Cache() CacheReader {
+	return s.parent.Cache()
+}
+
+func (s *changedSubscription) ResourceVersion() string {
+	return s.parent.ResourceVersion()
+}
+
+func (s *changedSubscription) AddIndex(name string, fn IndexFunc) error {
+	return s.parent.AddIndex(name, fn)
+}
+
+func (s *changedSubscription) Ready() <-chan struct{} {
+	return s.parent.Ready()
+}
+
+func (s *changedSubscription) Events() <-chan Event {
+	return s.buffer.ch
+}
+
+func (s *changedSubscription) EventsContext(ctx context.Context) <-chan Event {
+	return eventsContext(ctx, s)
+}
+
+func (s *changedSubscription) EventBatches(maxCount int, maxDelay time.Duration) <-chan []Event {
+	return eventBatches(s, maxCount, maxDelay, s.clock)
+}
+
+func (s *changedSubscription) Filter() filter.Filter {
+	return s.parent.Filter()
+}
+
+func (s *changedSubscription) Dropped() uint64 {
+	return s.buffer.Dropped()
+}
+
+func (s *changedSubscription) Snapshot() ([]metav1.Object, error) {
+	return requestSnapshot(s.parent.Ready(), s.snapshotch, s.lc.ShuttingDown())
+}
+
+func (s *changedSubscription) Close() {
+	s.parent.Close()
+}
+
+func (s *changedSubscription) Done() <-chan struct{} {
+	return s.lc.Done()
+}
+
+func (s *changedSubscription) Error() error {
+	if err := s.lc.Error(); err != nil {
+		return err
+	}
+	return s.parent.Error()
+}
+
+func (s *changedSubscription) run() {
+	defer s.lc.ShutdownCompleted()
+
+loop:
+	for {
+		select {
+		case err := <-s.lc.ShutdownRequest():
+			s.log.Debugf("shutdown requested: %v", err)
+			s.lc.ShutdownInitiated(err)
+			break loop
+
+		case evt, ok := <-s.parent.Events():
+			if !ok {
+				s.log.Debugf("update: parent closed")
+				s.lc.ShutdownInitiated(nil)
+				break loop
+			}
+
+			if m, ok := evt.(*flushMarker); ok {
+				m.awaitRead(s.buffer.ch, s.lc.ShuttingDown())
+				continue
+			}
+
+			if s.accept(evt) {
+				s.buffer.offer(evt)
+			}
+
+		case resultch := <-s.snapshotch:
+			discarded := s.buffer.drain()
+			s.log.Debugf("snapshot: discarded %v events", discarded)
+
+			list, err := s.parent.Snapshot()
+
+			// later updates are compared with the snapshot.
+			s.delivered = make(map[nsname.NSName]metav1.Object, len(list))
+			for _, obj := range list {
+				s.delivered[nsname.ForObject(obj)] = obj
+			}
+
+			resultch <- snapshotResult{list, err}
+		}
+	}
+
+	s.parent.Close()
+
+	s.buffer.close()
+
+	<-s.parent.Done()
+	releaseFlushes(s.parent.Events())
+}
+
+// accept() returns true if evt should be delivered and records
+// its object as delivered.
+func (s *changedSubscription) accept(evt Event) bool {
+	key := nsname.ForObject(evt.Resource())
+
+	switch evt.Type() {
+	case EventTypeDelete:
+		delete(s.delivered, key)
+		return true
+	case EventTypeUpdate:
+		if prev, ok := s.delivered[key]; ok && !s.changed(prev, evt.Resource()) {
+			return false
+		}
+	}
+
+	s.delivered[key] = evt.Resource()
+	return true
+}
@@ -0,0 +1,109 @@
+package kcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/clock"
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestChangedSubscription(t *testing.T) {
+	genEvent := func(et EventType, name, vsn, phase string) Event {
+		pod := testGenPod("ns", name, vsn)
+		pod.Labels = map[string]string{"phase": phase}
+		return NewEvent(et, pod)
+	}
+
+	changed := filter.Changed(func(obj metav1.Object) interface{} {
+		return obj.GetLabels()["phase"]
+	})
+
+	newSub := func(t *testing.T) (subscription, cache, Subscription) {
+		log := logutil.Default()
+		parent, cache, readych := testNewSubscription(t, log, filter.Null())
+		sub := newChangedSubscription(log, parent, changed, clock.Real(), nullMetrics{})
+		close(readych)
+		testutil.AssertReady(t, "sub", sub)
+		return parent, cache, sub
+	}
+
+	type result struct {
+		et  EventType
+		vsn string
+	}
+
+	read := func(t *testing.T, ctx context.Context, sub Subscription) []result {
+		var results []result
+		for {
+			select {
+			case evt, ok := <-sub.Events():
+				require.True(t, ok)
+				results = append(results, result{evt.Type(), evt.Resource().GetResourceVersion()})
+			case <-testutil.Timerch(ctx, 100*time.Millisecond):
+				return results
+			}
+		}
+	}
+
+	t.Run("events", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		parent, _, sub := newSub(t)
+		defer parent.Close()
+
+		parent.send(genEvent(EventTypeCreate, "a", "1", "pending"))
+		parent.send(genEvent(EventTypeUpdate, "a", "2", "pending"))
+		parent.send(genEvent(EventTypeUpdate, "a", "3", "running"))
+
+		// not previously delivered.
+		parent.send(genEvent(EventTypeUpdate, "b", "4", "running"))
+
+		// resync.
+		parent.send(resyncEvent{genEvent(EventTypeUpdate, "b", "4", "running")})
+
+		parent.send(genEvent(EventTypeDelete, "a", "5", "running"))
+		parent.send(genEvent(EventTypeUpdate, "a", "6", "running"))
+
+		assert.Equal(t, []result{
+			{EventTypeCreate, "1"},
+			{EventTypeUpdate, "3"},
+			{EventTypeUpdate, "4"},
+			{EventTypeDelete, "5"},
+			{EventTypeUpdate, "6"},
+		}, read(t, ctx, sub))
+
+		parent.Close()
+		testutil.AssertDone(t, "sub", sub)
+		_, ok := <-sub.Events()
+		assert.False(t, ok)
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		parent, cache, sub := newSub(t)
+		defer parent.Close()
+
+		evt := genEvent(EventTypeCreate, "a", "1", "pending")
+		cache.update(evt)
+		parent.send(evt)
+
+		list, err := sub.Snapshot()
+		require.NoError(t, err)
+		assert.Len(t, list, 1)
+
+		parent.send(genEvent(EventTypeUpdate, "a", "2", "pending"))
+		parent.send(genEvent(EventTypeUpdate, "a", "3", "running"))
+
+		assert.Equal(t, []result{{EventTypeUpdate, "3"}}, read(t, ctx, sub))
+	})
+}
@@ -0,0 +1,256 @@
+package kcache
+
+import (
+	"context"
+	"time"
+
+	lifecycle "github.com/boz/go-lifecycle"
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/clock"
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/nsname"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type coalescedSubscription struct {
+	parent Subscription
+	window time.Duration
+	clock  clock.Clock
+
+	snapshotch chan chan<- snapshotResult
+	buffer     *eventBuffer
+
+	pending coalescedEvents
+
+	lc  lifecycle.Lifecycle
+	log logutil.Log
+}
+
+// newCoalescedSubscription() returns a subscription that buffers
+// events from parent for window and then emits the latest event
+// for each object.  The window starts when the first event is buffered.
+func newCoalescedSubscription(log logutil.Log, parent Subscription, window time.Duration, clock clock.Clock, metrics Metrics) Subscription {
+	log = log.WithComponent("subscription-coalesced")
+	s := &coalescedSubscription{
+		parent:     parent,
+		window:     window,
+		clock:      clock,
+		snapshotch: make(chan chan<- snapshotResult),
+		buffer:     newEventBuffer(log, EventBufsiz, OverflowDropNewest, metrics),
+		pending:    newCoalescedEvents(),
+		lc:         lifecycle.New(),
+		log:        log,
+	}
+
+	go s.run()
+
+	return s
+}
+
+func (s *coalescedSubscription) Cache() CacheReader {
+	return s.parent.Cache()
+}
+
+func (s *coalescedSubscription) ResourceVersion() string {
+	return s.parent.ResourceVersion()
+}
+
+func (s *coalescedSubscription) AddIndex(name string, fn IndexFunc) error {
+	return s.parent.AddIndex(name, fn)
+}
+
+func (s *coalescedSubscription) Ready() <-chan struct{} {
+	return s.parent.Ready()
+}
+
+func (s *coalescedSubscription) Events() <-chan Event {
+	return s.buffer.ch
+}
+
+func (s *coalescedSubscription) EventsContext(ctx context.Context) <-chan Event {
+	return eventsContext(ctx, s)
+}
+
+func (s *coalescedSubscription) EventBatches(maxCount int, maxDelay time.Duration) <-chan []Event {
+	return eventBatches(s, maxCount, maxDelay, s.clock)
+}
+
+func (s *coalescedSubscription) Filter() filter.Filter {
+	return s.parent.Filter()
+}
+
+func (s *coalescedSubscription) Dropped() uint64 {
+	return s.buffer.Dropped()
+}
+
+func (s *coalescedSubscription) Snapshot() ([]metav1.Object, error) {
+	return requestSnapshot(s.parent.Ready(), s.snapshotch, s.lc.ShuttingDown())
+}
+
+func (s *coalescedSubscription) Close() {
+	s.parent.Close()
+}
+
+func (s *coalescedSubscription) Done() <-chan struct{} {
+	return s.lc.Done()
+}
+
+func (s *coalescedSubscription) Error() error {
+	if err := s.lc.Error(); err != nil {
+		return err
+	}
+	return s.parent.Error()
+}
+
+func (s *coalescedSubscription) run() {
+	defer s.lc.ShutdownCompleted()
+
+	// receives when the window of the pending events has elapsed.
+	var timer clock.Timer
+	var timerch <-chan time.Time
+
+	stopTimer := func() {
+		if timer != nil {
+			timer.Stop()
+			timer = nil
+			timerch = nil
+		}
+	}
+
+loop:
+	for {
+		select {
+		case err := <-s.lc.ShutdownRequest():
+			s.log.Debugf("shutdown requested: %v", err)
+			s.lc.ShutdownInitiated(err)
+			break loop
+
+		case evt, ok := <-s.parent.Events():
+			if !ok {
+				s.log.Debugf("update: parent closed")
+				s.lc.ShutdownInitiated(nil)
+				break loop
+			}
+
+			if m, ok := evt.(*flushMarker); ok {
+				// pending events precede the marker.
+				stopTimer()
+				s.distributeEvents(s.pending.flush())
+				m.awaitRead(s.buffer.ch, s.lc.ShuttingDown())
+				continue
+			}
+
+			s.pending.add(evt)
+
+			if timer == nil {
+				timer = s.clock.NewTimer(s.window)
+				timerch = timer.C()
+			}
+
+		case <-timerch:
+			timer = nil
+			timerch = nil
+
+			events := s.pending.flush()
+			s.log.Debugf("window elapsed: %v events", len(events))
+			s.distributeEvents(events)
+
+		case resultch := <-s.snapshotch:
+			stopTimer()
+			discarded := len(s.pending.flush()) + s.buffer.drain()
+			s.log.Debugf("snapshot: discarded %v events", discarded)
+
+			list, err := s.parent.Snapshot()
+			resultch <- snapshotResult{list, err}
+		}
+	}
+
+	stopTimer()
+
+	// events received before shutdown are delivered.
+	s.distributeEvents(s.pending.flush())
+
+	s.parent.Close()
+
+	s.buffer.close()
+
+	<-s.parent.Done()
+	releaseFlushes(s.parent.Events())
+}
+
+func (s *coalescedSubscription) distributeEvents(events []Event) {
+	for _, evt := range events {
+		s.buffer.offer(evt)
+	}
+}
+
+// coalescedEvents holds the latest event for each object,
+// in the order that the objects were first seen.  Each event
+// retains the observation time of the first event it replaced.
+type coalescedEvents struct {
+	keys   []nsname.NSName
+	events map[nsname.NSName]Event
+}
+
+func newCoalescedEvents() coalescedEvents {
+	return coalescedEvents{events: make(map[nsname.NSName]Event)}
+}
+
+func (c *coalescedEvents) add(evt Event) {
+	key := nsname.ForObject(evt.Resource())
+
+	prev, ok := c.events[key]
+	if !ok {
+		c.keys = append(c.keys, key)
+		c.events[key] = evt
+		return
+	}
+
+	switch {
+	case prev.Type() == EventTypeCreate && evt.Type() == EventTypeDelete:
+		// created and deleted within the window: nothing to report.
+		delete(c.events, key)
+	case evt.Type() == EventTypeDelete:
+		c.events[key] = deriveEvent(EventTypeDelete, evt.Resource(), prev)
+	case prev.Type() == EventTypeCreate:
+		c.events[key] = deriveEvent(EventTypeCreate, evt.Resource(), prev)
+	default:
+		c.events[key] = deriveEvent(EventTypeUpdate, evt.Resource(), prev)
+	}
+}
+
+func (c *coalescedEvents) len() int {
+	return len(c.events)
+}
+
+// contains() returns true if an event for obj is held.
+func (c *coalescedEvents) contains(obj metav1.Object) bool {
+	_, ok := c.events[nsname.ForObject(obj)]
+	return ok
+}
+
+// next() removes and returns the event for the object that was
+// first seen earliest.
+func (c *coalescedEvents) next() (Event, bool) {
+	for len(c.keys) > 0 {
+		key := c.keys[0]
+		c.keys = c.keys[1:]
+		if evt, ok := c.events[key]; ok {
+			delete(c.events, key)
+			return evt, true
+		}
+	}
+	return nil, false
+}
+
+func (c *coalescedEvents) flush() []Event {
+	events := make([]Event, 0, len(c.events))
+	for _, key := range c.keys {
+		if evt, ok := c.events[key]; ok {
+			events = append(events, evt)
+			delete(c.events, key)
+		}
+	}
+	c.keys = nil
+	return events
+}
@@ -0,0 +1,158 @@
+package kcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/clock"
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoalescedSubscription_clock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := clock.NewFake(time.Now())
+
+	log := logutil.Default()
+	parent, _, readych := testNewSubscription(t, log, filter.Null())
+	sub := newCoalescedSubscription(log, parent, time.Minute, c, nullMetrics{})
+	defer parent.Close()
+
+	close(readych)
+	testutil.AssertReady(t, "sub", sub)
+
+	parent.send(testGenEvent(EventTypeCreate, "a", "b", "1"))
+	testWaitForClock(t, c, 1)
+
+	c.Advance(time.Minute - time.Nanosecond)
+	select {
+	case <-sub.Events():
+		assert.Fail(t, "event delivered before window elapsed")
+	case <-testutil.AsyncWaitch(ctx):
+	}
+
+	c.Advance(time.Nanosecond)
+	select {
+	case ev := <-sub.Events():
+		assert.Equal(t, "b", ev.Resource().GetName())
+	case <-testutil.Timerch(ctx, time.Second):
+		require.Fail(t, "no event after window elapsed")
+	}
+
+	// the timer of a window that doesn't elapse is stopped.
+	parent.send(testGenEvent(EventTypeCreate, "a", "c", "2"))
+	testWaitForClock(t, c, 1)
+
+	sub.Close()
+	testutil.AssertDone(t, "sub", sub)
+	assert.Equal(t, 0, c.Waiters())
+}
+
+func TestCoalescedSubscription(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log := logutil.Default()
+	parent, _, readych := testNewSubscription(t, log, filter.Null())
+	sub := newCoalescedSubscription(log, parent, 50*time.Millisecond, clock.Real(), nullMetrics{})
+	defer parent.Close()
+
+	close(readych)
+	testutil.AssertReady(t, "sub", sub)
+
+	first := testGenEvent(EventTypeCreate, "a", "b", "1")
+
+	parent.send(first)
+	parent.send(testGenEvent(EventTypeUpdate, "a", "b", "2"))
+	parent.send(testGenEvent(EventTypeCreate, "a", "c", "3"))
+	parent.send(testGenEvent(EventTypeUpdate, "a", "d", "4"))
+	parent.send(testGenEvent(EventTypeDelete, "a", "c", "5"))
+	parent.send(testGenEvent(EventTypeUpdate, "a", "d", "6"))
+	parent.send(testGenEvent(EventTypeUpdate, "a", "e", "7"))
+	parent.send(testGenEvent(EventTypeDelete, "a", "e", "8"))
+
+	select {
+	case <-sub.Events():
+		assert.Fail(t, "event delivered before window elapsed")
+	case <-testutil.AsyncWaitch(ctx):
+	}
+
+	expected := []Event{
+		testGenEvent(EventTypeCreate, "a", "b", "2"),
+		testGenEvent(EventTypeUpdate, "a", "d", "6"),
+		testGenEvent(EventTypeDelete, "a", "e", "8"),
+	}
+
+	for i, exp := range expected {
+		select {
+		case ev, ok := <-sub.Events():
+			require.True(t, ok)
+			assert.Equal(t, exp.Type(), ev.Type())
+			assert.Equal(t, exp.Resource(), ev.Resource())
+			if i == 0 {
+				assert.Equal(t, first.ObservedAt(), ev.ObservedAt())
+			}
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "missing event", "%v", exp)
+		}
+	}
+
+	select {
+	case ev := <-sub.Events():
+		assert.Fail(t, "unexpected event", "%v", ev)
+	case <-testutil.Timerch(ctx, 100*time.Millisecond):
+	}
+
+	parent.send(testGenEvent(EventTypeDelete, "a", "b", "9"))
+
+	select {
+	case ev, ok := <-sub.Events():
+		require.True(t, ok)
+		assert.Equal(t, EventTypeDelete, ev.Type())
+		assert.Equal(t, testGenPod("a", "b", "9"), ev.Resource())
+	case <-testutil.Timerch(ctx, time.Second):
+		assert.Fail(t, "missing event in second window")
+	}
+
+	parent.Close()
+	testutil.AssertDone(t, "sub", sub)
+
+	select {
+	case _, ok := <-sub.Events():
+		assert.False(t, ok)
+	case <-testutil.AsyncWaitch(ctx):
+		assert.Fail(t, "events not closed")
+	}
+}
+
+func TestCoalescedSubscription_Snapshot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log := logutil.Default()
+	parent, cache, readych := testNewSubscription(t, log, filter.Null())
+	sub := newCoalescedSubscription(log, parent, 50*time.Millisecond, clock.Real(), nullMetrics{})
+	defer parent.Close()
+
+	close(readych)
+
+	evt := testGenEvent(EventTypeCreate, "a", "b", "1")
+	cache.update(evt)
+	parent.send(evt)
+
+	list, err := sub.Snapshot()
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	select {
+	case <-sub.Events():
+		assert.Fail(t, "event reflected in snapshot was delivered")
+	case <-testutil.Timerch(ctx, 100*time.Millisecond):
+	}
+}
@@ -2,47 +2,102 @@ package kcache
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	lifecycle "github.com/boz/go-lifecycle"
 	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/clock"
 	"github.com/boz/kcache/filter"
 	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type FilterSubscription interface {
 	Subscription
+
+	// Refilter() replaces the subscription's filter and delivers the
+	// events needed to bring its cache up to date.  Refilter() is a no-op
+	// if the new filter is equal to the current one according to
+	// filter.FiltersEqual() once both are normalized by
+	// filter.Normalize(); filters that are not comparable are always
+	// treated as a change.
+	//
+	// No accept decisions are carried over from the previous filter:
+	// objects it rejected are evaluated by the new one.  Results
+	// remembered by filter.Cached() belong to the filter value, not the
+	// subscription.
 	Refilter(filter.Filter) error
+
+	// RefilterWithRate() is like Refilter() but delivers the events
+	// needed to bring the subscription's cache up to date at no more than
+	// limit events per second.  The cache is updated immediately.
+	//
+	// Later events for an object whose refilter event has not been
+	// delivered are delivered after it, coalesced as by
+	// Publisher.SubscribeCoalesced().  Refilter() is equivalent to
+	// RefilterWithRate() with an unlimited rate.
+	RefilterWithRate(f filter.Filter, limit float64) error
+}
+
+type refilterWithRateRequest struct {
+	filter filter.Filter
+	limit  float64
 }
 
 type filterSubscription struct {
 	parent Subscription
 
 	deferReady bool
-	refilterch chan filter.Filter
 
-	outch   chan Event
+	// true if the subscription's events are read by a clone.
+	relay bool
+
+	refilterch chan refilterWithRateRequest
+	snapshotch chan chan<- snapshotResult
+	markch     chan *snapshotMarker
+
+	buffer  *eventBuffer
 	readych chan struct{}
 
+	// events awaiting delivery at the rate of limiter.
+	paced   coalescedEvents
+	limiter *rate.Limiter
+	clock   clock.Clock
+
 	filter filter.Filter
 	cache  cache
 
+	// the filter of the most recent refilter request.  refilterMtx
+	// orders requests; currentMtx guards current.
+	current     filter.Filter
+	currentMtx  sync.Mutex
+	refilterMtx sync.Mutex
+
 	lc  lifecycle.Lifecycle
 	log logutil.Log
 }
 
-func newFilterSubscription(log logutil.Log, parent Subscription, f filter.Filter, deferReady bool) FilterSubscription {
+func newFilterSubscription(log logutil.Log, parent Subscription, f filter.Filter, deferReady bool, relay bool, clock clock.Clock, panics panicGuard, metrics Metrics) *filterSubscription {
 
 	ctx := context.Background()
 	lc := lifecycle.New()
 
 	s := &filterSubscription{
 		parent:     parent,
-		refilterch: make(chan filter.Filter),
-		outch:      make(chan Event, EventBufsiz),
+		refilterch: make(chan refilterWithRateRequest),
+		snapshotch: make(chan chan<- snapshotResult),
+		markch:     make(chan *snapshotMarker),
+		buffer:     newEventBuffer(log, EventBufsiz, OverflowDropNewest, metrics),
 		readych:    make(chan struct{}),
+		paced:      newCoalescedEvents(),
+		clock:      clock,
 		deferReady: deferReady,
+		relay:      relay,
 		filter:     f,
-		cache:      newCache(ctx, log, lc.ShuttingDown(), f),
+		current:    f,
+		cache:      newIndexedCache(ctx, log, lc.ShuttingDown(), f, cacheIndexFuncs(parent.Cache()), panics),
 		lc:         lc,
 		log:        log,
 	}
@@ -55,11 +110,52 @@ func newFilterSubscription(log logutil.Log, parent Subscription, f filter.Filter
 func (s *filterSubscription) Cache() CacheReader {
 	return s.cache
 }
+
+func (s *filterSubscription) ResourceVersion() string {
+	return s.parent.ResourceVersion()
+}
+
+func (s *filterSubscription) AddIndex(name string, fn IndexFunc) error {
+	return s.cache.addIndex(name, fn)
+}
 func (s *filterSubscription) Ready() <-chan struct{} {
 	return s.readych
 }
 func (s *filterSubscription) Events() <-chan Event {
-	return s.outch
+	return s.buffer.ch
+}
+func (s *filterSubscription) EventsContext(ctx context.Context) <-chan Event {
+	return eventsContext(ctx, s)
+}
+func (s *filterSubscription) EventBatches(maxCount int, maxDelay time.Duration) <-chan []Event {
+	return eventBatches(s, maxCount, maxDelay, s.clock)
+}
+func (s *filterSubscription) Filter() filter.Filter {
+	return intersectFilters(s.parent.Filter(), s.ownFilter())
+}
+
+// ownFilter() returns the current filter of s, without that of its
+// parent.
+func (s *filterSubscription) ownFilter() filter.Filter {
+	s.currentMtx.Lock()
+	defer s.currentMtx.Unlock()
+	return s.current
+}
+
+// intersectFilters() is like filter.And(a, b) but omits either filter if
+// it is filter.Null().
+func intersectFilters(a, b filter.Filter) filter.Filter {
+	switch {
+	case filter.FiltersEqual(a, filter.Null()):
+		return b
+	case filter.FiltersEqual(b, filter.Null()):
+		return a
+	}
+	return filter.And(a, b)
+}
+
+func (s *filterSubscription) Dropped() uint64 {
+	return s.buffer.Dropped()
 }
 func (s *filterSubscription) Close() {
 	s.parent.Close()
@@ -74,9 +170,43 @@ func (s *filterSubscription) Error() error {
 	return s.parent.Error()
 }
 
+func (s *filterSubscription) Snapshot() ([]metav1.Object, error) {
+	return requestSnapshot(s.readych, s.snapshotch, s.lc.ShuttingDown())
+}
+
+func (s *filterSubscription) markSnapshot(m *snapshotMarker) error {
+	select {
+	case s.markch <- m:
+		return nil
+	case <-s.lc.ShuttingDown():
+		return errors.WithStack(ErrNotRunning)
+	}
+}
+
+func (s *filterSubscription) markFlush(m *flushMarker) error {
+	parent, ok := s.parent.(flushMarkable)
+	if !ok {
+		return errors.Errorf("flush not supported by %T", s.parent)
+	}
+	return parent.markFlush(m)
+}
+
 func (s *filterSubscription) Refilter(filter filter.Filter) error {
+	return s.RefilterWithRate(filter, 0)
+}
+
+func (s *filterSubscription) RefilterWithRate(filter filter.Filter, limit float64) error {
+	if limit < 0 {
+		return errors.Errorf("invalid refilter rate: %v", limit)
+	}
+	s.refilterMtx.Lock()
+	defer s.refilterMtx.Unlock()
+
 	select {
-	case s.refilterch <- filter:
+	case s.refilterch <- refilterWithRateRequest{filter, limit}:
+		s.currentMtx.Lock()
+		s.current = filter
+		s.currentMtx.Unlock()
 		return nil
 	case <-s.lc.ShuttingDown():
 		return errors.WithStack(ErrNotRunning)
@@ -91,6 +221,31 @@ func (s *filterSubscription) run() {
 	pending := false
 	ready := false
 
+	var pacer clock.Timer
+	var pacech <-chan time.Time
+
+	stopPacer := func() {
+		if pacer != nil {
+			pacer.Stop()
+			pacer = nil
+			pacech = nil
+		}
+	}
+
+	// schedules delivery of the next paced event.
+	startPacer := func() {
+		if pacer != nil {
+			return
+		}
+		if s.paced.len() == 0 {
+			s.limiter = nil
+			return
+		}
+		now := s.clock.Now()
+		pacer = s.clock.NewTimer(s.limiter.ReserveN(now, 1).DelayFrom(now))
+		pacech = pacer.C()
+	}
+
 loop:
 	for {
 		select {
@@ -108,7 +263,7 @@ loop:
 				continue
 			}
 
-			list, err := s.parent.Cache().List()
+			list, err := listForFilter(s.parent.Cache(), s.filter)
 			if err != nil {
 				s.log.Debugf("parent ready: cache list error: %v", err)
 				s.lc.ShutdownInitiated(errors.Wrap(err, "parent ready: cache list"))
@@ -125,10 +280,12 @@ loop:
 			close(s.readych)
 			ready = true
 
-		case f := <-s.refilterch:
+		case req := <-s.refilterch:
 			s.log.Debugf("refiltering...")
 
-			isNew := !filter.FiltersEqual(s.filter, f)
+			f := req.filter
+
+			isNew := !filter.FiltersEqual(filter.Normalize(s.filter), filter.Normalize(f))
 
 			switch {
 
@@ -162,7 +319,7 @@ loop:
 
 			// pready == nil && isNew
 
-			list, err := s.parent.Cache().List()
+			list, err := listForFilter(s.parent.Cache(), f)
 			if err != nil {
 				s.log.Debugf("refilter: cache list error: %v", err)
 				s.lc.ShutdownInitiated(errors.Wrap(err, "refilter: cache list"))
@@ -186,16 +343,88 @@ loop:
 
 			s.log.Debugf("refilter: %v events", len(events))
 
-			s.distributeEvents(events)
+			if req.limit == 0 {
+				s.distributeEvents(events)
+				continue
+			}
+
+			s.limiter = rate.NewLimiter(rate.Limit(req.limit), 1)
+			for _, evt := range events {
+				s.paced.add(evt)
+			}
+			startPacer()
+
+		case <-pacech:
+			pacer = nil
+			pacech = nil
+
+			if evt, ok := s.paced.next(); ok {
+				s.buffer.offer(evt)
+			}
+			startPacer()
+
+		case resultch := <-s.snapshotch:
+			stopPacer()
+			discarded := len(s.paced.flush()) + s.buffer.drain()
+			s.log.Debugf("snapshot: discarded %v events", discarded)
+			list, err := s.cache.List()
+			resultch <- snapshotResult{list, err}
+
+		case m := <-s.markch:
+			// paced events are reflected in the snapshot.
+			stopPacer()
+			s.log.Debugf("mark: discarded %v paced events", len(s.paced.flush()))
+
+			m.list, m.err = s.cache.List()
+			select {
+			case s.buffer.ch <- m:
+			case err := <-s.lc.ShutdownRequest():
+				s.log.Debugf("shutdown requested: %v", err)
+				s.lc.ShutdownInitiated(err)
+				m.fail(errors.WithStack(ErrNotRunning))
+				break loop
+			}
 
 		case evt, ok := <-s.parent.Events():
 
-			switch {
-			case !ok:
+			if !ok {
 				s.log.Debugf("update: parent closed")
 				s.lc.ShutdownInitiated(nil)
 				break loop
-			case !ready:
+			}
+
+			if m, ok := evt.(*flushMarker); ok {
+				// paced events precede the marker.
+				stopPacer()
+				s.distributeEvents(s.paced.flush())
+				s.limiter = nil
+
+				if !s.relay {
+					m.awaitRead(s.buffer.ch, s.lc.ShuttingDown())
+					continue
+				}
+				select {
+				case s.buffer.ch <- m:
+				case err := <-s.lc.ShutdownRequest():
+					s.log.Debugf("shutdown requested: %v", err)
+					s.lc.ShutdownInitiated(err)
+					m.done()
+					break loop
+				}
+				continue
+			}
+
+			if !ready {
+				continue
+			}
+
+			if _, ok := evt.(resyncEvent); ok {
+				// the cache ignores unchanged objects; forward resyncs for
+				// objects that pass the filter.
+				obj, err := s.cache.Get(evt.Resource().GetNamespace(), evt.Resource().GetName())
+				if err == nil && obj != nil && !s.paced.contains(obj) {
+					s.distributeEvents([]Event{evt})
+				}
 				continue
 			}
 
@@ -213,19 +442,28 @@ loop:
 		}
 	}
 
+	stopPacer()
+
+	// paced events received before shutdown are delivered.
+	s.distributeEvents(s.paced.flush())
+	s.limiter = nil
+
 	s.parent.Close()
 
-	close(s.outch)
+	s.buffer.close()
 
 	<-s.parent.Done()
+	releaseFlushes(s.parent.Events())
 }
 
+// distributeEvents() delivers events, or paces those for objects
+// with paced events.
 func (s *filterSubscription) distributeEvents(events []Event) {
 	for _, evt := range events {
-		select {
-		case s.outch <- evt:
-		default:
-			s.log.Warnf("event buffer overrun")
+		if s.limiter != nil && s.paced.contains(evt.Resource()) {
+			s.paced.add(evt)
+			continue
 		}
+		s.buffer.offer(evt)
 	}
 }
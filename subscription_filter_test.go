@@ -2,14 +2,18 @@ package kcache
 
 import (
 	"context"
+	"strconv"
 	"testing"
+	"time"
 
 	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/clock"
 	"github.com/boz/kcache/filter"
 	"github.com/boz/kcache/nsname"
 	"github.com/boz/kcache/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestFilterSubscriptionReady_immediate(t *testing.T) {
@@ -18,7 +22,7 @@ func TestFilterSubscriptionReady_immediate(t *testing.T) {
 
 	log := logutil.Default()
 	parent, cache, readych := testNewSubscription(t, log, filter.Null())
-	sub := newFilterSubscription(log, parent, filter.Null(), false)
+	sub := newFilterSubscription(log, parent, filter.Null(), false, false, clock.Real(), panicGuard{log: log}, nullMetrics{})
 	defer parent.Close()
 
 	testDoFilterSubscriptionReady(t, "immediate", parent, sub, cache)
@@ -50,7 +54,7 @@ func TestFilterSubscriptionReady_deferred(t *testing.T) {
 
 	log := logutil.Default()
 	parent, cache, readych := testNewSubscription(t, log, filter.Null())
-	sub := newFilterSubscription(log, parent, filter.Null(), true)
+	sub := newFilterSubscription(log, parent, filter.Null(), true, false, clock.Real(), panicGuard{log: log}, nullMetrics{})
 	defer parent.Close()
 
 	testDoFilterSubscriptionReady(t, "deferred", parent, sub, cache)
@@ -82,7 +86,7 @@ func TestFilterSubscriptionRefilter_immediate_refilter_before_ready(t *testing.T
 
 	log := logutil.Default()
 	parent, cache, readych := testNewSubscription(t, log, filter.Null())
-	sub := newFilterSubscription(log, parent, filter.Null(), false)
+	sub := newFilterSubscription(log, parent, filter.Null(), false, false, clock.Real(), panicGuard{log: log}, nullMetrics{})
 	defer parent.Close()
 
 	cache.update(testGenEvent(EventTypeCreate, "a", "b", "1"))
@@ -167,7 +171,7 @@ func TestFilterSubscriptionRefilter_immediate_refilter_after_ready(t *testing.T)
 
 	log := logutil.Default()
 	parent, cache, readych := testNewSubscription(t, log, filter.Null())
-	sub := newFilterSubscription(log, parent, filter.Null(), false)
+	sub := newFilterSubscription(log, parent, filter.Null(), false, false, clock.Real(), panicGuard{log: log}, nullMetrics{})
 	defer parent.Close()
 
 	cache.update(testGenEvent(EventTypeCreate, "a", "b", "1"))
@@ -219,13 +223,114 @@ func TestFilterSubscriptionRefilter_immediate_refilter_after_ready(t *testing.T)
 
 }
 
+func TestFilterSubscriptionRefilter_unchanged(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log := logutil.Default()
+	parent, cache, readych := testNewSubscription(t, log, filter.Null())
+	sub := newFilterSubscription(log, parent, filter.Namespace("a"), false, false, clock.Real(), panicGuard{log: log}, nullMetrics{})
+	defer parent.Close()
+
+	cache.update(testGenEvent(EventTypeCreate, "a", "b", "1"))
+
+	close(readych)
+	testutil.AssertReady(t, "ready", sub)
+
+	// not delivered to sub: only visible to a full refilter.
+	cache.update(testGenEvent(EventTypeCreate, "a", "c", "2"))
+
+	require.NoError(t, sub.Refilter(filter.Namespace("a")))
+	select {
+	case evt := <-sub.Events():
+		assert.Fail(t, "event after unchanged refilter", "%v", evt)
+	case <-testutil.AsyncWaitch(ctx):
+	}
+
+	list, err := sub.Cache().List()
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	// equal once normalized.
+	require.NoError(t, sub.Refilter(filter.Not(filter.Not(filter.And(filter.Namespace("a"))))))
+	select {
+	case evt := <-sub.Events():
+		assert.Fail(t, "event after normalized refilter", "%v", evt)
+	case <-testutil.AsyncWaitch(ctx):
+	}
+
+	// filters that are not comparable are always a change.
+	require.NoError(t, sub.Refilter(filter.FN(func(obj metav1.Object) bool {
+		return obj.GetNamespace() == "a"
+	})))
+	select {
+	case evt := <-sub.Events():
+		assert.Equal(t, EventTypeCreate, evt.Type())
+		assert.Equal(t, "c", evt.Resource().GetName())
+	case <-testutil.AsyncWaitch(ctx):
+		assert.Fail(t, "no event after refilter")
+	}
+}
+
+func TestFilterSubscriptionRefilter_broaden(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log := logutil.Default()
+	parent, cache, readych := testNewSubscription(t, log, filter.Null())
+	sub := newFilterSubscription(log, parent, filter.Cached(filter.Namespace("a")), false, false, clock.Real(), panicGuard{log: log}, nullMetrics{})
+	defer parent.Close()
+
+	cache.update(testGenEvent(EventTypeCreate, "a", "x", "1"))
+	cache.update(testGenEvent(EventTypeCreate, "b", "y", "2"))
+
+	close(readych)
+	testutil.AssertReady(t, "ready", sub)
+
+	// rejected, and remembered as rejected by the old filter.
+	evt := testGenEvent(EventTypeUpdate, "b", "y", "3")
+	cache.update(evt)
+	parent.send(evt)
+	select {
+	case evt := <-sub.Events():
+		assert.Fail(t, "filtered event", "%v", evt)
+	case <-testutil.AsyncWaitch(ctx):
+	}
+
+	require.NoError(t, sub.Refilter(filter.Cached(filter.Namespace("a", "b"))))
+	select {
+	case evt := <-sub.Events():
+		assert.Equal(t, EventTypeCreate, evt.Type())
+		assert.Equal(t, "y", evt.Resource().GetName())
+		assert.Equal(t, "3", evt.Resource().GetResourceVersion())
+	case <-testutil.Timerch(ctx, time.Second):
+		require.Fail(t, "no event for previously rejected object")
+	}
+
+	// later events for the object are delivered.
+	evt = testGenEvent(EventTypeUpdate, "b", "y", "4")
+	cache.update(evt)
+	parent.send(evt)
+	select {
+	case evt := <-sub.Events():
+		assert.Equal(t, EventTypeUpdate, evt.Type())
+		assert.Equal(t, "4", evt.Resource().GetResourceVersion())
+	case <-testutil.Timerch(ctx, time.Second):
+		require.Fail(t, "no event after refilter")
+	}
+
+	list, err := sub.Cache().List()
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+}
+
 func TestFilterSubscriptionRefilter_deferred_refilter_before_ready(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	log := logutil.Default()
 	parent, cache, readych := testNewSubscription(t, log, filter.Null())
-	sub := newFilterSubscription(log, parent, filter.Null(), true)
+	sub := newFilterSubscription(log, parent, filter.Null(), true, false, clock.Real(), panicGuard{log: log}, nullMetrics{})
 	defer parent.Close()
 
 	cache.update(testGenEvent(EventTypeCreate, "a", "b", "1"))
@@ -282,7 +387,7 @@ func TestFilterSubscriptionRefilter_deferred_refilter_after_ready(t *testing.T)
 
 	log := logutil.Default()
 	parent, cache, readych := testNewSubscription(t, log, filter.Null())
-	sub := newFilterSubscription(log, parent, filter.Null(), true)
+	sub := newFilterSubscription(log, parent, filter.Null(), true, false, clock.Real(), panicGuard{log: log}, nullMetrics{})
 	defer parent.Close()
 
 	cache.update(testGenEvent(EventTypeCreate, "a", "b", "1"))
@@ -375,3 +480,161 @@ func testDoTestFilterSubscriptionShutdown(t *testing.T, name string, parent subs
 	}
 
 }
+
+func TestFilterSubscription_Snapshot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log := logutil.Default()
+	parent, cache, readych := testNewSubscription(t, log, filter.Null())
+	sub := newFilterSubscription(log, parent, filter.NSName(nsname.New("a", "")), false, false, clock.Real(), panicGuard{log: log}, nullMetrics{})
+	defer parent.Close()
+
+	cache.update(testGenEvent(EventTypeCreate, "a", "b", "1"))
+	cache.update(testGenEvent(EventTypeCreate, "x", "b", "1"))
+
+	close(readych)
+
+	testutil.AssertReady(t, "sub", sub)
+
+	evt := testGenEvent(EventTypeCreate, "a", "c", "2")
+	cache.update(evt)
+	parent.send(evt)
+
+	list, err := sub.Snapshot()
+	require.NoError(t, err)
+
+	// a/c is either in the snapshot or delivered after it; never both.
+	switch len(list) {
+	case 2:
+		select {
+		case <-sub.Events():
+			assert.Fail(t, "event reflected in snapshot was delivered")
+		case <-testutil.AsyncWaitch(ctx):
+		}
+	case 1:
+		select {
+		case ev := <-sub.Events():
+			assert.Equal(t, "c", ev.Resource().GetName())
+		case <-testutil.AsyncWaitch(ctx):
+			assert.Fail(t, "event not in snapshot was not delivered")
+		}
+	default:
+		assert.Fail(t, "unexpected snapshot", "%v", list)
+	}
+
+	evt = testGenEvent(EventTypeUpdate, "a", "c", "3")
+	cache.update(evt)
+	parent.send(evt)
+
+	select {
+	case ev, ok := <-sub.Events():
+		assert.True(t, ok)
+		assert.Equal(t, EventTypeUpdate, ev.Type())
+		assert.Equal(t, "c", ev.Resource().GetName())
+	case <-testutil.AsyncWaitch(ctx):
+		assert.Fail(t, "no event after snapshot")
+	}
+
+	testDoTestFilterSubscriptionShutdown(t, "snapshot", parent, sub)
+
+	_, err = sub.Snapshot()
+	assert.Error(t, err)
+}
+
+func TestFilterSubscriptionRefilterWithRate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const (
+		count = 30
+		limit = 100.0
+	)
+
+	log := logutil.Default()
+	parent, cache, readych := testNewSubscription(t, log, filter.Null())
+	sub := newFilterSubscription(log, parent, filter.Namespace("a"), false, false, clock.Real(), panicGuard{log: log}, nullMetrics{})
+	defer parent.Close()
+
+	for i := 0; i < count; i++ {
+		cache.update(testGenEvent(EventTypeCreate, "b", strconv.Itoa(i), "1"))
+	}
+
+	close(readych)
+	testutil.AssertReady(t, "ready", sub)
+
+	assert.Error(t, sub.RefilterWithRate(filter.Namespace("b"), -1))
+
+	start := time.Now()
+	require.NoError(t, sub.RefilterWithRate(filter.Namespace("b"), limit))
+
+	// delivered after the paced create.
+	evt := testGenEvent(EventTypeUpdate, "b", strconv.Itoa(count-1), "2")
+	cache.update(evt)
+	parent.send(evt)
+
+	versions := make(map[string]string)
+	var times []time.Duration
+	for len(times) < count {
+		select {
+		case evt := <-sub.Events():
+			assert.Equal(t, EventTypeCreate, evt.Type())
+			versions[evt.Resource().GetName()] = evt.Resource().GetResourceVersion()
+			times = append(times, time.Since(start))
+
+			if len(times) == 1 {
+				// the cache is refiltered before paced events are delivered.
+				list, err := sub.Cache().List()
+				require.NoError(t, err)
+				assert.Len(t, list, count)
+			}
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "missing events", "%v/%v events", len(times), count)
+		}
+	}
+
+	assert.Len(t, versions, count)
+	assert.Equal(t, "2", versions[strconv.Itoa(count-1)])
+
+	// event n is not delivered before n/limit seconds.
+	for i, elapsed := range times {
+		min := time.Duration(float64(i) / limit * float64(time.Second))
+		assert.True(t, elapsed >= min-5*time.Millisecond, "event %v after %v", i, elapsed)
+	}
+
+	select {
+	case evt := <-sub.Events():
+		assert.Fail(t, "unexpected event", "%v", evt)
+	case <-testutil.AsyncWaitch(ctx):
+	}
+}
+
+func TestFilterSubscriptionRefilterWithRate_snapshot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log := logutil.Default()
+	parent, cache, readych := testNewSubscription(t, log, filter.Null())
+	sub := newFilterSubscription(log, parent, filter.Namespace("a"), false, false, clock.Real(), panicGuard{log: log}, nullMetrics{})
+	defer parent.Close()
+
+	for i := 0; i < 10; i++ {
+		cache.update(testGenEvent(EventTypeCreate, "b", strconv.Itoa(i), "1"))
+	}
+
+	close(readych)
+	testutil.AssertReady(t, "ready", sub)
+
+	require.NoError(t, sub.RefilterWithRate(filter.Namespace("b"), 1))
+
+	list, err := sub.Snapshot()
+	require.NoError(t, err)
+	assert.Len(t, list, 10)
+
+	// paced events are reflected in the snapshot.
+	select {
+	case evt := <-sub.Events():
+		assert.Fail(t, "event reflected in snapshot was delivered", "%v", evt)
+	case <-testutil.Timerch(ctx, 100*time.Millisecond):
+	}
+}
@@ -3,11 +3,15 @@ package kcache
 import (
 	"context"
 	"testing"
+	"time"
 
 	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/clock"
 	"github.com/boz/kcache/filter"
 	"github.com/boz/kcache/testutil"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSubscription(t *testing.T) {
@@ -24,7 +28,7 @@ func testDoTestSubscription(t *testing.T, name string, stopfn func(subscription,
 	stopch := make(chan struct{})
 	cache := newCache(ctx, log, stopch, filter.Null())
 
-	sub := newSubscription(log, stopch, readych, cache)
+	sub := newSubscription(log, stopch, nil, nil, readych, cache)
 	defer sub.Close()
 
 	testutil.AssertNotDone(t, name, sub)
@@ -56,3 +60,161 @@ func testDoTestSubscription(t *testing.T, name string, stopfn func(subscription,
 	}
 
 }
+
+func TestSubscription_Snapshot(t *testing.T) {
+	log := logutil.Default()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	readych := make(chan struct{})
+	cache := newCache(ctx, log, nil, filter.Null())
+
+	sub := newSubscription(log, nil, nil, nil, readych, cache)
+	defer sub.Close()
+
+	evt := testGenEvent(EventTypeCreate, "a", "b", "1")
+	_, err := cache.update(evt)
+	require.NoError(t, err)
+	require.NoError(t, sub.send(evt))
+
+	close(readych)
+
+	list, err := sub.Snapshot()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "b", list[0].GetName())
+
+	select {
+	case <-sub.Events():
+		assert.Fail(t, "event reflected in snapshot was delivered")
+	case <-testutil.AsyncWaitch(ctx):
+	}
+
+	evt = testGenEvent(EventTypeCreate, "a", "c", "1")
+	require.NoError(t, sub.send(evt))
+
+	select {
+	case ev, ok := <-sub.Events():
+		assert.True(t, ok)
+		assert.Equal(t, evt, ev)
+	case <-testutil.AsyncWaitch(ctx):
+		assert.Fail(t, "no event after snapshot")
+	}
+
+	sub.Close()
+	testutil.AssertDone(t, "sub", sub)
+
+	_, err = sub.Snapshot()
+	assert.Equal(t, ErrNotRunning, errors.Cause(err))
+}
+
+func TestSubscription_EventsContext(t *testing.T) {
+	log := logutil.Default()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	readych := make(chan struct{})
+	cache := newCache(ctx, log, nil, filter.Null())
+
+	sub := newSubscription(log, nil, nil, nil, readych, cache)
+	defer sub.Close()
+
+	sctx, scancel := context.WithCancel(ctx)
+	events := sub.EventsContext(sctx)
+
+	evt := testGenEvent(EventTypeCreate, "a", "b", "1")
+	sub.send(evt)
+
+	select {
+	case ev, ok := <-events:
+		assert.True(t, ok)
+		assert.Equal(t, evt, ev)
+	case <-testutil.AsyncWaitch(ctx):
+		assert.Fail(t, "no event")
+	}
+
+	testutil.AssertNotDone(t, "sub", sub)
+
+	scancel()
+
+	testutil.AssertDone(t, "sub", sub)
+	assert.NoError(t, sub.Error())
+
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-testutil.AsyncWaitch(ctx):
+		assert.Fail(t, "events not closed")
+	}
+}
+
+func TestSubscription_closeBuffered(t *testing.T) {
+	log := logutil.Default()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// reads events until Events() is closed.
+	drain := func(name string, sub Subscription) []Event {
+		var events []Event
+		for {
+			select {
+			case ev, ok := <-sub.Events():
+				if !ok {
+					return events
+				}
+				events = append(events, ev)
+			case <-testutil.AsyncWaitch(ctx):
+				assert.Fail(t, "events not closed", name)
+				return events
+			}
+		}
+	}
+
+	events := []Event{
+		testGenEvent(EventTypeCreate, "a", "1", "1"),
+		testGenEvent(EventTypeCreate, "a", "2", "2"),
+		testGenEvent(EventTypeCreate, "a", "3", "3"),
+	}
+
+	{
+		readych := make(chan struct{})
+		cache := newCache(ctx, log, nil, filter.Null())
+		sub := newSubscription(log, nil, nil, nil, readych, cache)
+
+		for _, evt := range events {
+			require.NoError(t, sub.send(evt))
+		}
+		// wait until the events are buffered.
+		for len(sub.Events()) < len(events) {
+			<-testutil.Timerch(ctx, time.Millisecond)
+		}
+
+		sub.Close()
+		testutil.AssertDone(t, "sub", sub)
+
+		assert.Equal(t, events, drain("sub", sub))
+	}
+
+	{
+		readych := make(chan struct{})
+		cache := newCache(ctx, log, nil, filter.Null())
+		parent := newSubscription(log, nil, nil, nil, readych, cache)
+		sub := newCoalescedSubscription(log, parent, time.Hour, clock.Real(), nullMetrics{})
+
+		for _, evt := range events {
+			require.NoError(t, parent.send(evt))
+		}
+		// wait until the parent's events have been read.
+		for len(parent.Events()) > 0 {
+			<-testutil.Timerch(ctx, time.Millisecond)
+		}
+
+		// events are pending until the window elapses.
+		testutil.AssertNotDone(t, "csub", sub)
+
+		parent.Close()
+		testutil.AssertDone(t, "csub", sub)
+
+		assert.Len(t, drain("csub", sub), len(events))
+	}
+}
@@ -0,0 +1,26 @@
+package testutil
+
+import (
+	"github.com/boz/kcache/client/mocks"
+	"github.com/stretchr/testify/mock"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// MockWatch() returns a mock watch that delivers the events sent on
+// eventch.
+func MockWatch(eventch chan watch.Event) *mocks.WatchInterface {
+	mwatch := &mocks.WatchInterface{}
+	mwatch.On("ResultChan").Return(eventch)
+	mwatch.On("Stop").Return()
+	return mwatch
+}
+
+// MockClient() returns a mock client whose List() returns list and whose
+// Watch() returns MockWatch(eventch).
+func MockClient(list runtime.Object, eventch chan watch.Event) *mocks.Client {
+	client := &mocks.Client{}
+	client.On("Watch", mock.Anything, mock.AnythingOfType("v1.ListOptions")).Return(MockWatch(eventch), nil)
+	client.On("List", mock.Anything, mock.AnythingOfType("v1.ListOptions")).Return(list, nil)
+	return client
+}
@@ -3,12 +3,21 @@ package kcache
 import (
 	"context"
 	"testing"
+	"time"
 
 	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/client"
+	"github.com/boz/kcache/client/mocks"
+	"github.com/boz/kcache/clock"
 	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 )
 
 func testGenPod(ns, name, vsn string) *v1.Pod {
@@ -31,7 +40,7 @@ func testNewSubscription(t *testing.T, log logutil.Log, f filter.Filter) (subscr
 	readych := make(chan struct{})
 	cache := newCache(ctx, log, nil, f)
 
-	sub := newSubscription(log, nil, readych, cache)
+	sub := newSubscription(log, nil, nil, nil, readych, cache)
 
 	go func() {
 		<-sub.Done()
@@ -41,3 +50,93 @@ func testNewSubscription(t *testing.T, log logutil.Log, f filter.Filter) (subscr
 	return sub, cache, readych
 
 }
+
+// testWaitForClock() waits until c has at least count waiters.
+func testWaitForClock(t *testing.T, c *clock.Fake, count int) {
+	timeout := testutil.Timerch(context.Background(), time.Second)
+	for c.Waiters() < count {
+		select {
+		case <-time.After(time.Millisecond):
+		case <-timeout:
+			require.Fail(t, "not waiting on clock", "%v/%v waiters", c.Waiters(), count)
+		}
+	}
+}
+
+// testControllerConfig holds the options of testNewController().
+type testControllerConfig struct {
+	ctx     context.Context
+	eventch chan watch.Event
+	list    runtime.Object
+	listch  <-chan time.Time
+	client  client.Client
+	build   []func(Builder)
+}
+
+type testControllerOption func(*testControllerConfig)
+
+// testContext() sets the context of the controller.  The default is
+// context.Background().
+func testContext(ctx context.Context) testControllerOption {
+	return func(cfg *testControllerConfig) { cfg.ctx = ctx }
+}
+
+// testEvents() sets the channel of events delivered by the watch.  By
+// default no events are delivered.
+func testEvents(eventch chan watch.Event) testControllerOption {
+	return func(cfg *testControllerConfig) { cfg.eventch = eventch }
+}
+
+// testList() sets the result of listing.  The default is an empty list
+// at resource version "1".
+func testList(list runtime.Object) testControllerOption {
+	return func(cfg *testControllerConfig) { cfg.list = list }
+}
+
+// testListWait() delays each list until listch is ready.
+func testListWait(listch <-chan time.Time) testControllerOption {
+	return func(cfg *testControllerConfig) { cfg.listch = listch }
+}
+
+// testClient() sets the client of the controller, for tests that set up
+// its calls themselves.  testEvents(), testList(), and testListWait() are
+// ignored.
+func testClient(client client.Client) testControllerOption {
+	return func(cfg *testControllerConfig) { cfg.client = client }
+}
+
+// testBuild() configures the builder before the controller is created.
+func testBuild(fn func(Builder)) testControllerOption {
+	return func(cfg *testControllerConfig) { cfg.build = append(cfg.build, fn) }
+}
+
+// testNewController() returns a controller whose client is a mock
+// configured by opts.
+func testNewController(t testing.TB, opts ...testControllerOption) Controller {
+	cfg := &testControllerConfig{
+		ctx:     context.Background(),
+		eventch: make(chan watch.Event),
+		list:    &v1.PodList{ListMeta: metav1.ListMeta{ResourceVersion: "1"}},
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.client == nil {
+		client := &mocks.Client{}
+		client.On("Watch", mock.Anything, mock.AnythingOfType("v1.ListOptions")).Return(testutil.MockWatch(cfg.eventch), nil)
+		client.On("List", mock.Anything, mock.AnythingOfType("v1.ListOptions")).
+			WaitUntil(cfg.listch).
+			Return(cfg.list, nil)
+		cfg.client = client
+	}
+
+	builder := NewBuilder().Context(cfg.ctx).Client(cfg.client)
+	for _, fn := range cfg.build {
+		fn(builder)
+	}
+
+	controller, err := builder.Create()
+	require.NoError(t, err)
+	return controller
+}
@@ -3,6 +3,8 @@ package kcache
 import (
 	"math/rand"
 	"time"
+
+	"github.com/boz/kcache/clock"
 )
 
 type ticker interface {
@@ -12,11 +14,12 @@ type ticker interface {
 	Done() <-chan struct{}
 }
 
-func newTicker(period time.Duration, fuzz float64) ticker {
+func newTicker(period time.Duration, fuzz float64, clock clock.Clock) ticker {
 
 	t := &_ticker{
 		period:  period,
 		fuzz:    fuzz,
+		clock:   clock,
 		nextch:  make(chan int),
 		resetch: make(chan bool),
 		stopch:  make(chan bool),
@@ -31,6 +34,7 @@ func newTicker(period time.Duration, fuzz float64) ticker {
 type _ticker struct {
 	period time.Duration
 	fuzz   float64
+	clock  clock.Clock
 
 	nextch  chan int
 	resetch chan bool
@@ -64,7 +68,7 @@ func (t *_ticker) run() {
 	defer close(t.donech)
 
 	count := 0
-	timer := time.NewTimer(t.nextPeriod())
+	timer := t.clock.NewTimer(t.nextPeriod())
 
 	var nextch chan int
 
@@ -73,8 +77,9 @@ func (t *_ticker) run() {
 		select {
 
 		case <-t.resetch:
-			if !timer.Stop() {
-				<-timer.C
+			// a timer that fired is drained unless its tick is pending.
+			if !timer.Stop() && nextch == nil {
+				<-timer.C()
 			}
 			timer.Reset(t.nextPeriod())
 			nextch = nil
@@ -83,7 +88,7 @@ func (t *_ticker) run() {
 			timer.Stop()
 			return
 
-		case <-timer.C:
+		case <-timer.C():
 			timer.Stop()
 			nextch = t.nextch
 
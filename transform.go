@@ -0,0 +1,61 @@
+package kcache
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// TransformFunc returns the object to cache and publish in place of obj.
+//
+// The result must have the same namespace, name, and resource version
+// as obj.  obj must not be modified; return a modified copy instead.
+type TransformFunc func(obj metav1.Object) metav1.Object
+
+func transformList(fn TransformFunc, list []metav1.Object) []metav1.Object {
+	if fn == nil {
+		return list
+	}
+	result := make([]metav1.Object, 0, len(list))
+	for _, obj := range list {
+		result = append(result, fn(obj))
+	}
+	return result
+}
+
+func transformEvent(fn TransformFunc, evt Event) Event {
+	if fn == nil {
+		return evt
+	}
+	return deriveEvent(evt.Type(), fn(evt.Resource()), evt)
+}
+
+// LastAppliedConfigAnnotation is the annotation in which
+// "kubectl apply" records the applied configuration.
+const LastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// TrimLastAppliedConfig() is a TransformFunc that removes
+// LastAppliedConfigAnnotation, which is often larger than the rest
+// of the object.
+func TrimLastAppliedConfig(obj metav1.Object) metav1.Object {
+	if _, ok := obj.GetAnnotations()[LastAppliedConfigAnnotation]; !ok {
+		return obj
+	}
+
+	robj, ok := obj.(runtime.Object)
+	if !ok {
+		return obj
+	}
+
+	copy, ok := robj.DeepCopyObject().(metav1.Object)
+	if !ok {
+		return obj
+	}
+
+	annotations := copy.GetAnnotations()
+	delete(annotations, LastAppliedConfigAnnotation)
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	copy.SetAnnotations(annotations)
+	return copy
+}
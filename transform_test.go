@@ -0,0 +1,101 @@
+package kcache
+
+import (
+	"context"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/filter"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testGenAppliedDeployment(name string) *v1beta1.Deployment {
+	return &v1beta1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       "ns",
+			Name:            name,
+			ResourceVersion: "1",
+			Annotations: map[string]string{
+				"a":                         "b",
+				LastAppliedConfigAnnotation: strings.Repeat("x", 4096),
+			},
+		},
+	}
+}
+
+func TestTrimLastAppliedConfig(t *testing.T) {
+	obj := testGenAppliedDeployment("a")
+
+	trimmed := TrimLastAppliedConfig(obj)
+	assert.Equal(t, map[string]string{"a": "b"}, trimmed.GetAnnotations())
+	assert.Equal(t, obj.GetName(), trimmed.GetName())
+	assert.Equal(t, obj.GetResourceVersion(), trimmed.GetResourceVersion())
+
+	// the original is not modified.
+	assert.Contains(t, obj.GetAnnotations(), LastAppliedConfigAnnotation)
+
+	delete(obj.Annotations, "a")
+	assert.Nil(t, TrimLastAppliedConfig(obj).GetAnnotations())
+
+	plain := testGenPod("ns", "a", "1")
+	assert.True(t, plain == TrimLastAppliedConfig(plain))
+}
+
+func TestTransformEvent(t *testing.T) {
+	evt := NewEvent(EventTypeUpdate, testGenAppliedDeployment("a"))
+	assert.True(t, evt == transformEvent(nil, evt))
+
+	transformed := transformEvent(TrimLastAppliedConfig, evt)
+	assert.Equal(t, EventTypeUpdate, transformed.Type())
+	assert.Equal(t, map[string]string{"a": "b"}, transformed.Resource().GetAnnotations())
+	assert.Equal(t, evt.ObservedAt(), transformed.ObservedAt())
+}
+
+// benchmarkTransformCache() logs the heap retained by a cache of
+// annotated deployments.
+func benchmarkTransformCache(b *testing.B, fn TransformFunc) {
+	var retained uint64
+	for i := 0; i < b.N; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		before := testHeapAlloc()
+
+		cache := newCache(ctx, logutil.Default(), nil, filter.Null())
+		list := make([]metav1.Object, 0, 1000)
+		for j := 0; j < 1000; j++ {
+			list = append(list, testGenAppliedDeployment(strconv.Itoa(j)))
+		}
+		if _, err := cache.sync(transformList(fn, list)); err != nil {
+			b.Fatal(err)
+		}
+		list = nil
+
+		if after := testHeapAlloc(); after > before {
+			retained += after - before
+		}
+
+		runtime.KeepAlive(cache)
+		cancel()
+	}
+	b.Logf("retained %v bytes per cache", retained/uint64(b.N))
+}
+
+func testHeapAlloc() uint64 {
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+func BenchmarkCache_untrimmed(b *testing.B) {
+	benchmarkTransformCache(b, nil)
+}
+
+func BenchmarkCache_trimLastAppliedConfig(b *testing.B) {
+	benchmarkTransformCache(b, TrimLastAppliedConfig)
+}
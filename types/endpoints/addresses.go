@@ -0,0 +1,35 @@
+package endpoints
+
+import (
+	"github.com/boz/kcache"
+	"k8s.io/api/core/v1"
+)
+
+// ReadyAddresses() returns the ready IP addresses of the endpoints
+// ns/name in cache, in the order they first appear across its subsets.
+// Addresses listed only in NotReadyAddresses are excluded.  It returns
+// nil if the endpoints are not in cache.
+func ReadyAddresses(cache CacheReader, ns, name string) ([]string, error) {
+	obj, err := cache.Get(ns, name)
+	if kcache.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return readyAddresses(obj), nil
+}
+
+func readyAddresses(obj *v1.Endpoints) []string {
+	var ips []string
+	seen := make(map[string]bool)
+	for _, subset := range obj.Subsets {
+		for _, addr := range subset.Addresses {
+			if !seen[addr.IP] {
+				seen[addr.IP] = true
+				ips = append(ips, addr.IP)
+			}
+		}
+	}
+	return ips
+}
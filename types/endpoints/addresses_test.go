@@ -0,0 +1,78 @@
+package endpoints_test
+
+import (
+	"context"
+	"testing"
+
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/client/mocks"
+	"github.com/boz/kcache/testutil"
+	"github.com/boz/kcache/types/endpoints"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestReadyAddresses(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	addrs := func(ips ...string) []v1.EndpointAddress {
+		var addrs []v1.EndpointAddress
+		for _, ip := range ips {
+			addrs = append(addrs, v1.EndpointAddress{IP: ip})
+		}
+		return addrs
+	}
+
+	genendpoints := func(name string, subsets ...v1.EndpointSubset) v1.Endpoints {
+		return v1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, ResourceVersion: "1"},
+			Subsets:    subsets,
+		}
+	}
+
+	list := &v1.EndpointsList{
+		ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+		Items: []v1.Endpoints{
+			genendpoints("single", v1.EndpointSubset{
+				Addresses:         addrs("10.0.0.1", "10.0.0.2"),
+				NotReadyAddresses: addrs("10.0.0.3"),
+			}),
+			genendpoints("multi",
+				v1.EndpointSubset{Addresses: addrs("10.0.0.1"), NotReadyAddresses: addrs("10.0.0.2")},
+				v1.EndpointSubset{},
+				v1.EndpointSubset{Addresses: addrs("10.0.0.3", "10.0.0.1")}),
+			genendpoints("unready", v1.EndpointSubset{NotReadyAddresses: addrs("10.0.0.1")}),
+			genendpoints("empty"),
+		},
+	}
+
+	mwatch := &mocks.WatchInterface{}
+	mwatch.On("ResultChan").Return(make(chan watch.Event))
+	mwatch.On("Stop").Return()
+
+	client := &mocks.Client{}
+	client.On("Watch", mock.Anything, mock.AnythingOfType("v1.ListOptions")).Return(mwatch, nil)
+	client.On("List", mock.Anything, mock.AnythingOfType("v1.ListOptions")).Return(list, nil)
+
+	controller, err := endpoints.BuildController(ctx, logutil.Default(), client)
+	require.NoError(t, err)
+	defer controller.Close()
+	testutil.AssertReady(t, "controller", controller)
+
+	for name, expected := range map[string][]string{
+		"single":  {"10.0.0.1", "10.0.0.2"},
+		"multi":   {"10.0.0.1", "10.0.0.3"},
+		"unready": nil,
+		"empty":   nil,
+		"missing": nil,
+	} {
+		ips, err := endpoints.ReadyAddresses(controller.Cache(), "ns", name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, expected, ips, name)
+	}
+}
@@ -0,0 +1,586 @@
+// This file was automatically generated by genny.
+// Any changes will be lost if this file is regenerated.
+// see https://github.com/cheekybits/genny
+
+package endpoints
+
+import (
+	"context"
+
+	"fmt"
+
+	"io"
+
+	"time"
+
+	logutil "github.com/boz/go-logutil"
+
+	"github.com/boz/kcache"
+
+	"github.com/boz/kcache/client"
+
+	"github.com/boz/kcache/filter"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/client-go/kubernetes"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/api/extensions/v1beta1"
+)
+
+var (
+	ErrInvalidType = fmt.Errorf("invalid type")
+	adapter        = _adapter{}
+)
+
+var _ = v1beta1.Deployment{}
+
+type Event interface {
+	Type() kcache.EventType
+	Resource() *v1.Endpoints
+	DeepCopyResource() *v1.Endpoints
+	ObservedAt() time.Time
+}
+
+type CacheReader interface {
+	Get(ns string, name string) (*v1.Endpoints, error)
+	GetByKey(key string) (*v1.Endpoints, error)
+	List() ([]*v1.Endpoints, error)
+	ByIndex(name string, value string) ([]*v1.Endpoints, error)
+}
+
+type CacheController interface {
+	Cache() CacheReader
+	Ready() <-chan struct{}
+	AddIndex(name string, fn kcache.IndexFunc) error
+}
+
+type Subscription interface {
+	CacheController
+	Events() <-chan Event
+	Close()
+	Done() <-chan struct{}
+}
+
+type Publisher interface {
+	Subscribe() (Subscription, error)
+	SubscribeWithFilter(filter.Filter) (FilterSubscription, error)
+	SubscribeForFilter() (FilterSubscription, error)
+	Clone() (Controller, error)
+	CloneWithFilter(filter.Filter) (FilterController, error)
+	CloneForFilter() (FilterController, error)
+}
+
+type Controller interface {
+	CacheController
+	Publisher
+	Done() <-chan struct{}
+	Close()
+	Error() error
+	WaitForSync(ctx context.Context) bool
+	Stats() kcache.Stats
+	Shutdown(ctx context.Context) error
+	Export(w io.Writer) error
+}
+
+type FilterSubscription interface {
+	Subscription
+	Refilter(filter.Filter) error
+	RefilterWithRate(f filter.Filter, limit float64) error
+}
+
+type FilterController interface {
+	Controller
+	Refilter(filter.Filter) error
+	RefilterWithRate(f filter.Filter, limit float64) error
+}
+
+type BaseHandler interface {
+	OnCreate(*v1.Endpoints)
+	OnUpdate(*v1.Endpoints)
+	OnDelete(*v1.Endpoints)
+}
+
+type Handler interface {
+	BaseHandler
+	OnInitialize([]*v1.Endpoints)
+}
+
+type HandlerBuilder interface {
+	OnInitialize(func([]*v1.Endpoints)) HandlerBuilder
+	OnCreate(func(*v1.Endpoints)) HandlerBuilder
+	OnUpdate(func(*v1.Endpoints)) HandlerBuilder
+	OnDelete(func(*v1.Endpoints)) HandlerBuilder
+	Create() Handler
+}
+
+type UnitaryHandler interface {
+	BaseHandler
+	OnInitialize(*v1.Endpoints)
+}
+
+type UnitaryHandlerBuilder interface {
+	OnInitialize(func(*v1.Endpoints)) UnitaryHandlerBuilder
+	OnCreate(func(*v1.Endpoints)) UnitaryHandlerBuilder
+	OnUpdate(func(*v1.Endpoints)) UnitaryHandlerBuilder
+	OnDelete(func(*v1.Endpoints)) UnitaryHandlerBuilder
+	Create() UnitaryHandler
+}
+
+type _adapter struct{}
+
+func (_adapter) adaptObject(obj metav1.Object) (*v1.Endpoints, error) {
+	if obj, ok := obj.(*v1.Endpoints); ok {
+		return obj, nil
+	}
+	return nil, ErrInvalidType
+}
+
+func (a _adapter) adaptList(objs []metav1.Object) ([]*v1.Endpoints, error) {
+	var ret []*v1.Endpoints
+	for _, orig := range objs {
+		adapted, err := a.adaptObject(orig)
+		if err != nil {
+			continue
+		}
+		ret = append(ret, adapted)
+	}
+	return ret, nil
+}
+
+func newCache(parent kcache.CacheReader) CacheReader {
+	return &cache{parent}
+}
+
+type cache struct {
+	parent kcache.CacheReader
+}
+
+func (c *cache) Get(ns string, name string) (*v1.Endpoints, error) {
+	obj, err := c.parent.Get(ns, name)
+	if err != nil {
+		return nil, err
+	}
+	return adapter.adaptObject(obj)
+}
+
+func (c *cache) GetByKey(key string) (*v1.Endpoints, error) {
+	obj, err := c.parent.GetByKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return adapter.adaptObject(obj)
+}
+
+func (c *cache) List() ([]*v1.Endpoints, error) {
+	objs, err := c.parent.List()
+	if err != nil {
+		return nil, err
+	}
+	return adapter.adaptList(objs)
+}
+
+func (c *cache) ByIndex(name string, value string) ([]*v1.Endpoints, error) {
+	objs, err := c.parent.ByIndex(name, value)
+	if err != nil {
+		return nil, err
+	}
+	return adapter.adaptList(objs)
+}
+
+type event struct {
+	etype    kcache.EventType
+	resource *v1.Endpoints
+	parent   kcache.Event
+}
+
+func wrapEvent(evt kcache.Event) (Event, error) {
+	obj, err := adapter.adaptObject(evt.Resource())
+	if err != nil {
+		return nil, err
+	}
+	return event{evt.Type(), obj, evt}, nil
+}
+
+func (e event) Type() kcache.EventType {
+	return e.etype
+}
+
+func (e event) Resource() *v1.Endpoints {
+	return e.resource
+}
+
+func (e event) DeepCopyResource() *v1.Endpoints {
+	obj, _ := adapter.adaptObject(e.parent.DeepCopyResource())
+	return obj
+}
+
+func (e event) ObservedAt() time.Time {
+	return e.parent.ObservedAt()
+}
+
+type subscription struct {
+	parent kcache.Subscription
+	cache  CacheReader
+	outch  chan Event
+	donech chan struct{}
+}
+
+func newSubscription(parent kcache.Subscription) *subscription {
+	s := &subscription{
+		parent: parent,
+		cache:  newCache(parent.Cache()),
+		outch:  make(chan Event, kcache.EventBufsiz),
+		donech: make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *subscription) run() {
+	defer close(s.donech)
+	defer func() { <-s.parent.Done() }()
+	defer close(s.outch)
+	for pevt := range s.parent.Events() {
+		evt, err := wrapEvent(pevt)
+		if err != nil {
+			continue
+		}
+		select {
+		case s.outch <- evt:
+		default:
+		}
+	}
+}
+
+func (s *subscription) Cache() CacheReader {
+	return s.cache
+}
+
+func (s *subscription) Ready() <-chan struct{} {
+	return s.parent.Ready()
+}
+
+func (s *subscription) AddIndex(name string, fn kcache.IndexFunc) error {
+	return s.parent.AddIndex(name, fn)
+}
+
+func (s *subscription) Events() <-chan Event {
+	return s.outch
+}
+
+func (s *subscription) Close() {
+	s.parent.Close()
+}
+
+func (s *subscription) Done() <-chan struct{} {
+	return s.donech
+}
+
+func NewController(ctx context.Context, log logutil.Log, cs kubernetes.Interface, ns string) (Controller, error) {
+	client := NewClient(cs, ns)
+	return BuildController(ctx, log, client)
+}
+
+func BuildController(ctx context.Context, log logutil.Log, client client.Client) (Controller, error) {
+	parent, err := kcache.NewController(ctx, log, client)
+	if err != nil {
+		return nil, err
+	}
+	return newController(parent), nil
+}
+
+func newController(parent kcache.Controller) *controller {
+	return &controller{parent, newCache(parent.Cache())}
+}
+
+type controller struct {
+	parent kcache.Controller
+	cache  CacheReader
+}
+
+func (c *controller) Close() {
+	c.parent.Close()
+}
+
+func (c *controller) Ready() <-chan struct{} {
+	return c.parent.Ready()
+}
+
+func (c *controller) AddIndex(name string, fn kcache.IndexFunc) error {
+	return c.parent.AddIndex(name, fn)
+}
+
+func (c *controller) Done() <-chan struct{} {
+	return c.parent.Done()
+}
+
+func (c *controller) WaitForSync(ctx context.Context) bool {
+	return c.parent.WaitForSync(ctx)
+}
+
+func (c *controller) Stats() kcache.Stats {
+	return c.parent.Stats()
+}
+
+func (c *controller) Export(w io.Writer) error {
+	return c.parent.Export(w)
+}
+
+func (c *controller) Shutdown(ctx context.Context) error {
+	return c.parent.Shutdown(ctx)
+}
+
+func (c *controller) Error() error {
+	return c.parent.Error()
+}
+
+func (c *controller) Cache() CacheReader {
+	return c.cache
+}
+
+func (c *controller) Subscribe() (Subscription, error) {
+	parent, err := c.parent.Subscribe()
+	if err != nil {
+		return nil, err
+	}
+	return newSubscription(parent), nil
+}
+
+func (c *controller) SubscribeWithFilter(f filter.Filter) (FilterSubscription, error) {
+	parent, err := c.parent.SubscribeWithFilter(f)
+	if err != nil {
+		return nil, err
+	}
+	return newFilterSubscription(parent), nil
+}
+
+func (c *controller) SubscribeForFilter() (FilterSubscription, error) {
+	parent, err := c.parent.SubscribeForFilter()
+	if err != nil {
+		return nil, err
+	}
+	return newFilterSubscription(parent), nil
+}
+
+func (c *controller) Clone() (Controller, error) {
+	parent, err := c.parent.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return newController(parent), nil
+}
+
+func (c *controller) CloneWithFilter(f filter.Filter) (FilterController, error) {
+	parent, err := c.parent.CloneWithFilter(f)
+	if err != nil {
+		return nil, err
+	}
+	return newFilterController(parent), nil
+}
+
+func (c *controller) CloneForFilter() (FilterController, error) {
+	parent, err := c.parent.CloneForFilter()
+	if err != nil {
+		return nil, err
+	}
+	return newFilterController(parent), nil
+}
+
+type filterController struct {
+	controller
+	filterParent kcache.FilterController
+}
+
+func newFilterController(parent kcache.FilterController) FilterController {
+	return &filterController{
+		controller:   controller{parent, newCache(parent.Cache())},
+		filterParent: parent,
+	}
+}
+
+func (c *filterController) Refilter(f filter.Filter) error {
+	return c.filterParent.Refilter(f)
+}
+
+func (c *filterController) RefilterWithRate(f filter.Filter, limit float64) error {
+	return c.filterParent.RefilterWithRate(f, limit)
+}
+
+type filterSubscription struct {
+	subscription
+	filterParent kcache.FilterSubscription
+}
+
+func newFilterSubscription(parent kcache.FilterSubscription) FilterSubscription {
+	return &filterSubscription{
+		subscription: *newSubscription(parent),
+		filterParent: parent,
+	}
+}
+
+func (s *filterSubscription) Refilter(f filter.Filter) error {
+	return s.filterParent.Refilter(f)
+}
+
+func (s *filterSubscription) RefilterWithRate(f filter.Filter, limit float64) error {
+	return s.filterParent.RefilterWithRate(f, limit)
+}
+
+func NewMonitor(publisher Publisher, handler Handler) (kcache.Monitor, error) {
+	phandler := kcache.BuildHandler().
+		OnInitialize(func(objs []metav1.Object) {
+			aobjs, _ := adapter.adaptList(objs)
+			handler.OnInitialize(aobjs)
+		}).
+		OnCreate(func(obj metav1.Object) {
+			aobj, _ := adapter.adaptObject(obj)
+			handler.OnCreate(aobj)
+		}).
+		OnUpdate(func(obj metav1.Object) {
+			aobj, _ := adapter.adaptObject(obj)
+			handler.OnUpdate(aobj)
+		}).
+		OnDelete(func(obj metav1.Object) {
+			aobj, _ := adapter.adaptObject(obj)
+			handler.OnDelete(aobj)
+		}).Create()
+
+	switch obj := publisher.(type) {
+	case *controller:
+		return kcache.NewMonitor(obj.parent, phandler)
+	case *filterController:
+		return kcache.NewMonitor(obj.parent, phandler)
+	default:
+		panic(fmt.Sprintf("Invalid publisher type: %T is not a *controller", publisher))
+	}
+}
+
+func ToUnitary(log logutil.Log, delegate UnitaryHandler) Handler {
+	return BuildHandler().
+		OnInitialize(func(objs []*v1.Endpoints) {
+			if count := len(objs); count > 1 {
+				log.Warnf("initialized with invalid count: %v", count)
+				return
+			}
+			if count := len(objs); count == 0 {
+				log.Debugf("initialized with empty result, ignoring")
+				return
+			}
+			delegate.OnInitialize(objs[0])
+		}).
+		OnCreate(func(obj *v1.Endpoints) {
+			delegate.OnCreate(obj)
+		}).
+		OnUpdate(func(obj *v1.Endpoints) {
+			delegate.OnUpdate(obj)
+		}).
+		OnDelete(func(obj *v1.Endpoints) {
+			delegate.OnDelete(obj)
+		}).Create()
+}
+
+func BuildHandler() HandlerBuilder {
+	return &handlerBuilder{}
+}
+
+func BuildUnitaryHandler() UnitaryHandlerBuilder {
+	return &unitaryHandlerBuilder{}
+}
+
+type baseHandler struct {
+	onCreate func(*v1.Endpoints)
+	onUpdate func(*v1.Endpoints)
+	onDelete func(*v1.Endpoints)
+}
+
+type handler struct {
+	baseHandler
+	onInitialize func([]*v1.Endpoints)
+}
+type handlerBuilder handler
+
+type unitaryHandler struct {
+	baseHandler
+	onInitialize func(*v1.Endpoints)
+}
+type unitaryHandlerBuilder unitaryHandler
+
+func (hb *handlerBuilder) OnInitialize(fn func([]*v1.Endpoints)) HandlerBuilder {
+	hb.onInitialize = fn
+	return hb
+}
+
+func (hb *handlerBuilder) OnCreate(fn func(*v1.Endpoints)) HandlerBuilder {
+	hb.onCreate = fn
+	return hb
+}
+
+func (hb *handlerBuilder) OnUpdate(fn func(*v1.Endpoints)) HandlerBuilder {
+	hb.onUpdate = fn
+	return hb
+}
+
+func (hb *handlerBuilder) OnDelete(fn func(*v1.Endpoints)) HandlerBuilder {
+	hb.onDelete = fn
+	return hb
+}
+
+func (hb *handlerBuilder) Create() Handler {
+	return handler(*hb)
+}
+
+func (h handler) OnInitialize(objs []*v1.Endpoints) {
+	if h.onInitialize != nil {
+		h.onInitialize(objs)
+	}
+}
+
+func (hb *unitaryHandlerBuilder) OnInitialize(fn func(*v1.Endpoints)) UnitaryHandlerBuilder {
+	hb.onInitialize = fn
+	return hb
+}
+
+func (hb *unitaryHandlerBuilder) OnCreate(fn func(*v1.Endpoints)) UnitaryHandlerBuilder {
+	hb.onCreate = fn
+	return hb
+}
+
+func (hb *unitaryHandlerBuilder) OnUpdate(fn func(*v1.Endpoints)) UnitaryHandlerBuilder {
+	hb.onUpdate = fn
+	return hb
+}
+
+func (hb *unitaryHandlerBuilder) OnDelete(fn func(*v1.Endpoints)) UnitaryHandlerBuilder {
+	hb.onDelete = fn
+	return hb
+}
+
+func (hb *unitaryHandlerBuilder) Create() UnitaryHandler {
+	return unitaryHandler(*hb)
+}
+
+func (h unitaryHandler) OnInitialize(obj *v1.Endpoints) {
+	if h.onInitialize != nil {
+		h.onInitialize(obj)
+	}
+}
+
+func (h baseHandler) OnCreate(obj *v1.Endpoints) {
+	if h.onCreate != nil {
+		h.onCreate(obj)
+	}
+}
+
+func (h baseHandler) OnUpdate(obj *v1.Endpoints) {
+	if h.onUpdate != nil {
+		h.onUpdate(obj)
+	}
+}
+
+func (h baseHandler) OnDelete(obj *v1.Endpoints) {
+	if h.onDelete != nil {
+		h.onDelete(obj)
+	}
+}
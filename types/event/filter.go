@@ -1,6 +1,8 @@
 package event
 
 import (
+	"fmt"
+
 	"github.com/boz/kcache/filter"
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -45,3 +47,7 @@ func (f *involvedFilter) Equals(other filter.Filter) bool {
 	}
 	return false
 }
+
+func (f *involvedFilter) String() string {
+	return fmt.Sprintf("InvolvedFilter(%v %v/%v)", f.kind, f.ns, f.name)
+}
@@ -1,6 +1,7 @@
 package event_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/boz/kcache/types/event"
@@ -63,3 +64,8 @@ func TestInvolvedForFilter(t *testing.T) {
 		assert.False(t, f.Equals(event.InvolvedFilter("service", "a", "b")))
 	}
 }
+
+func TestFilterString(t *testing.T) {
+	f := event.InvolvedFilter("Pod", "ns", "name")
+	assert.Equal(t, "InvolvedFilter(Pod ns/name)", fmt.Sprint(f))
+}
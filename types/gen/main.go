@@ -219,7 +219,7 @@ func TestController(t *testing.T) {
 		}
 
 		obj, err = c.Cache().Get(obj_b.GetNamespace(), obj_b.GetName())
-		assert.NoError(t, err, name)
+		assert.Equal(t, kcache.ErrNotFound, err, name)
 		assert.Nil(t, obj, name)
 
 	}
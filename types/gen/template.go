@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
+	"time"
 
 	logutil "github.com/boz/go-logutil"
 	"github.com/boz/kcache"
@@ -27,16 +29,21 @@ type ObjectType generic.Type
 type Event interface {
 	Type() kcache.EventType
 	Resource() ObjectType
+	DeepCopyResource() ObjectType
+	ObservedAt() time.Time
 }
 
 type CacheReader interface {
 	Get(ns string, name string) (ObjectType, error)
+	GetByKey(key string) (ObjectType, error)
 	List() ([]ObjectType, error)
+	ByIndex(name string, value string) ([]ObjectType, error)
 }
 
 type CacheController interface {
 	Cache() CacheReader
 	Ready() <-chan struct{}
+	AddIndex(name string, fn kcache.IndexFunc) error
 }
 
 type Subscription interface {
@@ -61,16 +68,22 @@ type Controller interface {
 	Done() <-chan struct{}
 	Close()
 	Error() error
+	WaitForSync(ctx context.Context) bool
+	Stats() kcache.Stats
+	Shutdown(ctx context.Context) error
+	Export(w io.Writer) error
 }
 
 type FilterSubscription interface {
 	Subscription
 	Refilter(filter.Filter) error
+	RefilterWithRate(f filter.Filter, limit float64) error
 }
 
 type FilterController interface {
 	Controller
 	Refilter(filter.Filter) error
+	RefilterWithRate(f filter.Filter, limit float64) error
 }
 
 type BaseHandler interface {
@@ -136,14 +149,18 @@ type cache struct {
 
 func (c *cache) Get(ns string, name string) (ObjectType, error) {
 	obj, err := c.parent.Get(ns, name)
-	switch {
-	case err != nil:
+	if err != nil {
 		return nil, err
-	case obj == nil:
-		return nil, nil
-	default:
-		return adapter.adaptObject(obj)
 	}
+	return adapter.adaptObject(obj)
+}
+
+func (c *cache) GetByKey(key string) (ObjectType, error) {
+	obj, err := c.parent.GetByKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return adapter.adaptObject(obj)
 }
 
 func (c *cache) List() ([]ObjectType, error) {
@@ -154,9 +171,18 @@ func (c *cache) List() ([]ObjectType, error) {
 	return adapter.adaptList(objs)
 }
 
+func (c *cache) ByIndex(name string, value string) ([]ObjectType, error) {
+	objs, err := c.parent.ByIndex(name, value)
+	if err != nil {
+		return nil, err
+	}
+	return adapter.adaptList(objs)
+}
+
 type event struct {
 	etype    kcache.EventType
 	resource ObjectType
+	parent   kcache.Event
 }
 
 func wrapEvent(evt kcache.Event) (Event, error) {
@@ -164,7 +190,7 @@ func wrapEvent(evt kcache.Event) (Event, error) {
 	if err != nil {
 		return nil, err
 	}
-	return event{evt.Type(), obj}, nil
+	return event{evt.Type(), obj, evt}, nil
 }
 
 func (e event) Type() kcache.EventType {
@@ -175,10 +201,20 @@ func (e event) Resource() ObjectType {
 	return e.resource
 }
 
+func (e event) DeepCopyResource() ObjectType {
+	obj, _ := adapter.adaptObject(e.parent.DeepCopyResource())
+	return obj
+}
+
+func (e event) ObservedAt() time.Time {
+	return e.parent.ObservedAt()
+}
+
 type subscription struct {
 	parent kcache.Subscription
 	cache  CacheReader
 	outch  chan Event
+	donech chan struct{}
 }
 
 func newSubscription(parent kcache.Subscription) *subscription {
@@ -186,12 +222,15 @@ func newSubscription(parent kcache.Subscription) *subscription {
 		parent: parent,
 		cache:  newCache(parent.Cache()),
 		outch:  make(chan Event, kcache.EventBufsiz),
+		donech: make(chan struct{}),
 	}
 	go s.run()
 	return s
 }
 
 func (s *subscription) run() {
+	defer close(s.donech)
+	defer func() { <-s.parent.Done() }()
 	defer close(s.outch)
 	for pevt := range s.parent.Events() {
 		evt, err := wrapEvent(pevt)
@@ -213,6 +252,10 @@ func (s *subscription) Ready() <-chan struct{} {
 	return s.parent.Ready()
 }
 
+func (s *subscription) AddIndex(name string, fn kcache.IndexFunc) error {
+	return s.parent.AddIndex(name, fn)
+}
+
 func (s *subscription) Events() <-chan Event {
 	return s.outch
 }
@@ -222,7 +265,7 @@ func (s *subscription) Close() {
 }
 
 func (s *subscription) Done() <-chan struct{} {
-	return s.parent.Done()
+	return s.donech
 }
 
 func NewController(ctx context.Context, log logutil.Log, cs kubernetes.Interface, ns string) (Controller, error) {
@@ -255,10 +298,30 @@ func (c *controller) Ready() <-chan struct{} {
 	return c.parent.Ready()
 }
 
+func (c *controller) AddIndex(name string, fn kcache.IndexFunc) error {
+	return c.parent.AddIndex(name, fn)
+}
+
 func (c *controller) Done() <-chan struct{} {
 	return c.parent.Done()
 }
 
+func (c *controller) WaitForSync(ctx context.Context) bool {
+	return c.parent.WaitForSync(ctx)
+}
+
+func (c *controller) Stats() kcache.Stats {
+	return c.parent.Stats()
+}
+
+func (c *controller) Export(w io.Writer) error {
+	return c.parent.Export(w)
+}
+
+func (c *controller) Shutdown(ctx context.Context) error {
+	return c.parent.Shutdown(ctx)
+}
+
 func (c *controller) Error() error {
 	return c.parent.Error()
 }
@@ -331,6 +394,10 @@ func (c *filterController) Refilter(f filter.Filter) error {
 	return c.filterParent.Refilter(f)
 }
 
+func (c *filterController) RefilterWithRate(f filter.Filter, limit float64) error {
+	return c.filterParent.RefilterWithRate(f, limit)
+}
+
 type filterSubscription struct {
 	subscription
 	filterParent kcache.FilterSubscription
@@ -347,6 +414,10 @@ func (s *filterSubscription) Refilter(f filter.Filter) error {
 	return s.filterParent.Refilter(f)
 }
 
+func (s *filterSubscription) RefilterWithRate(f filter.Filter, limit float64) error {
+	return s.filterParent.RefilterWithRate(f, limit)
+}
+
 func NewMonitor(publisher Publisher, handler Handler) (kcache.Monitor, error) {
 	phandler := kcache.BuildHandler().
 		OnInitialize(func(objs []metav1.Object) {
@@ -0,0 +1,31 @@
+// Package generic provides controllers for any resource whose type is
+// registered in a runtime.Scheme, without generated code.
+//
+// Objects in the cache and in events are of the registered concrete type,
+// such as *v1.Pod, and may be type-asserted by consumers.
+package generic
+
+import (
+	"context"
+
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache"
+	"github.com/boz/kcache/client"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+)
+
+func NewClient(c rest.Interface, s *runtime.Scheme, exemplar runtime.Object, res string, ns string) (client.Client, error) {
+	return client.ForSchemeResource(c, s, exemplar, res, ns)
+}
+
+// NewController() returns a controller for the resource res in namespace
+// ns, or in all namespaces if ns is empty.  Responses are decoded with s
+// into the type of exemplar.  See client.ForSchemeResource().
+func NewController(ctx context.Context, log logutil.Log, c rest.Interface, s *runtime.Scheme, exemplar runtime.Object, res string, ns string) (kcache.Controller, error) {
+	client, err := NewClient(c, s, exemplar, res, ns)
+	if err != nil {
+		return nil, err
+	}
+	return kcache.NewController(ctx, log, client)
+}
@@ -0,0 +1,124 @@
+package generic_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache"
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/testutil"
+	"github.com/boz/kcache/types/generic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+func testGenPod(name, vsn string, labels map[string]string) *v1.Pod {
+	return &v1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, ResourceVersion: vsn, Labels: labels},
+	}
+}
+
+func testRESTClient(t *testing.T, url string) rest.Interface {
+	c, err := rest.RESTClientFor(&rest.Config{
+		Host:    url,
+		APIPath: "/api",
+		ContentConfig: rest.ContentConfig{
+			GroupVersion:         &v1.SchemeGroupVersion,
+			NegotiatedSerializer: serializer.DirectCodecFactory{CodecFactory: scheme.Codecs},
+		},
+	})
+	require.NoError(t, err)
+	return c
+}
+
+func TestController(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watchch := make(chan *v1.Pod, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/namespaces/ns/pods", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&v1.PodList{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "PodList"},
+			ListMeta: metav1.ListMeta{ResourceVersion: "2"},
+			Items: []v1.Pod{
+				*testGenPod("a", "1", map[string]string{"app": "x"}),
+				*testGenPod("b", "2", nil),
+			},
+		})
+	})
+	mux.HandleFunc("/api/v1/watch/namespaces/ns/pods", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", runtime.ContentTypeJSON)
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case pod := <-watchch:
+				json.NewEncoder(w).Encode(map[string]interface{}{"type": "ADDED", "object": pod})
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	controller, err := generic.NewController(
+		ctx, logutil.Default(), testRESTClient(t, srv.URL), scheme.Scheme, &v1.Pod{}, "pods", "ns")
+	require.NoError(t, err)
+	defer controller.Close()
+
+	sub, err := controller.SubscribeWithFilter(filter.Labels(map[string]string{"app": "x"}))
+	require.NoError(t, err)
+
+	// lists over http.
+	select {
+	case <-sub.Ready():
+	case <-testutil.Timerch(ctx, time.Second):
+		require.Fail(t, "sub not ready")
+	}
+
+	list, err := sub.Cache().List()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.IsType(t, &v1.Pod{}, list[0])
+	assert.Equal(t, "a", list[0].GetName())
+
+	watchch <- testGenPod("c", "3", map[string]string{"app": "x"})
+
+	select {
+	case ev := <-sub.Events():
+		assert.Equal(t, kcache.EventTypeCreate, ev.Type())
+		require.IsType(t, &v1.Pod{}, ev.Resource())
+		assert.Equal(t, "c", ev.Resource().GetName())
+	case <-testutil.Timerch(ctx, time.Second):
+		assert.Fail(t, "no event")
+	}
+}
+
+func TestNewClient_unregistered(t *testing.T) {
+	c := testRESTClient(t, "http://localhost")
+
+	_, err := generic.NewClient(c, runtime.NewScheme(), &v1.Pod{}, "pods", "ns")
+	assert.Error(t, err)
+
+	// no list kind.
+	_, err = generic.NewClient(c, scheme.Scheme, &v1.Binding{}, "bindings", "ns")
+	assert.Error(t, err)
+}
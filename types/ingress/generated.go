@@ -9,6 +9,10 @@ import (
 
 	"fmt"
 
+	"io"
+
+	"time"
+
 	logutil "github.com/boz/go-logutil"
 
 	"github.com/boz/kcache"
@@ -34,16 +38,21 @@ var _ = v1beta1.Deployment{}
 type Event interface {
 	Type() kcache.EventType
 	Resource() *v1beta1.Ingress
+	DeepCopyResource() *v1beta1.Ingress
+	ObservedAt() time.Time
 }
 
 type CacheReader interface {
 	Get(ns string, name string) (*v1beta1.Ingress, error)
+	GetByKey(key string) (*v1beta1.Ingress, error)
 	List() ([]*v1beta1.Ingress, error)
+	ByIndex(name string, value string) ([]*v1beta1.Ingress, error)
 }
 
 type CacheController interface {
 	Cache() CacheReader
 	Ready() <-chan struct{}
+	AddIndex(name string, fn kcache.IndexFunc) error
 }
 
 type Subscription interface {
@@ -68,16 +77,22 @@ type Controller interface {
 	Done() <-chan struct{}
 	Close()
 	Error() error
+	WaitForSync(ctx context.Context) bool
+	Stats() kcache.Stats
+	Shutdown(ctx context.Context) error
+	Export(w io.Writer) error
 }
 
 type FilterSubscription interface {
 	Subscription
 	Refilter(filter.Filter) error
+	RefilterWithRate(f filter.Filter, limit float64) error
 }
 
 type FilterController interface {
 	Controller
 	Refilter(filter.Filter) error
+	RefilterWithRate(f filter.Filter, limit float64) error
 }
 
 type BaseHandler interface {
@@ -143,14 +158,18 @@ type cache struct {
 
 func (c *cache) Get(ns string, name string) (*v1beta1.Ingress, error) {
 	obj, err := c.parent.Get(ns, name)
-	switch {
-	case err != nil:
+	if err != nil {
+		return nil, err
+	}
+	return adapter.adaptObject(obj)
+}
+
+func (c *cache) GetByKey(key string) (*v1beta1.Ingress, error) {
+	obj, err := c.parent.GetByKey(key)
+	if err != nil {
 		return nil, err
-	case obj == nil:
-		return nil, nil
-	default:
-		return adapter.adaptObject(obj)
 	}
+	return adapter.adaptObject(obj)
 }
 
 func (c *cache) List() ([]*v1beta1.Ingress, error) {
@@ -161,9 +180,18 @@ func (c *cache) List() ([]*v1beta1.Ingress, error) {
 	return adapter.adaptList(objs)
 }
 
+func (c *cache) ByIndex(name string, value string) ([]*v1beta1.Ingress, error) {
+	objs, err := c.parent.ByIndex(name, value)
+	if err != nil {
+		return nil, err
+	}
+	return adapter.adaptList(objs)
+}
+
 type event struct {
 	etype    kcache.EventType
 	resource *v1beta1.Ingress
+	parent   kcache.Event
 }
 
 func wrapEvent(evt kcache.Event) (Event, error) {
@@ -171,7 +199,7 @@ func wrapEvent(evt kcache.Event) (Event, error) {
 	if err != nil {
 		return nil, err
 	}
-	return event{evt.Type(), obj}, nil
+	return event{evt.Type(), obj, evt}, nil
 }
 
 func (e event) Type() kcache.EventType {
@@ -182,10 +210,20 @@ func (e event) Resource() *v1beta1.Ingress {
 	return e.resource
 }
 
+func (e event) DeepCopyResource() *v1beta1.Ingress {
+	obj, _ := adapter.adaptObject(e.parent.DeepCopyResource())
+	return obj
+}
+
+func (e event) ObservedAt() time.Time {
+	return e.parent.ObservedAt()
+}
+
 type subscription struct {
 	parent kcache.Subscription
 	cache  CacheReader
 	outch  chan Event
+	donech chan struct{}
 }
 
 func newSubscription(parent kcache.Subscription) *subscription {
@@ -193,12 +231,15 @@ func newSubscription(parent kcache.Subscription) *subscription {
 		parent: parent,
 		cache:  newCache(parent.Cache()),
 		outch:  make(chan Event, kcache.EventBufsiz),
+		donech: make(chan struct{}),
 	}
 	go s.run()
 	return s
 }
 
 func (s *subscription) run() {
+	defer close(s.donech)
+	defer func() { <-s.parent.Done() }()
 	defer close(s.outch)
 	for pevt := range s.parent.Events() {
 		evt, err := wrapEvent(pevt)
@@ -220,6 +261,10 @@ func (s *subscription) Ready() <-chan struct{} {
 	return s.parent.Ready()
 }
 
+func (s *subscription) AddIndex(name string, fn kcache.IndexFunc) error {
+	return s.parent.AddIndex(name, fn)
+}
+
 func (s *subscription) Events() <-chan Event {
 	return s.outch
 }
@@ -229,7 +274,7 @@ func (s *subscription) Close() {
 }
 
 func (s *subscription) Done() <-chan struct{} {
-	return s.parent.Done()
+	return s.donech
 }
 
 func NewController(ctx context.Context, log logutil.Log, cs kubernetes.Interface, ns string) (Controller, error) {
@@ -262,10 +307,30 @@ func (c *controller) Ready() <-chan struct{} {
 	return c.parent.Ready()
 }
 
+func (c *controller) AddIndex(name string, fn kcache.IndexFunc) error {
+	return c.parent.AddIndex(name, fn)
+}
+
 func (c *controller) Done() <-chan struct{} {
 	return c.parent.Done()
 }
 
+func (c *controller) WaitForSync(ctx context.Context) bool {
+	return c.parent.WaitForSync(ctx)
+}
+
+func (c *controller) Stats() kcache.Stats {
+	return c.parent.Stats()
+}
+
+func (c *controller) Export(w io.Writer) error {
+	return c.parent.Export(w)
+}
+
+func (c *controller) Shutdown(ctx context.Context) error {
+	return c.parent.Shutdown(ctx)
+}
+
 func (c *controller) Error() error {
 	return c.parent.Error()
 }
@@ -338,6 +403,10 @@ func (c *filterController) Refilter(f filter.Filter) error {
 	return c.filterParent.Refilter(f)
 }
 
+func (c *filterController) RefilterWithRate(f filter.Filter, limit float64) error {
+	return c.filterParent.RefilterWithRate(f, limit)
+}
+
 type filterSubscription struct {
 	subscription
 	filterParent kcache.FilterSubscription
@@ -354,6 +423,10 @@ func (s *filterSubscription) Refilter(f filter.Filter) error {
 	return s.filterParent.Refilter(f)
 }
 
+func (s *filterSubscription) RefilterWithRate(f filter.Filter, limit float64) error {
+	return s.filterParent.RefilterWithRate(f, limit)
+}
+
 func NewMonitor(publisher Publisher, handler Handler) (kcache.Monitor, error) {
 	phandler := kcache.BuildHandler().
 		OnInitialize(func(objs []metav1.Object) {
@@ -2,10 +2,15 @@ package pod
 
 import (
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/nsname"
+	"github.com/boz/kcache/types/service"
 	"k8s.io/api/core/v1"
 )
 
@@ -34,3 +39,267 @@ func (f nodeFilter) Equals(other filter.Filter) bool {
 	}
 	return false
 }
+
+func (f nodeFilter) String() string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "NodeFilter(" + strings.Join(names, ",") + ")"
+}
+
+// PhaseFilter() returns a filter whose Accept() returns true
+// if the object is a Pod in one of the given phases.
+func PhaseFilter(phases ...v1.PodPhase) filter.ComparableFilter {
+	set := make(map[v1.PodPhase]interface{})
+	for _, phase := range phases {
+		set[phase] = struct{}{}
+	}
+	return phaseFilter(set)
+}
+
+type phaseFilter map[v1.PodPhase]interface{}
+
+func (f phaseFilter) Accept(obj metav1.Object) bool {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return false
+	}
+	_, ok = f[pod.Status.Phase]
+	return ok
+}
+
+func (f phaseFilter) Equals(other filter.Filter) bool {
+	if other, ok := other.(phaseFilter); ok {
+		return reflect.DeepEqual(f, other)
+	}
+	return false
+}
+
+func (f phaseFilter) String() string {
+	phases := make([]string, 0, len(f))
+	for phase := range f {
+		phases = append(phases, string(phase))
+	}
+	sort.Strings(phases)
+	return "PhaseFilter(" + strings.Join(phases, ",") + ")"
+}
+
+// ReadyFilter() returns a filter whose Accept() returns true
+// if the object is a Pod whose Ready condition is true.
+func ReadyFilter() filter.ComparableFilter {
+	return readyFilter{}
+}
+
+type readyFilter struct{}
+
+func (readyFilter) Accept(obj metav1.Object) bool {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == v1.PodReady {
+			return cond.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func (readyFilter) Equals(other filter.Filter) bool {
+	_, ok := other.(readyFilter)
+	return ok
+}
+
+func (readyFilter) String() string {
+	return "ReadyFilter()"
+}
+
+// ContainerReadyFilter() returns a filter whose Accept() returns true
+// if the object is a Pod whose status reports the named container as
+// ready.  Unlike ReadyFilter(), it ignores the readiness of other
+// containers, such as sidecars.
+func ContainerReadyFilter(name string) filter.ComparableFilter {
+	return containerReadyFilter(name)
+}
+
+type containerReadyFilter string
+
+func (f containerReadyFilter) Accept(obj metav1.Object) bool {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return false
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == string(f) {
+			return status.Ready
+		}
+	}
+	return false
+}
+
+func (f containerReadyFilter) Equals(other filter.Filter) bool {
+	if other, ok := other.(containerReadyFilter); ok {
+		return f == other
+	}
+	return false
+}
+
+func (f containerReadyFilter) String() string {
+	return "ContainerReadyFilter(" + string(f) + ")"
+}
+
+// ImageFilter() returns a filter whose Accept() returns true if the
+// object is a Pod with a container or init container whose image is ref
+// or begins with ref.
+//
+// An image without a tag or digest is treated as having the "latest"
+// tag, so ImageFilter("nginx:latest") accepts Pods running "nginx".
+func ImageFilter(ref string) filter.ComparableFilter {
+	return imageFilter(ref)
+}
+
+type imageFilter string
+
+func (f imageFilter) Accept(obj metav1.Object) bool {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return false
+	}
+	for _, c := range pod.Spec.InitContainers {
+		if f.matches(c.Image) {
+			return true
+		}
+	}
+	for _, c := range pod.Spec.Containers {
+		if f.matches(c.Image) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f imageFilter) matches(image string) bool {
+	ref := string(f)
+	return strings.HasPrefix(image, ref) ||
+		normalizeImage(image) == normalizeImage(ref)
+}
+
+func (f imageFilter) Equals(other filter.Filter) bool {
+	if other, ok := other.(imageFilter); ok {
+		return f == other
+	}
+	return false
+}
+
+func (f imageFilter) String() string {
+	return "ImageFilter(" + string(f) + ")"
+}
+
+// normalizeImage() adds the implicit "latest" tag to image if it has
+// neither a tag nor a digest.
+func normalizeImage(image string) string {
+	if image == "" || strings.Contains(image, "@") {
+		return image
+	}
+	// a colon before the last slash separates a registry port.
+	if strings.LastIndex(image, ":") > strings.LastIndex(image, "/") {
+		return image
+	}
+	return image + ":latest"
+}
+
+// ContainerPortFilter() returns a filter whose Accept() returns true if
+// the object is a Pod with a container that declares the given container
+// port.
+func ContainerPortFilter(port int32) filter.ComparableFilter {
+	return containerPortFilter(port)
+}
+
+type containerPortFilter int32
+
+func (f containerPortFilter) Accept(obj metav1.Object) bool {
+	return acceptContainerPort(obj, func(port v1.ContainerPort) bool {
+		return port.ContainerPort == int32(f)
+	})
+}
+
+func (f containerPortFilter) Equals(other filter.Filter) bool {
+	if other, ok := other.(containerPortFilter); ok {
+		return f == other
+	}
+	return false
+}
+
+func (f containerPortFilter) String() string {
+	return "ContainerPortFilter(" + strconv.Itoa(int(f)) + ")"
+}
+
+// NamedPortFilter() returns a filter whose Accept() returns true if the
+// object is a Pod with a container that declares a port with the given
+// name.
+func NamedPortFilter(name string) filter.ComparableFilter {
+	return namedPortFilter(name)
+}
+
+type namedPortFilter string
+
+func (f namedPortFilter) Accept(obj metav1.Object) bool {
+	return acceptContainerPort(obj, func(port v1.ContainerPort) bool {
+		return port.Name == string(f)
+	})
+}
+
+func (f namedPortFilter) Equals(other filter.Filter) bool {
+	if other, ok := other.(namedPortFilter); ok {
+		return f == other
+	}
+	return false
+}
+
+func (f namedPortFilter) String() string {
+	return "NamedPortFilter(" + string(f) + ")"
+}
+
+// acceptContainerPort() returns true if obj is a Pod with a container
+// port for which fn returns true.
+func acceptContainerPort(obj metav1.Object, fn func(v1.ContainerPort) bool) bool {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return false
+	}
+	for _, c := range pod.Spec.Containers {
+		for _, port := range c.Ports {
+			if fn(port) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ServicesFilter() returns a filter whose Accept() returns true if
+// the object is a Service that selects any of the given pods.
+func ServicesFilter(pods ...*v1.Pod) filter.ComparableFilter {
+
+	// make a copy and sort
+	sorted := make([]*v1.Pod, len(pods))
+	copy(sorted, pods)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return nsname.ForObject(sorted[i]).Less(nsname.ForObject(sorted[j]))
+	})
+
+	var filters []filter.Filter
+
+	for _, pod := range sorted {
+		if len(pod.Labels) > 0 {
+			nsfilter := filter.NSName(nsname.New(pod.GetNamespace(), ""))
+			sfilter := service.SelectorMatchFilter(pod.Labels)
+			filters = append(filters, filter.And(nsfilter, sfilter))
+		}
+	}
+
+	return filter.Or(filters...)
+}
@@ -1,6 +1,7 @@
 package pod_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/boz/kcache/types/pod"
@@ -34,8 +35,203 @@ func TestNodeFilter(t *testing.T) {
 	assert.False(t, pod.NodeFilter().Equals(other))
 }
 
+func TestPhaseFilter(t *testing.T) {
+
+	genpod := func(name string, phase v1.PodPhase) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     v1.PodStatus{Phase: phase},
+		}
+	}
+
+	assert.True(t, pod.PhaseFilter(v1.PodRunning).Accept(genpod("x", v1.PodRunning)))
+	assert.True(t, pod.PhaseFilter(v1.PodPending, v1.PodRunning).Accept(genpod("x", v1.PodRunning)))
+	assert.False(t, pod.PhaseFilter().Accept(genpod("x", v1.PodRunning)))
+	assert.False(t, pod.PhaseFilter(v1.PodRunning).Accept(genpod("x", v1.PodPending)))
+	assert.False(t, pod.PhaseFilter(v1.PodRunning).Accept(&v1.Service{}))
+
+	assert.True(t, pod.PhaseFilter().Equals(pod.PhaseFilter()))
+	assert.True(t, pod.PhaseFilter(v1.PodRunning).Equals(pod.PhaseFilter(v1.PodRunning)))
+	assert.True(t, pod.PhaseFilter(v1.PodFailed, v1.PodSucceeded).Equals(pod.PhaseFilter(v1.PodSucceeded, v1.PodFailed)))
+	assert.False(t, pod.PhaseFilter(v1.PodRunning).Equals(pod.PhaseFilter(v1.PodPending)))
+	assert.False(t, pod.PhaseFilter().Equals(pod.NodeFilter()))
+}
+
+func TestReadyFilter(t *testing.T) {
+
+	genpod := func(conditions ...v1.PodCondition) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "x"},
+			Status:     v1.PodStatus{Conditions: conditions},
+		}
+	}
+
+	ready := v1.PodCondition{Type: v1.PodReady, Status: v1.ConditionTrue}
+	notReady := v1.PodCondition{Type: v1.PodReady, Status: v1.ConditionFalse}
+	unknown := v1.PodCondition{Type: v1.PodReady, Status: v1.ConditionUnknown}
+	scheduled := v1.PodCondition{Type: v1.PodScheduled, Status: v1.ConditionTrue}
+
+	assert.True(t, pod.ReadyFilter().Accept(genpod(ready)))
+	assert.True(t, pod.ReadyFilter().Accept(genpod(scheduled, ready)))
+	assert.False(t, pod.ReadyFilter().Accept(genpod(notReady)))
+	assert.False(t, pod.ReadyFilter().Accept(genpod(unknown)))
+	assert.False(t, pod.ReadyFilter().Accept(genpod(scheduled)))
+	assert.False(t, pod.ReadyFilter().Accept(genpod()))
+	assert.False(t, pod.ReadyFilter().Accept(&v1.Service{}))
+
+	assert.True(t, pod.ReadyFilter().Equals(pod.ReadyFilter()))
+	assert.False(t, pod.ReadyFilter().Equals(pod.PhaseFilter(v1.PodRunning)))
+}
+
+func TestContainerReadyFilter(t *testing.T) {
+
+	genpod := func(statuses ...v1.ContainerStatus) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "x"},
+			Status:     v1.PodStatus{ContainerStatuses: statuses},
+		}
+	}
+
+	app := v1.ContainerStatus{Name: "app", Ready: true}
+	appNotReady := v1.ContainerStatus{Name: "app"}
+	sidecar := v1.ContainerStatus{Name: "sidecar", Ready: true}
+	sidecarNotReady := v1.ContainerStatus{Name: "sidecar"}
+
+	f := pod.ContainerReadyFilter("app")
+
+	assert.True(t, f.Accept(genpod(app)))
+	assert.True(t, f.Accept(genpod(sidecarNotReady, app)))
+	assert.False(t, f.Accept(genpod(appNotReady)))
+	assert.False(t, f.Accept(genpod(sidecar, appNotReady)))
+	assert.False(t, f.Accept(genpod(sidecar)))
+	assert.False(t, f.Accept(genpod()))
+	assert.False(t, f.Accept(&v1.Service{}))
+
+	assert.True(t, f.Equals(pod.ContainerReadyFilter("app")))
+	assert.False(t, f.Equals(pod.ContainerReadyFilter("sidecar")))
+	assert.False(t, f.Equals(pod.ReadyFilter()))
+}
+
+func TestFilterString(t *testing.T) {
+	assert.Equal(t, "NodeFilter(a,b)", fmt.Sprint(pod.NodeFilter("b", "a")))
+	assert.Equal(t, "PhaseFilter(Pending,Running)", fmt.Sprint(pod.PhaseFilter(v1.PodRunning, v1.PodPending)))
+	assert.Equal(t, "ReadyFilter()", fmt.Sprint(pod.ReadyFilter()))
+	assert.Equal(t, "ContainerReadyFilter(app)", fmt.Sprint(pod.ContainerReadyFilter("app")))
+	assert.Equal(t, "ImageFilter(nginx)", fmt.Sprint(pod.ImageFilter("nginx")))
+	assert.Equal(t, "ContainerPortFilter(80)", fmt.Sprint(pod.ContainerPortFilter(80)))
+	assert.Equal(t, "NamedPortFilter(http)", fmt.Sprint(pod.NamedPortFilter("http")))
+}
+
+func TestContainerPortFilter(t *testing.T) {
+
+	genpod := func(ports ...v1.ContainerPort) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "x"},
+			Spec: v1.PodSpec{
+				InitContainers: []v1.Container{{Ports: []v1.ContainerPort{{Name: "init", ContainerPort: 9000}}}},
+				Containers:     []v1.Container{{}, {Ports: ports}},
+			},
+		}
+	}
+
+	http := v1.ContainerPort{Name: "http", ContainerPort: 80}
+	metrics := v1.ContainerPort{Name: "metrics", ContainerPort: 9090}
+
+	assert.True(t, pod.ContainerPortFilter(80).Accept(genpod(http)))
+	assert.True(t, pod.ContainerPortFilter(9090).Accept(genpod(http, metrics)))
+	assert.False(t, pod.ContainerPortFilter(8080).Accept(genpod(http, metrics)))
+	assert.False(t, pod.ContainerPortFilter(9000).Accept(genpod(http)))
+	assert.False(t, pod.ContainerPortFilter(80).Accept(genpod()))
+	assert.False(t, pod.ContainerPortFilter(80).Accept(&v1.Service{}))
+
+	assert.True(t, pod.NamedPortFilter("http").Accept(genpod(http)))
+	assert.True(t, pod.NamedPortFilter("metrics").Accept(genpod(http, metrics)))
+	assert.False(t, pod.NamedPortFilter("grpc").Accept(genpod(http, metrics)))
+	assert.False(t, pod.NamedPortFilter("init").Accept(genpod(http)))
+	assert.False(t, pod.NamedPortFilter("http").Accept(&v1.Service{}))
+
+	assert.True(t, pod.ContainerPortFilter(80).Equals(pod.ContainerPortFilter(80)))
+	assert.False(t, pod.ContainerPortFilter(80).Equals(pod.ContainerPortFilter(81)))
+	assert.False(t, pod.ContainerPortFilter(80).Equals(pod.NamedPortFilter("80")))
+	assert.True(t, pod.NamedPortFilter("http").Equals(pod.NamedPortFilter("http")))
+	assert.False(t, pod.NamedPortFilter("http").Equals(pod.NamedPortFilter("https")))
+	assert.False(t, pod.NamedPortFilter("http").Equals(pod.ImageFilter("http")))
+}
+
+func TestImageFilter(t *testing.T) {
+
+	genpod := func(init string, images ...string) *v1.Pod {
+		obj := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "x"}}
+		if init != "" {
+			obj.Spec.InitContainers = []v1.Container{{Image: init}}
+		}
+		for _, image := range images {
+			obj.Spec.Containers = append(obj.Spec.Containers, v1.Container{Image: image})
+		}
+		return obj
+	}
+
+	nginx := pod.ImageFilter("nginx")
+	assert.True(t, nginx.Accept(genpod("", "nginx")))
+	assert.True(t, nginx.Accept(genpod("", "nginx:1.15")))
+	assert.True(t, nginx.Accept(genpod("", "busybox", "nginx:latest")))
+	assert.True(t, nginx.Accept(genpod("nginx:1.15", "busybox")))
+	assert.False(t, nginx.Accept(genpod("", "busybox")))
+	assert.False(t, nginx.Accept(genpod("")))
+	assert.False(t, nginx.Accept(&v1.Service{}))
+
+	latest := pod.ImageFilter("nginx:latest")
+	assert.True(t, latest.Accept(genpod("", "nginx")))
+	assert.True(t, latest.Accept(genpod("", "nginx:latest")))
+	assert.False(t, latest.Accept(genpod("", "nginx:1.15")))
+	assert.False(t, latest.Accept(genpod("", "nginx@sha256:abcd")))
+
+	registry := pod.ImageFilter("registry.local:5000/app:latest")
+	assert.True(t, registry.Accept(genpod("", "registry.local:5000/app")))
+	assert.False(t, registry.Accept(genpod("", "registry.local:5000/app:v2")))
+
+	prefix := pod.ImageFilter("gcr.io/project/")
+	assert.True(t, prefix.Accept(genpod("", "gcr.io/project/app:v1")))
+	assert.False(t, prefix.Accept(genpod("", "gcr.io/other/app:v1")))
+
+	assert.True(t, nginx.Equals(pod.ImageFilter("nginx")))
+	assert.False(t, nginx.Equals(latest))
+	assert.False(t, nginx.Equals(pod.NodeFilter("nginx")))
+}
+
 type otherFilter map[string]interface{}
 
 func (otherFilter) Accept(_ metav1.Object) bool {
 	return false
 }
+
+func TestServicesFilter(t *testing.T) {
+	genpod := func(ns, name string, labels map[string]string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name, Labels: labels}}
+	}
+	gensvc := func(ns string, selector map[string]string) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: ns},
+			Spec:       v1.ServiceSpec{Selector: selector},
+		}
+	}
+
+	p1 := genpod("a", "1", map[string]string{"app": "x", "tier": "web"})
+	p2 := genpod("a", "2", map[string]string{"app": "y"})
+
+	f := pod.ServicesFilter(p1)
+	assert.True(t, f.Accept(gensvc("a", map[string]string{"app": "x"})))
+	assert.True(t, f.Accept(gensvc("a", map[string]string{"app": "x", "tier": "web"})))
+	assert.False(t, f.Accept(gensvc("a", map[string]string{"app": "x", "tier": "db"})))
+	assert.False(t, f.Accept(gensvc("a", map[string]string{"app": "x", "other": "z"})))
+	assert.False(t, f.Accept(gensvc("a", nil)))
+	assert.False(t, f.Accept(gensvc("b", map[string]string{"app": "x"})))
+	assert.False(t, f.Accept(p1))
+
+	assert.True(t, pod.ServicesFilter(p1, p2).Accept(gensvc("a", map[string]string{"app": "y"})))
+	assert.False(t, pod.ServicesFilter().Accept(gensvc("a", map[string]string{"app": "y"})))
+	assert.False(t, pod.ServicesFilter(genpod("a", "3", nil)).Accept(gensvc("a", map[string]string{"app": "y"})))
+
+	assert.True(t, pod.ServicesFilter(p1, p2).Equals(pod.ServicesFilter(p2, p1)))
+	assert.False(t, pod.ServicesFilter(p1).Equals(pod.ServicesFilter(p2)))
+}
@@ -0,0 +1,45 @@
+package pod
+
+import (
+	"context"
+
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache"
+	"github.com/boz/kcache/client"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NodeIndex is the name of the index created with NodeIndexFunc.
+const NodeIndex = "pod.spec.nodeName"
+
+// NodeIndexFunc() indexes pods by the node they are scheduled on.
+// Unscheduled pods are not indexed.
+func NodeIndexFunc(obj metav1.Object) []string {
+	pod, ok := obj.(*v1.Pod)
+	if !ok || pod.Spec.NodeName == "" {
+		return nil
+	}
+	return []string{pod.Spec.NodeName}
+}
+
+// BuildNodeIndexedController() returns a controller whose cache
+// supports ListByNode().
+func BuildNodeIndexedController(ctx context.Context, log logutil.Log, client client.Client) (Controller, error) {
+	parent, err := kcache.NewBuilder().
+		Context(ctx).
+		Log(log).
+		Client(client).
+		Index(NodeIndex, NodeIndexFunc).
+		Create()
+	if err != nil {
+		return nil, err
+	}
+	return newController(parent), nil
+}
+
+// ListByNode() returns the pods in cache that are scheduled on node.
+// The cache must be indexed by NodeIndex.
+func ListByNode(cache CacheReader, node string) ([]*v1.Pod, error) {
+	return cache.ByIndex(NodeIndex, node)
+}
@@ -0,0 +1,97 @@
+package pod_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/client/mocks"
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/testutil"
+	"github.com/boz/kcache/types/pod"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestListByNode(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	genpod := func(name, node, vsn string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, ResourceVersion: vsn},
+			Spec:       v1.PodSpec{NodeName: node},
+		}
+	}
+
+	eventch := make(chan watch.Event, 10)
+	mwatch := &mocks.WatchInterface{}
+	mwatch.On("ResultChan").Return(eventch)
+	mwatch.On("Stop").Return()
+
+	client := &mocks.Client{}
+	client.On("Watch", mock.Anything, mock.AnythingOfType("v1.ListOptions")).Return(mwatch, nil)
+	client.On("List", mock.Anything, mock.AnythingOfType("v1.ListOptions")).
+		Return(&v1.PodList{
+			ListMeta: metav1.ListMeta{ResourceVersion: "3"},
+			Items: []v1.Pod{
+				*genpod("a", "n1", "1"),
+				*genpod("b", "n1", "2"),
+				*genpod("c", "", "3"),
+			},
+		}, nil)
+
+	controller, err := pod.BuildNodeIndexedController(ctx, logutil.Default(), client)
+	require.NoError(t, err)
+	defer controller.Close()
+
+	sub, err := controller.SubscribeWithFilter(filter.Null())
+	require.NoError(t, err)
+
+	testutil.AssertReady(t, "controller", controller)
+	testutil.AssertReady(t, "sub", sub)
+
+	names := func(c pod.CacheReader, node string) []string {
+		pods, err := pod.ListByNode(c, node)
+		require.NoError(t, err)
+		var names []string
+		for _, p := range pods {
+			names = append(names, p.GetName())
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	assert.Equal(t, []string{"a", "b"}, names(controller.Cache(), "n1"))
+	assert.Empty(t, names(controller.Cache(), "n2"))
+	assert.Equal(t, []string{"a", "b"}, names(sub.Cache(), "n1"))
+
+	eventch <- watch.Event{Type: watch.Modified, Object: genpod("b", "n2", "4")}
+	eventch <- watch.Event{Type: watch.Modified, Object: genpod("c", "n2", "5")}
+	eventch <- watch.Event{Type: watch.Deleted, Object: genpod("a", "n1", "6")}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-sub.Events():
+		case <-testutil.Timerch(ctx, time.Second):
+			require.Fail(t, "no event")
+		}
+	}
+
+	for name, c := range map[string]pod.CacheReader{"controller": controller.Cache(), "sub": sub.Cache()} {
+		assert.Empty(t, names(c, "n1"), name)
+		assert.Equal(t, []string{"b", "c"}, names(c, "n2"), name)
+	}
+}
+
+func TestNodeIndexFunc(t *testing.T) {
+	assert.Equal(t, []string{"n1"}, pod.NodeIndexFunc(&v1.Pod{Spec: v1.PodSpec{NodeName: "n1"}}))
+	assert.Empty(t, pod.NodeIndexFunc(&v1.Pod{}))
+	assert.Empty(t, pod.NodeIndexFunc(&v1.Service{}))
+}
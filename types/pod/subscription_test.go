@@ -0,0 +1,51 @@
+package pod_test
+
+import (
+	"context"
+	"testing"
+
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache/kcachetest"
+	"github.com/boz/kcache/testutil"
+	"github.com/boz/kcache/types/pod"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSubscription_closeBeforeDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client, err := kcachetest.NewClient()
+	require.NoError(t, err)
+
+	controller, err := pod.BuildController(ctx, logutil.Default(), client)
+	require.NoError(t, err)
+	defer controller.Close()
+
+	sub, err := controller.Subscribe()
+	require.NoError(t, err)
+	testutil.AssertReady(t, "sub", sub)
+
+	require.NoError(t, client.Create(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "a"}}))
+
+	select {
+	case ev := <-sub.Events():
+		assert.Equal(t, "a", ev.Resource().GetName())
+	case <-testutil.AsyncWaitch(ctx):
+		require.Fail(t, "no event")
+	}
+
+	sub.Close()
+	testutil.AssertDone(t, "sub", sub)
+
+	// Events() is closed before Done().
+	select {
+	case _, ok := <-sub.Events():
+		assert.False(t, ok)
+	default:
+		assert.Fail(t, "events open after done")
+	}
+}
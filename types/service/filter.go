@@ -12,10 +12,26 @@ import (
 
 // SelectorMatchFilter() removes all objects that are not services whose
 // selector matches the given target.
+//
+// A service matches when its selector is a non-empty subset of target:
+// that is, when the service would select a pod labeled with target.
+// See SelectingFilter().
 func SelectorMatchFilter(target map[string]string) filter.ComparableFilter {
 	return &serviceForFilter{target}
 }
 
+// SelectingFilter() returns a filter whose Accept() returns true if the
+// object is a service that selects objects labeled with labels.
+//
+// Every key and value of the service's selector must be present in labels;
+// labels may contain additional keys.  Services with an empty selector
+// select nothing and are never accepted.
+//
+// SelectingFilter() is equivalent to SelectorMatchFilter().
+func SelectingFilter(labels map[string]string) filter.ComparableFilter {
+	return SelectorMatchFilter(labels)
+}
+
 type serviceForFilter struct {
 	target map[string]string
 }
@@ -49,6 +65,10 @@ func (f *serviceForFilter) Equals(other filter.Filter) bool {
 	return false
 }
 
+func (f *serviceForFilter) String() string {
+	return "SelectorMatchFilter(" + labels.Set(f.target).String() + ")"
+}
+
 func PodsFilter(services ...*v1.Service) filter.ComparableFilter {
 
 	// make a copy and sort
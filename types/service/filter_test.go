@@ -1,6 +1,7 @@
 package service_test
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/boz/kcache/types/service"
@@ -102,3 +103,40 @@ func TestPodsFilter(t *testing.T) {
 	assert.True(t, service.PodsFilter(s4, s3).Equals(service.PodsFilter(s3, s4)))
 
 }
+
+func TestFilterString(t *testing.T) {
+	f := service.SelectorMatchFilter(map[string]string{"b": "2", "a": "1"})
+	assert.Equal(t, "SelectorMatchFilter(a=1,b=2)", fmt.Sprint(f))
+}
+
+func TestSelectingFilter(t *testing.T) {
+	gensvc := func(selector map[string]string) metav1.Object {
+		return &v1.Service{Spec: v1.ServiceSpec{Selector: selector}}
+	}
+
+	labels := map[string]string{"app": "x", "tier": "web"}
+	f := service.SelectingFilter(labels)
+
+	// selector is a subset of the labels.
+	assert.True(t, f.Accept(gensvc(map[string]string{"app": "x"})))
+	assert.True(t, f.Accept(gensvc(map[string]string{"app": "x", "tier": "web"})))
+
+	// selector is a superset of the labels.
+	assert.False(t, f.Accept(gensvc(map[string]string{"app": "x", "tier": "web", "env": "prod"})))
+
+	// partial overlap.
+	assert.False(t, f.Accept(gensvc(map[string]string{"app": "x", "env": "prod"})))
+	assert.False(t, f.Accept(gensvc(map[string]string{"app": "x", "tier": "db"})))
+
+	// disjoint.
+	assert.False(t, f.Accept(gensvc(map[string]string{"env": "prod"})))
+
+	// empty selector.
+	assert.False(t, f.Accept(gensvc(nil)))
+	assert.False(t, f.Accept(gensvc(map[string]string{})))
+
+	// empty labels.
+	assert.False(t, service.SelectingFilter(nil).Accept(gensvc(map[string]string{"app": "x"})))
+
+	assert.True(t, f.Equals(service.SelectorMatchFilter(labels)))
+}
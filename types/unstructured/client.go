@@ -0,0 +1,25 @@
+// Package unstructured provides controllers for arbitrary resources,
+// including custom resources, using the dynamic client.
+//
+// Objects in the cache and in events are *unstructured.Unstructured.
+package unstructured
+
+import (
+	"context"
+
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache"
+	"github.com/boz/kcache/client"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+func NewClient(dc dynamic.Interface, gvr schema.GroupVersionResource, ns string) client.Client {
+	return client.ForDynamicResource(dc, gvr, ns)
+}
+
+// NewController() returns a controller for the resource gvr in namespace
+// ns, or in all namespaces if ns is empty.
+func NewController(ctx context.Context, log logutil.Log, dc dynamic.Interface, gvr schema.GroupVersionResource, ns string) (kcache.Controller, error) {
+	return kcache.NewController(ctx, log, NewClient(dc, gvr, ns))
+}
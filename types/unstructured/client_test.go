@@ -0,0 +1,110 @@
+package unstructured_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	logutil "github.com/boz/go-logutil"
+	"github.com/boz/kcache"
+	"github.com/boz/kcache/filter"
+	"github.com/boz/kcache/testutil"
+	"github.com/boz/kcache/types/unstructured"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kunstructured "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+var testGVR = schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+
+func testGenObject(ns, name, vsn string, labels map[string]string) *kunstructured.Unstructured {
+	obj := &kunstructured.Unstructured{}
+	obj.SetAPIVersion("example.com/v1")
+	obj.SetKind("Widget")
+	obj.SetNamespace(ns)
+	obj.SetName(name)
+	obj.SetResourceVersion(vsn)
+	obj.SetLabels(labels)
+	return obj
+}
+
+type fakeDynamic struct {
+	gvr   schema.GroupVersionResource
+	ns    string
+	list  *kunstructured.UnstructuredList
+	watch *watch.FakeWatcher
+}
+
+func (c *fakeDynamic) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	c.gvr = gvr
+	return &fakeResource{c: c}
+}
+
+type fakeResource struct {
+	// unimplemented methods panic.
+	dynamic.ResourceInterface
+	c *fakeDynamic
+}
+
+func (r *fakeResource) Namespace(ns string) dynamic.ResourceInterface {
+	r.c.ns = ns
+	return r
+}
+
+func (r *fakeResource) List(_ metav1.ListOptions) (*kunstructured.UnstructuredList, error) {
+	return r.c.list.DeepCopy(), nil
+}
+
+func (r *fakeResource) Watch(_ metav1.ListOptions) (watch.Interface, error) {
+	return r.c.watch, nil
+}
+
+func TestController(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dc := &fakeDynamic{
+		list: &kunstructured.UnstructuredList{
+			Object: map[string]interface{}{"apiVersion": "example.com/v1", "kind": "WidgetList"},
+			Items: []kunstructured.Unstructured{
+				*testGenObject("ns", "a", "1", map[string]string{"app": "x"}),
+				*testGenObject("ns", "b", "2", nil),
+			},
+		},
+		watch: watch.NewFakeWithChanSize(1, false),
+	}
+	dc.list.SetResourceVersion("2")
+
+	controller, err := unstructured.NewController(ctx, logutil.Default(), dc, testGVR, "ns")
+	require.NoError(t, err)
+	defer controller.Close()
+
+	assert.Equal(t, testGVR, dc.gvr)
+	assert.Equal(t, "ns", dc.ns)
+
+	sub, err := controller.SubscribeWithFilter(filter.Labels(map[string]string{"app": "x"}))
+	require.NoError(t, err)
+
+	testutil.AssertReady(t, "sub", sub)
+
+	list, err := sub.Cache().List()
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.IsType(t, &kunstructured.Unstructured{}, list[0])
+	assert.Equal(t, "a", list[0].GetName())
+
+	dc.watch.Add(testGenObject("ns", "c", "3", map[string]string{"app": "x"}))
+
+	select {
+	case ev := <-sub.Events():
+		assert.Equal(t, kcache.EventTypeCreate, ev.Type())
+		assert.Equal(t, "c", ev.Resource().GetName())
+		assert.True(t, filter.Kind("Widget").Accept(ev.Resource()))
+	case <-testutil.Timerch(ctx, time.Second):
+		assert.Fail(t, "no event")
+	}
+}
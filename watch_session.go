@@ -7,6 +7,7 @@ import (
 	logutil "github.com/boz/go-logutil"
 	"github.com/boz/kcache/client"
 	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/watch"
@@ -104,6 +105,10 @@ func (s *_watchSession) run() {
 
 			if status, ok := kevt.Object.(*metav1.Status); ok {
 				s.logStatus(status)
+				if kevt.Type == watch.Error {
+					s.lc.ShutdownInitiated(errors.Wrap(apierrors.FromObject(status), "watch error"))
+					return
+				}
 				continue
 			}
 
@@ -7,17 +7,19 @@ import (
 	lifecycle "github.com/boz/go-lifecycle"
 	logutil "github.com/boz/go-logutil"
 	"github.com/boz/kcache/client"
+	"github.com/boz/kcache/clock"
 	"github.com/pkg/errors"
-)
-
-const (
-	watchRetryDelay = time.Second
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 type watcher interface {
 	reset(string) error
 	events() <-chan Event
 
+	// expired() receives when the watch can't be resumed from the
+	// current version.  The watch is idle until the next reset().
+	expired() <-chan struct{}
+
 	Done() <-chan struct{}
 	Error() error
 }
@@ -25,27 +27,37 @@ type watcher interface {
 type _watcher struct {
 	version string
 
-	client client.WatchClient
+	client  client.WatchClient
+	backoff *backoff
+	clock   clock.Clock
+	metrics Metrics
 
-	resetch chan string
-	evtch   chan chan (<-chan Event)
+	resetch   chan string
+	retrych   chan string
+	evtch     chan chan (<-chan Event)
+	expiredch chan struct{}
 
 	log logutil.Log
 	lc  lifecycle.Lifecycle
 	ctx context.Context
 }
 
-func newWatcher(ctx context.Context, log logutil.Log, stopch <-chan struct{}, client client.WatchClient) watcher {
+func newWatcher(ctx context.Context, log logutil.Log, stopch <-chan struct{}, client client.WatchClient, backoff *backoff, clock clock.Clock, metrics Metrics) watcher {
 	log = log.WithComponent("watcher")
 	lc := lifecycle.New()
 
 	w := &_watcher{
-		client:  client,
-		resetch: make(chan string),
-		evtch:   make(chan chan (<-chan Event)),
-		log:     log,
-		lc:      lc,
-		ctx:     ctx,
+		client:    client,
+		backoff:   backoff,
+		clock:     clock,
+		metrics:   metrics,
+		resetch:   make(chan string),
+		retrych:   make(chan string),
+		evtch:     make(chan chan (<-chan Event)),
+		expiredch: make(chan struct{}, 1),
+		log:       log,
+		lc:        lc,
+		ctx:       ctx,
 	}
 
 	go w.lc.WatchContext(ctx)
@@ -74,6 +86,10 @@ func (w *_watcher) events() <-chan Event {
 	}
 }
 
+func (w *_watcher) expired() <-chan struct{} {
+	return w.expiredch
+}
+
 func (w *_watcher) Done() <-chan struct{} {
 	return w.lc.Done()
 }
@@ -93,7 +109,11 @@ func (w *_watcher) run() {
 
 	var curVersion string
 
-	var retry *time.Timer
+	// closed to cancel a scheduled retry.
+	var retry chan struct{}
+
+	// when the last session ended; zero if there was none.
+	var sessionEnded time.Time
 
 mainloop:
 	for {
@@ -108,22 +128,71 @@ mainloop:
 			w.log.Debugf("ressetting to version %v", vsn)
 
 			if retry != nil {
-				retry.Stop()
+				close(retry)
 				retry = nil
 			}
 
+			if !sessionEnded.IsZero() {
+				w.metrics.WatchReconnected(w.clock.Now().Sub(sessionEnded))
+				sessionEnded = time.Time{}
+			}
+
 			session.stop()
 			session = newWatchSession(ctx, w.log, w.client, vsn)
 			outch = make(chan Event, EventBufsiz)
 			curVersion = vsn
 
 		case <-session.done():
-			w.log.Debugf("session done.  retrying version %v in %v", curVersion, watchRetryDelay)
+			err := session.Error()
+
+			switch classifyWatchError(err) {
+			case watchActionFail:
+				w.log.Errorf("session failed: %v", err)
+				w.lc.ShutdownInitiated(errors.Wrap(err, "watch"))
+				break mainloop
 
+			case watchActionRelist:
+				w.log.Infof("session done: version %v expired (%v).  relisting", curVersion, err)
+
+				sessionEnded = w.clock.Now()
+
+				session.stop()
+				session = nullWatchSession{}
+				outch = nil
+
+				select {
+				case w.expiredch <- struct{}{}:
+				default:
+				}
+				continue
+			}
+
+			attempt := w.backoff.attempts + 1
+			ceiling := w.backoff.ceiling()
+			delay := w.backoff.next()
+			w.log.Infof("session done.  retrying version %v in %v (attempt %v, max delay %v)",
+				curVersion, delay, attempt, ceiling)
+
+			sessionEnded = w.clock.Now()
+
+			// outch is kept: the controller may be waiting on it.
 			session.stop()
 			session = nullWatchSession{}
-			outch = nil
-			retry = w.scheduleRetry(w.resetch, curVersion)
+			retry = w.scheduleRetry(w.retrych, curVersion, delay)
+
+		case vsn := <-w.retrych:
+			if retry == nil || vsn != curVersion {
+				// superseded by reset().
+				continue
+			}
+			retry = nil
+
+			w.log.Debugf("retrying version %v", vsn)
+
+			w.metrics.WatchReconnected(w.clock.Now().Sub(sessionEnded))
+			sessionEnded = time.Time{}
+
+			session = newWatchSession(ctx, w.log, w.client, vsn)
 
 		case evt := <-session.events():
 
@@ -134,6 +203,7 @@ mainloop:
 			}
 
 			curVersion = evt.Resource().GetResourceVersion()
+			w.backoff.reset()
 
 			w.log.Debugf("session event: %v version: %v", evt, curVersion)
 
@@ -144,7 +214,7 @@ mainloop:
 
 	cancel()
 	if retry != nil {
-		retry.Stop()
+		close(retry)
 	}
 
 	if donech := session.done(); donech != nil {
@@ -152,11 +222,71 @@ mainloop:
 	}
 }
 
-func (w *_watcher) scheduleRetry(ch chan string, vsn string) *time.Timer {
-	return time.AfterFunc(watchRetryDelay, func() {
+type watchAction int
+
+const (
+	// retry the watch from the current version after a delay.
+	watchActionBackoff watchAction = iota
+
+	// list again to obtain a version that can be watched.
+	watchActionRelist
+
+	// the watch can't succeed; shut down.
+	watchActionFail
+)
+
+func (a watchAction) String() string {
+	switch a {
+	case watchActionBackoff:
+		return "backoff"
+	case watchActionRelist:
+		return "relist"
+	case watchActionFail:
+		return "fail"
+	default:
+		return "unknown"
+	}
+}
+
+// classifyWatchError() returns the action to take when a watch session
+// ends with err.
+func classifyWatchError(err error) watchAction {
+	err = errors.Cause(err)
+	switch {
+	case err == nil:
+		return watchActionBackoff
+	case apierrors.IsResourceExpired(err), apierrors.IsGone(err):
+		return watchActionRelist
+	case apierrors.IsForbidden(err),
+		apierrors.IsUnauthorized(err),
+		apierrors.IsMethodNotSupported(err),
+		apierrors.IsBadRequest(err):
+		return watchActionFail
+	default:
+		return watchActionBackoff
+	}
+}
+
+// scheduleRetry() sends vsn to ch after delay unless the returned channel
+// is closed first.
+func (w *_watcher) scheduleRetry(ch chan string, vsn string, delay time.Duration) chan struct{} {
+	stopch := make(chan struct{})
+	afterch := w.clock.After(delay)
+
+	go func() {
+		select {
+		case <-afterch:
+		case <-stopch:
+			return
+		case <-w.lc.ShuttingDown():
+			return
+		}
 		select {
 		case ch <- vsn:
+		case <-stopch:
 		case <-w.lc.ShuttingDown():
 		}
-	})
+	}()
+
+	return stopch
 }
@@ -0,0 +1,35 @@
+package kcache
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestClassifyWatchError(t *testing.T) {
+	gr := schema.GroupResource{Resource: "pods"}
+
+	for _, test := range []struct {
+		err    error
+		action watchAction
+	}{
+		{nil, watchActionBackoff},
+		{fmt.Errorf("connection refused"), watchActionBackoff},
+		{apierrors.NewInternalError(fmt.Errorf("boom")), watchActionBackoff},
+		{apierrors.NewServiceUnavailable("unavailable"), watchActionBackoff},
+		{apierrors.NewGone("gone"), watchActionRelist},
+		{apierrors.NewResourceExpired("expired"), watchActionRelist},
+		{errors.Wrap(apierrors.NewResourceExpired("expired"), "watch error"), watchActionRelist},
+		{apierrors.NewForbidden(gr, "", fmt.Errorf("denied")), watchActionFail},
+		{apierrors.NewUnauthorized("unauthorized"), watchActionFail},
+		{apierrors.NewMethodNotSupported(gr, "watch"), watchActionFail},
+		{apierrors.NewBadRequest("field label not supported: spec.nodename"), watchActionFail},
+		{errors.Wrap(apierrors.NewForbidden(gr, "", fmt.Errorf("denied")), "connecting to server"), watchActionFail},
+	} {
+		assert.Equal(t, test.action, classifyWatchError(test.err), "%v", test.err)
+	}
+}